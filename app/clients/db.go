@@ -1,9 +1,17 @@
 package clients
 
 import (
+	"closealerts/app/tracing"
 	"closealerts/app/types"
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,14 +21,62 @@ type DB struct {
 	db *gorm.DB
 }
 
+const (
+	DBDriverSQLite   = "sqlite"
+	DBDriverPostgres = "postgres"
+)
+
+// NewDB opens the database of the configured driver. Repositories stick to SQL both drivers understand:
+// booleans go as parameters, and global updates use AllowGlobalUpdate instead of "1 = 1".
+func NewDB(config types.Config) (DB, error) {
+	switch config.DBDriver {
+	case "", DBDriverSQLite:
+		return NewDBFromSQLite(config)
+	case DBDriverPostgres:
+		return NewDBFromPostgres(config)
+	default:
+		return DB{}, fmt.Errorf("unknown db driver %s", config.DBDriver)
+	}
+}
+
 func NewDBFromSQLite(config types.Config) (DB, error) {
-	db, err := gorm.Open(sqlite.Open(config.SQLite3DBPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+	dsn := config.SQLite3DBPath
+
+	// concurrent writers wait for the lock instead of failing with "database is locked"
+	if dsn != ":memory:" && !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000&_journal_mode=WAL"
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		Plugins: map[string]gorm.Plugin{tracingPluginName: tracingPlugin{system: semconv.DBSystemSqlite}},
 	})
 	if err != nil {
 		return DB{}, fmt.Errorf("gorm open sqlite open: %w", err)
 	}
 
+	// every connection to :memory: gets a database of its own, so there must be only one
+	if config.SQLite3DBPath == ":memory:" {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return DB{}, fmt.Errorf("sql db: %w", err)
+		}
+
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	return DB{db: db}, nil
+}
+
+func NewDBFromPostgres(config types.Config) (DB, error) {
+	db, err := gorm.Open(postgres.Open(config.DatabaseURL), &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Silent),
+		Plugins: map[string]gorm.Plugin{tracingPluginName: tracingPlugin{system: semconv.DBSystemPostgreSQL}},
+	})
+	if err != nil {
+		return DB{}, fmt.Errorf("gorm open postgres open: %w", err)
+	}
+
 	return DB{db: db}, nil
 }
 
@@ -40,6 +96,98 @@ func (r DB) Insert(val interface{}) error {
 	return nil
 }
 
+func (r DB) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return fmt.Errorf("db: %w", err)
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	return nil
+}
+
 func (r DB) DB() *gorm.DB {
 	return r.db
 }
+
+const tracingPluginName = "tracing"
+
+// tracingPlugin wraps every query repositories make in a span, a child of the one in the query context.
+type tracingPlugin struct {
+	system attribute.KeyValue
+}
+
+func (p tracingPlugin) Name() string {
+	return tracingPluginName
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	} {
+		if err != nil {
+			return fmt.Errorf("register callback: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		// chained calls like FirstOrCreate share the statement, so the context is restored after
+		tx.InstanceSet("tracing:parent", tx.Statement.Context)
+
+		ctx, span := tracing.Start(tx.Statement.Context, "db "+operation, p.system, semconv.DBOperationKey.String(operation))
+		tx.Statement.Context = ctx
+		tx.InstanceSet("tracing:span", span)
+	}
+}
+
+func (p tracingPlugin) after(tx *gorm.DB) {
+	val, ok := tx.InstanceGet("tracing:span")
+	if !ok {
+		return
+	}
+
+	span, ok := val.(trace.Span)
+	if !ok {
+		return
+	}
+
+	if parent, ok := tx.InstanceGet("tracing:parent"); ok {
+		if ctx, ok := parent.(context.Context); ok {
+			tx.Statement.Context = ctx
+		}
+	}
+
+	span.SetAttributes(
+		semconv.DBSQLTableKey.String(tx.Statement.Table),
+		semconv.DBStatementKey.String(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// an expected answer, like a map not rendered yet
+		err = nil
+	}
+
+	tracing.End(span, err)
+}
@@ -0,0 +1,308 @@
+// Package fakebot is an in-process stand-in for the Telegram Bot API. It answers the methods the bot uses,
+// records every call and serves scripted updates, so whole command flows can be run without Telegram.
+package fakebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	BotID       = 1000
+	BotUsername = "fake_closealerts_bot"
+	Token       = "fake-token"
+)
+
+// Call is a Bot API request the bot has made.
+type Call struct {
+	Method string
+	Params url.Values
+}
+
+func (r Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Params.Get("chat_id"), 10, 64)
+
+	return id
+}
+
+func (r Call) Text() string {
+	return r.Params.Get("text")
+}
+
+type Server struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	calls     []Call
+	updates   []tgbotapi.Update
+	admins    map[[2]int64]bool
+	messageID int
+	updateID  int
+	webhook   string
+}
+
+func NewServer() *Server {
+	r := &Server{admins: map[[2]int64]bool{}}
+
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+
+	return r
+}
+
+func (r *Server) Close() {
+	r.server.Close()
+}
+
+// Endpoint is what tgbotapi.NewBotAPIWithAPIEndpoint takes.
+func (r *Server) Endpoint() string {
+	return r.server.URL + "/bot%s/%s"
+}
+
+// Calls returns the calls of the method made so far, or all of them if the method is empty.
+func (r *Server) Calls(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Call
+
+	for _, call := range r.calls {
+		if len(method) == 0 || call.Method == method {
+			out = append(out, call)
+		}
+	}
+
+	return out
+}
+
+// Sent returns the texts of messages sent to the chat.
+func (r *Server) Sent(chatID int64) []string {
+	var out []string
+
+	for _, call := range r.Calls("sendMessage") {
+		if call.ChatID() == chatID {
+			out = append(out, call.Text())
+		}
+	}
+
+	return out
+}
+
+func (r *Server) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+// SetAdmin makes getChatMember report the user as an administrator of the chat.
+func (r *Server) SetAdmin(chatID, userID int64, admin bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.admins[[2]int64{chatID, userID}] = admin
+}
+
+// Script queues updates for getUpdates, numbering them.
+func (r *Server) Script(updates ...tgbotapi.Update) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, update := range updates {
+		r.updateID++
+		update.UpdateID = r.updateID
+		r.updates = append(r.updates, update)
+	}
+}
+
+// Push numbers the updates and posts them to the webhook the bot has set, the way Telegram delivers them.
+func (r *Server) Push(updates ...tgbotapi.Update) error {
+	r.mu.Lock()
+	webhook := r.webhook
+	r.mu.Unlock()
+
+	if len(webhook) == 0 {
+		return errors.New("no webhook set")
+	}
+
+	for _, update := range updates {
+		r.mu.Lock()
+		r.updateID++
+		update.UpdateID = r.updateID
+		r.mu.Unlock()
+
+		bts, err := json.Marshal(update)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+
+		resp, err := http.Post(webhook, "application/json", bytes.NewReader(bts))
+		if err != nil {
+			return fmt.Errorf("post: %w", err)
+		}
+
+		_ = resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("post: status %d", resp.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+// Message builds an update of a user writing to the chat; commands get their entity, as Telegram does.
+func Message(chat tgbotapi.Chat, from tgbotapi.User, text string) tgbotapi.Update {
+	msg := &tgbotapi.Message{
+		MessageID: int(time.Now().UnixNano() % 1_000_000),
+		From:      &from,
+		Chat:      &chat,
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		length := strings.IndexByte(text, ' ')
+		if length < 0 {
+			length = len(text)
+		}
+
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+
+	return tgbotapi.Update{Message: msg}
+}
+
+// Callback builds an update of a user pressing an inline button under the message.
+func Callback(msg *tgbotapi.Message, from tgbotapi.User, data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      strconv.FormatInt(time.Now().UnixNano(), 10),
+		From:    &from,
+		Message: msg,
+		Data:    data,
+	}}
+}
+
+func (r *Server) serve(w http.ResponseWriter, req *http.Request) {
+	// paths are /bot<token>/<method>
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		http.NotFound(w, req)
+
+		return
+	}
+
+	if err := req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		respond(w, nil, fmt.Errorf("parse form: %w", err))
+
+		return
+	}
+
+	call := Call{Method: parts[1], Params: req.Form}
+
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+
+	result, err := r.handle(call)
+	respond(w, result, err)
+}
+
+func (r *Server) handle(call Call) (interface{}, error) {
+	switch call.Method {
+	case "getMe":
+		return tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Fake", UserName: BotUsername}, nil
+
+	case "sendMessage", "sendPhoto", "editMessageText", "editMessageReplyMarkup":
+		r.mu.Lock()
+		r.messageID++
+		id := r.messageID
+		r.mu.Unlock()
+
+		if messageID, err := strconv.Atoi(call.Params.Get("message_id")); err == nil {
+			id = messageID
+		}
+
+		msg := tgbotapi.Message{
+			MessageID: id,
+			Chat:      &tgbotapi.Chat{ID: call.ChatID()},
+			Date:      int(time.Now().Unix()),
+			Text:      call.Text(),
+			From:      &tgbotapi.User{ID: BotID, IsBot: true, UserName: BotUsername},
+		}
+
+		if call.Method == "sendPhoto" {
+			msg.Photo = []tgbotapi.PhotoSize{{FileID: "fake-photo-" + strconv.Itoa(id)}}
+		}
+
+		return msg, nil
+
+	case "getChatMember":
+		chatID := call.ChatID()
+		userID, _ := strconv.ParseInt(call.Params.Get("user_id"), 10, 64)
+
+		r.mu.Lock()
+		admin := r.admins[[2]int64{chatID, userID}]
+		r.mu.Unlock()
+
+		status := "member"
+		if admin {
+			status = "administrator"
+		}
+
+		return tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: status}, nil
+
+	case "getUpdates":
+		r.mu.Lock()
+		updates := r.updates
+		r.updates = nil
+		r.mu.Unlock()
+
+		if updates == nil {
+			updates = []tgbotapi.Update{}
+		}
+
+		return updates, nil
+
+	case "setWebhook":
+		r.mu.Lock()
+		r.webhook = call.Params.Get("url")
+		r.mu.Unlock()
+
+		return true, nil
+
+	case "getWebhookInfo":
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		return tgbotapi.WebhookInfo{URL: r.webhook}, nil
+
+	default:
+		// setMyCommands, answerCallbackQuery and the like
+		return true, nil
+	}
+}
+
+func respond(w http.ResponseWriter, result interface{}, err error) {
+	resp := map[string]interface{}{"ok": err == nil}
+
+	if err != nil {
+		resp["error_code"] = http.StatusBadRequest
+		resp["description"] = err.Error()
+	} else {
+		resp["result"] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
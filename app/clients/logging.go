@@ -1,30 +1,120 @@
 package clients
 
 import (
+	"closealerts/app/types"
+	"context"
 	"fmt"
+	"os"
+	"time"
 
+	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func NewLogger() (*zap.Logger, error) {
-	logger, err := zap.Config{
-		Level:             zap.NewAtomicLevelAt(zap.DebugLevel),
-		DisableCaller:     true,
-		DisableStacktrace: true,
-		Encoding:          "json",
-		EncoderConfig:     zap.NewDevelopmentEncoderConfig(),
-		OutputPaths:       []string{"stderr", "./log.log"},
-		ErrorOutputPaths:  []string{"stderr", "./internal.log"},
-		InitialFields:     nil,
-	}.Build()
-
-	if err != nil {
-		return nil, fmt.Errorf("zap new development: %w", err)
+// NewLogLevel is the level of the logger, changed on config reload and by /admin_log_level.
+func NewLogLevel(config types.Config) zap.AtomicLevel {
+	return zap.NewAtomicLevelAt(config.LogLevel)
+}
+
+// NewLogger logs to the configured outputs: stderr, stdout or files, which are rotated by size
+// and optionally every LogRotateEvery. Sampling, when set, caps repeated messages per second.
+func NewLogger(lc fx.Lifecycle, config types.Config, level zap.AtomicLevel) (*zap.Logger, error) {
+	var files []*lumberjack.Logger
+
+	open := func(outputs []string) zapcore.WriteSyncer {
+		syncers := make([]zapcore.WriteSyncer, 0, len(outputs))
+
+		for _, output := range outputs {
+			switch output {
+			case "stderr":
+				syncers = append(syncers, zapcore.Lock(os.Stderr))
+			case "stdout":
+				syncers = append(syncers, zapcore.Lock(os.Stdout))
+			default:
+				file := &lumberjack.Logger{
+					Filename:   output,
+					MaxSize:    config.LogMaxSizeMB,
+					MaxBackups: config.LogMaxBackups,
+					MaxAge:     config.LogMaxAgeDays,
+					Compress:   config.LogCompress,
+				}
+
+				files = append(files, file)
+				syncers = append(syncers, zapcore.AddSync(file))
+			}
+		}
+
+		return zapcore.NewMultiWriteSyncer(syncers...)
 	}
 
+	out, errOut := open(config.LogOutputs), open(config.LogErrorOutputs)
+
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+
+	var encoder zapcore.Encoder
+
+	switch config.LogEncoding {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown log encoding %s", config.LogEncoding)
+	}
+
+	core := zapcore.NewCore(encoder, out, level)
+
+	if config.LogSamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, config.LogSamplingInitial, config.LogSamplingThereafter)
+	}
+
+	logger := zap.New(core, zap.ErrorOutput(errOut))
+
+	stop := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if config.LogRotateEvery > 0 && len(files) > 0 {
+				go rotate(files, config.LogRotateEvery, stop)
+			}
+
+			return nil
+		},
+
+		OnStop: func(context.Context) error {
+			close(stop)
+			_ = logger.Sync()
+
+			for _, file := range files {
+				_ = file.Close()
+			}
+
+			return nil
+		},
+	})
+
 	return logger, nil
 }
 
+// rotate starts new log files every period, on top of the rotation by size.
+func rotate(files []*lumberjack.Logger, every time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, file := range files {
+				_ = file.Rotate()
+			}
+		}
+	}
+}
+
 func NewSugaredLogger(log *zap.Logger) *zap.SugaredLogger {
 	return log.Sugar()
 }
@@ -0,0 +1,235 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Recording is one source response saved to disk. Query strings are left out of the URL, they carry API tokens.
+type Recording struct {
+	URL        string    `json:"url"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Status     int       `json:"status"`
+	Body       string    `json:"body"`
+}
+
+func recordingKey(req *http.Request) string {
+	return req.URL.Host + req.URL.Path
+}
+
+type RecordingTransport struct {
+	log  *zap.SugaredLogger
+	next http.RoundTripper
+	dir  string
+}
+
+func NewRecordingTransport(log *zap.SugaredLogger, next http.RoundTripper, dir string) RecordingTransport {
+	return RecordingTransport{log: log, next: next, dir: dir}
+}
+
+// RoundTrip saves the response next to the previous ones; failing to save does not fail the request.
+func (r RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	bts, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("io read all: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(bts))
+
+	recording := Recording{
+		URL:        recordingKey(req),
+		RecordedAt: time.Now(),
+		Status:     resp.StatusCode,
+		Body:       string(bts),
+	}
+
+	if err := r.save(recording); err != nil {
+		r.log.Errorw("save recording", "url", recording.URL, "err", err)
+	}
+
+	return resp, nil
+}
+
+func (r RecordingTransport) save(recording Recording) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("mkdir all: %w", err)
+	}
+
+	bts, err := json.Marshal(recording)
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+
+	name := strconv.FormatInt(recording.RecordedAt.UnixNano(), 10) + ".json"
+	if err := os.WriteFile(filepath.Join(r.dir, name), bts, 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+// ReplayTransport serves recordings instead of going to the network. The time of the first recording is
+// matched with the start of the replay, and every request gets the latest response recorded for its URL
+// by the replay clock, which runs speed times faster than the real one.
+type ReplayTransport struct {
+	recordings map[string][]Recording
+	origin     time.Time
+	speed      float64
+	clock      *replayClock
+}
+
+type replayClock struct {
+	mu      sync.Mutex
+	started time.Time
+}
+
+func NewReplayTransport(dir string, speed float64) (ReplayTransport, error) {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	recordings, err := LoadRecordings(dir)
+	if err != nil {
+		return ReplayTransport{}, fmt.Errorf("load recordings: %w", err)
+	}
+
+	if len(recordings) == 0 {
+		return ReplayTransport{}, fmt.Errorf("no recordings in %s", dir)
+	}
+
+	byURL := map[string][]Recording{}
+	for _, recording := range recordings {
+		byURL[recording.URL] = append(byURL[recording.URL], recording)
+	}
+
+	return ReplayTransport{
+		recordings: byURL,
+		origin:     recordings[0].RecordedAt,
+		speed:      speed,
+		clock:      &replayClock{},
+	}, nil
+}
+
+// LoadRecordings reads the recordings of the dir ordered by time.
+func LoadRecordings(dir string) ([]Recording, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+
+	recordings := make([]Recording, 0, len(paths))
+
+	for _, path := range paths {
+		bts, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		var recording Recording
+		if err := json.Unmarshal(bts, &recording); err != nil {
+			return nil, fmt.Errorf("json unmarshal %s: %w", path, err)
+		}
+
+		recordings = append(recordings, recording)
+	}
+
+	sort.SliceStable(recordings, func(i, j int) bool { return recordings[i].RecordedAt.Before(recordings[j].RecordedAt) })
+
+	return recordings, nil
+}
+
+var ErrNoRecording = errors.New("no recording")
+
+// At returns the latest recording of the URL at the replay time; the replay clock starts with the first call.
+func (r ReplayTransport) At(key string) (Recording, error) {
+	list, ok := r.recordings[key]
+	if !ok {
+		return Recording{}, fmt.Errorf("%s: %w", key, ErrNoRecording)
+	}
+
+	r.clock.mu.Lock()
+	if r.clock.started.IsZero() {
+		r.clock.started = time.Now()
+	}
+	elapsed := time.Since(r.clock.started)
+	r.clock.mu.Unlock()
+
+	now := r.origin.Add(time.Duration(float64(elapsed) * r.speed))
+
+	// until the URL is first recorded, its first recording stands in
+	current := list[0]
+
+	for _, recording := range list {
+		if recording.RecordedAt.After(now) {
+			break
+		}
+
+		current = recording
+	}
+
+	return current, nil
+}
+
+func (r ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recording, err := r.At(recordingKey(req))
+	if err != nil {
+		return nil, fmt.Errorf("at: %w", err)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(recording.Status),
+		StatusCode:    recording.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewBufferString(recording.Body)),
+		ContentLength: int64(len(recording.Body)),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP makes the replay a stand-in server for the sources: requests are matched by path only,
+// as the hosts of the real sources all point to it.
+func (r ReplayTransport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	for key := range r.recordings {
+		if !strings.HasSuffix(key, req.URL.Path) {
+			continue
+		}
+
+		recording, err := r.At(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(recording.Status)
+		_, _ = io.WriteString(w, recording.Body)
+
+		return
+	}
+
+	http.NotFound(w, req)
+}
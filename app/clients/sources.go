@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SourcesModeLive   = "live"
+	SourcesModeRecord = "record"
+	SourcesModeReplay = "replay"
+)
+
+// Sources fetches alert sources. Depending on the mode it records the responses to disk or replays the recorded
+// ones instead of going to the network; a base URL makes it go to a stand-in server instead of the real hosts.
+type Sources struct {
+	log   *zap.SugaredLogger
+	state *sourcesState
+}
+
+type sourcesState struct {
+	mu      sync.RWMutex
+	client  *http.Client
+	baseURL *url.URL
+}
+
+func NewSources(log *zap.SugaredLogger, config types.Config) (Sources, error) {
+	r := Sources{log: log, state: &sourcesState{}}
+
+	if err := r.Reload(config); err != nil {
+		return Sources{}, err
+	}
+
+	return r, nil
+}
+
+// Reload switches to the mode and base URL of the config; the current ones stay if the new ones don't work.
+func (r Sources) Reload(config types.Config) error {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	switch config.SourcesMode {
+	case "", SourcesModeLive:
+	case SourcesModeRecord:
+		transport = NewRecordingTransport(r.log, transport, config.SourcesDir)
+	case SourcesModeReplay:
+		replay, err := NewReplayTransport(config.SourcesDir, config.SourcesReplaySpeed)
+		if err != nil {
+			return fmt.Errorf("new replay transport: %w", err)
+		}
+
+		transport = replay
+	default:
+		return fmt.Errorf("unknown sources mode %s", config.SourcesMode)
+	}
+
+	var baseURL *url.URL
+
+	if len(config.SourcesBaseURL) > 0 {
+		parsed, err := url.Parse(config.SourcesBaseURL)
+		if err != nil {
+			return fmt.Errorf("parse sources base url: %w", err)
+		}
+
+		baseURL = parsed
+	}
+
+	r.state.mu.Lock()
+	r.state.client, r.state.baseURL = &http.Client{Transport: transport}, baseURL
+	r.state.mu.Unlock()
+
+	return nil
+}
+
+func (r Sources) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	r.state.mu.RLock()
+	client, baseURL := r.state.client, r.state.baseURL
+	r.state.mu.RUnlock()
+
+	if baseURL != nil {
+		target.Scheme, target.Host = baseURL.Scheme, baseURL.Host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request with context: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	bts, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io read all: %w", err)
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return nil, fmt.Errorf("resp body close: %w", err)
+	}
+
+	return bts, nil
+}
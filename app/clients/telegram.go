@@ -1,12 +1,19 @@
 package clients
 
 import (
+	"closealerts/app/metrics"
+	"closealerts/app/tracing"
 	"closealerts/app/types"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/fx"
 	"go.uber.org/ratelimit"
 	"go.uber.org/zap"
@@ -15,12 +22,44 @@ import (
 type Telegram struct {
 	log *zap.SugaredLogger
 
-	Client *tgbotapi.BotAPI
-	rl     ratelimit.Limiter
+	Client  *tgbotapi.BotAPI
+	rl      *limiter
+	webhook *webhookRegistration
+}
+
+// limiter is a rate limiter whose rate can be changed on config reload.
+type limiter struct {
+	mu sync.RWMutex
+	rl ratelimit.Limiter
+}
+
+func (r *limiter) Take() time.Time {
+	r.mu.RLock()
+	rl := r.rl
+	r.mu.RUnlock()
+
+	return rl.Take()
+}
+
+func (r *limiter) Set(perSecond int) {
+	r.mu.Lock()
+	r.rl = ratelimit.New(perSecond, ratelimit.Per(time.Second))
+	r.mu.Unlock()
+}
+
+type webhookRegistration struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+// WebhookStatus is what Telegram reports about the webhook, along with the time the bot registered it.
+type WebhookStatus struct {
+	tgbotapi.WebhookInfo
+	RegisteredAt time.Time
 }
 
 func NewTelegram(log *zap.SugaredLogger, config types.Config) (Telegram, error) {
-	api, err := tgbotapi.NewBotAPI(config.TelegramBotAPI)
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(config.TelegramBotAPI, config.TelegramAPIEndpoint)
 	if err != nil {
 		return Telegram{}, fmt.Errorf("new bot api: %w", err)
 	}
@@ -28,13 +67,19 @@ func NewTelegram(log *zap.SugaredLogger, config types.Config) (Telegram, error)
 	api.Debug = config.DebugTelegram
 
 	return Telegram{
-		log:    log,
-		Client: api,
-		rl:     ratelimit.New(30, ratelimit.Per(time.Second)),
+		log:     log,
+		Client:  api,
+		rl:      &limiter{rl: ratelimit.New(config.TelegramRateLimit, ratelimit.Per(time.Second))},
+		webhook: &webhookRegistration{},
 	}, nil
 }
 
-func RegisterTelegram(lc fx.Lifecycle, config types.Config, bot Telegram) {
+// WebhookEndpoint registers the webhook with Telegram; Telegram implements it.
+type WebhookEndpoint interface {
+	SetupWebhookEndpoint(pattern string, cert string) error
+}
+
+func RegisterTelegram(lc fx.Lifecycle, config types.Config, bot WebhookEndpoint) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			if err := bot.SetupWebhookEndpoint(config.WHEndpoint, config.Cert); err != nil {
@@ -46,43 +91,6 @@ func RegisterTelegram(lc fx.Lifecycle, config types.Config, bot Telegram) {
 	})
 }
 
-func RegisterTelegramCommands(log *zap.SugaredLogger, bot Telegram) error {
-	commands := tgbotapi.NewSetMyCommands(
-		tgbotapi.BotCommand{
-			Command:     "map",
-			Description: "Показати тривоги на мапі",
-		},
-
-		tgbotapi.BotCommand{
-			Command:     "alerts",
-			Description: "Місця, де оголошена тривога",
-		},
-
-		tgbotapi.BotCommand{
-			Command:     "areas",
-			Description: "Список відслідковуваних областей, разом з налаштуванням",
-		},
-
-		tgbotapi.BotCommand{
-			Command:     "start",
-			Description: "Коротко про те, як працює бот.",
-		},
-	)
-
-	resp, err := bot.Client.Request(commands)
-	if err != nil {
-		log.Errorw("set commands", "err", err)
-	} else {
-		log.Infow(
-			"set commands",
-			"ok", resp.Ok,
-			"desc", resp.Description,
-		)
-	}
-
-	return nil
-}
-
 func (r Telegram) SetupWebhookEndpoint(pattern string, cert string) error {
 	var (
 		wh  tgbotapi.WebhookConfig
@@ -103,32 +111,188 @@ func (r Telegram) SetupWebhookEndpoint(pattern string, cert string) error {
 		return fmt.Errorf("request: %w", err)
 	}
 
+	r.webhook.mu.Lock()
+	r.webhook.at = time.Now()
+	r.webhook.mu.Unlock()
+
 	return nil
 }
 
-func (r Telegram) MaybeSend(_ context.Context, c tgbotapi.Chattable) {
-	r.rl.Take()
+func (r Telegram) WebhookStatus(_ context.Context) (WebhookStatus, error) {
+	r.webhook.mu.Lock()
+	registeredAt := r.webhook.at
+	r.webhook.mu.Unlock()
+
+	info, err := r.Client.GetWebhookInfo()
+	if err != nil {
+		return WebhookStatus{RegisteredAt: registeredAt}, fmt.Errorf("get webhook info: %w", err)
+	}
+
+	return WebhookStatus{WebhookInfo: info, RegisteredAt: registeredAt}, nil
+}
+
+// SetRateLimit changes the number of Bot API requests a second.
+func (r Telegram) SetRateLimit(perSecond int) {
+	r.rl.Set(perSecond)
+}
+
+func (r Telegram) Username() string {
+	return r.Client.Self.UserName
+}
+
+func (r Telegram) IsChatAdmin(ctx context.Context, chatID, userID int64) (bool, error) {
+	var member tgbotapi.ChatMember
+
+	err := r.call(ctx, "GetChatMemberConfig", chatID, func() (err error) {
+		member, err = r.Client.GetChatMember(tgbotapi.GetChatMemberConfig{
+			ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+		})
+
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("get chat member: %w", err)
+	}
+
+	return member.IsCreator() || member.IsAdministrator(), nil
+}
+
+func (r Telegram) MaybeSend(ctx context.Context, c tgbotapi.Chattable) {
+	err := r.call(ctx, requestName(c), chatOf(c), func() error {
+		_, err := r.Client.Request(c)
 
-	if _, err := r.Client.Request(c); err != nil {
+		return err
+	})
+	if err != nil {
 		r.log.Errorw("send new message", "err", err)
 	}
 }
 
-func (r Telegram) MaybeSendText(_ context.Context, chatID int64, msg string) {
-	r.rl.Take()
+func (r Telegram) MaybeSendText(ctx context.Context, chatID int64, msg string) {
+	err := r.call(ctx, "MessageConfig", chatID, func() error {
+		_, err := r.Client.Send(tgbotapi.NewMessage(chatID, msg))
 
-	if _, err := r.Client.Send(tgbotapi.NewMessage(chatID, msg)); err != nil {
+		return err
+	})
+	if err != nil {
 		r.log.Errorw("send new message", "err", err)
 	}
 }
 
-func (r Telegram) Send(_ context.Context, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
-	r.rl.Take()
+// IsBlocked tells if sending failed because the bot was blocked, kicked or the chat is gone.
+func IsBlocked(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+
+	return tgErr.Code == http.StatusForbidden ||
+		(tgErr.Code == http.StatusBadRequest && strings.Contains(tgErr.Message, "chat not found"))
+}
+
+// IsMessageGone tells if editing failed because the message was deleted.
+func IsMessageGone(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+
+	return tgErr.Code == http.StatusBadRequest &&
+		(strings.Contains(tgErr.Message, "message to edit not found") || strings.Contains(tgErr.Message, "MESSAGE_ID_INVALID"))
+}
+
+// IsNotModified tells if editing failed because the message already says the same.
+func IsNotModified(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+
+	return tgErr.Code == http.StatusBadRequest && strings.Contains(tgErr.Message, "message is not modified")
+}
+
+// Request makes a Bot API request answered with no message, like setting the commands.
+func (r Telegram) Request(ctx context.Context, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+
+	err := r.call(ctx, requestName(c), chatOf(c), func() (err error) {
+		resp, err = r.Client.Request(c)
 
-	msg, err := r.Client.Send(chattable)
+		return err
+	})
+	if err != nil {
+		return resp, fmt.Errorf("request: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (r Telegram) Send(ctx context.Context, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+
+	err := r.call(ctx, requestName(chattable), chatOf(chattable), func() (err error) {
+		msg, err = r.Client.Send(chattable)
+
+		return err
+	})
 	if err != nil {
 		return msg, fmt.Errorf("send: %w", err)
 	}
 
 	return msg, nil
 }
+
+// call makes the request once the rate limiter lets it through, tracing the wait along with the request.
+func (r Telegram) call(ctx context.Context, request string, chatID int64, do func() error) error {
+	_, span := tracing.Start(ctx, "telegram "+request,
+		attribute.String("telegram.request", request),
+		attribute.Int64("chat.id", chatID),
+	)
+
+	r.rl.Take()
+	span.AddEvent("rate limiter passed")
+
+	started := time.Now()
+	err := do()
+	observe(request, started, err)
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// chatOf returns the chat of the requests the bot makes, 0 for the others.
+func chatOf(chattable tgbotapi.Chattable) int64 {
+	switch c := chattable.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID
+	case tgbotapi.PhotoConfig:
+		return c.ChatID
+	case tgbotapi.ChatActionConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return c.ChatID
+	case tgbotapi.PinChatMessageConfig:
+		return c.ChatID
+	case tgbotapi.UnpinChatMessageConfig:
+		return c.ChatID
+	default:
+		return 0
+	}
+}
+
+// requestName labels Bot API requests by the config type, e.g. MessageConfig or EditMessageTextConfig.
+func requestName(chattable tgbotapi.Chattable) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", chattable), "tgbotapi.")
+}
+
+func observe(request string, started time.Time, err error) {
+	metrics.TelegramRequestDuration.WithLabelValues(request).Observe(time.Since(started).Seconds())
+
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) && tgErr.Code == http.StatusTooManyRequests {
+		metrics.TelegramRateLimited.Inc()
+	}
+}
@@ -0,0 +1,565 @@
+// Command geo-raions writes the bundled raion borders and their adjacency out of the bundled oblast borders.
+//
+// Raion borders are approximated: every oblast is split between its raions, each raion taking the part of
+// the oblast closer to its central point than to the central points of the other raions. The points are
+// picked inside the raions, away from their administrative centres, which often lie at an edge. Raions whose
+// borders touch are neighbors, within an oblast and across the borders of neighboring oblasts.
+//
+// Run it with go generate in app/services after changing the oblast borders or the raions.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type raion struct {
+	name, oblast string
+	lat, lon     float64
+}
+
+// raions are the 136 raions of 2020, as the alert sources name them, with the point each one is grown from.
+var raions = []raion{
+	{"Вінницький", "Вінницька", 49.25, 28.6},
+	{"Гайсинський", "Вінницька", 48.85, 29.45},
+	{"Жмеринський", "Вінницька", 48.95, 28.0},
+	{"Могилів-Подільський", "Вінницька", 48.5, 27.75},
+	{"Тульчинський", "Вінницька", 48.55, 28.8},
+	{"Хмільницький", "Вінницька", 49.55, 27.95},
+
+	{"Володимирський", "Волинська", 50.8, 24.15},
+	{"Камінь-Каширський", "Волинська", 51.55, 25.35},
+	{"Ковельський", "Волинська", 51.35, 24.6},
+	{"Луцький", "Волинська", 50.75, 25.2},
+
+	{"Дніпровський", "Дніпропетровська", 48.4, 34.95},
+	{"Кам'янський", "Дніпропетровська", 48.55, 34.0},
+	{"Криворізький", "Дніпропетровська", 47.95, 33.45},
+	{"Нікопольський", "Дніпропетровська", 47.6, 34.3},
+	{"Новомосковський", "Дніпропетровська", 48.85, 35.4},
+	{"Павлоградський", "Дніпропетровська", 48.5, 36.0},
+	{"Синельниківський", "Дніпропетровська", 48.05, 35.9},
+
+	{"Бахмутський", "Донецька", 48.65, 38.1},
+	{"Волноваський", "Донецька", 47.65, 37.5},
+	{"Горлівський", "Донецька", 48.3, 38.15},
+	{"Донецький", "Донецька", 47.95, 38.2},
+	{"Кальміуський", "Донецька", 47.5, 38.15},
+	{"Краматорський", "Донецька", 48.85, 37.5},
+	{"Маріупольський", "Донецька", 47.2, 37.4},
+	{"Покровський", "Донецька", 48.3, 37.05},
+
+	{"Бердичівський", "Житомирська", 49.85, 28.7},
+	{"Житомирський", "Житомирська", 50.25, 28.7},
+	{"Звягельський", "Житомирська", 50.55, 27.6},
+	{"Коростенський", "Житомирська", 51.0, 28.5},
+
+	{"Берегівський", "Закарпатська", 48.2, 22.75},
+	{"Мукачівський", "Закарпатська", 48.6, 23.0},
+	{"Рахівський", "Закарпатська", 47.95, 24.0},
+	{"Тячівський", "Закарпатська", 48.15, 23.75},
+	{"Ужгородський", "Закарпатська", 48.65, 22.45},
+	{"Хустський", "Закарпатська", 48.4, 23.45},
+
+	{"Бердянський", "Запорізька", 46.95, 36.5},
+	{"Василівський", "Запорізька", 47.35, 35.0},
+	{"Запорізький", "Запорізька", 47.8, 35.3},
+	{"Мелітопольський", "Запорізька", 46.9, 35.1},
+	{"Пологівський", "Запорізька", 47.45, 36.3},
+
+	{"Верховинський", "Івано-Франківська", 48.05, 24.7},
+	{"Івано-Франківський", "Івано-Франківська", 49.0, 24.85},
+	{"Калуський", "Івано-Франківська", 48.85, 24.2},
+	{"Коломийський", "Івано-Франківська", 48.6, 25.2},
+	{"Косівський", "Івано-Франківська", 48.3, 25.0},
+	{"Надвірнянський", "Івано-Франківська", 48.5, 24.3},
+
+	{"Білоцерківський", "Київська", 49.6, 30.1},
+	{"Бориспільський", "Київська", 50.15, 31.3},
+	{"Броварський", "Київська", 50.7, 31.1},
+	{"Бучанський", "Київська", 50.55, 29.75},
+	{"Вишгородський", "Київська", 51.0, 30.0},
+	{"Обухівський", "Київська", 49.95, 30.75},
+	{"Фастівський", "Київська", 50.05, 29.85},
+
+	{"Голованівський", "Кіровоградська", 48.45, 30.6},
+	{"Кропивницький", "Кіровоградська", 48.45, 32.3},
+	{"Новоукраїнський", "Кіровоградська", 48.25, 31.5},
+	{"Олександрійський", "Кіровоградська", 48.7, 32.9},
+
+	{"Алчевський", "Луганська", 48.5, 38.8},
+	{"Довжанський", "Луганська", 48.05, 39.75},
+	{"Луганський", "Луганська", 48.45, 39.4},
+	{"Ровеньківський", "Луганська", 48.1, 39.2},
+	{"Сватівський", "Луганська", 49.5, 38.3},
+	{"Сєвєродонецький", "Луганська", 48.95, 38.5},
+	{"Старобільський", "Луганська", 49.45, 39.3},
+	{"Щастинський", "Луганська", 48.75, 39.5},
+
+	{"Дрогобицький", "Львівська", 49.3, 23.45},
+	{"Золочівський", "Львівська", 49.95, 24.95},
+	{"Львівський", "Львівська", 49.85, 24.2},
+	{"Самбірський", "Львівська", 49.3, 22.9},
+	{"Стрийський", "Львівська", 49.2, 24.0},
+	{"Шептицький", "Львівська", 50.3, 24.3},
+	{"Яворівський", "Львівська", 50.0, 23.4},
+
+	{"Баштанський", "Миколаївська", 47.35, 32.6},
+	{"Вознесенський", "Миколаївська", 47.45, 31.75},
+	{"Миколаївський", "Миколаївська", 46.85, 31.9},
+	{"Первомайський", "Миколаївська", 47.75, 31.35},
+
+	{"Березівський", "Одеська", 47.3, 30.6},
+	{"Білгород-Дністровський", "Одеська", 46.1, 29.9},
+	{"Болградський", "Одеська", 45.75, 28.8},
+	{"Ізмаїльський", "Одеська", 45.45, 29.1},
+	{"Одеський", "Одеська", 46.5, 30.6},
+	{"Подільський", "Одеська", 47.75, 29.7},
+	{"Роздільнянський", "Одеська", 46.8, 29.9},
+
+	{"Кременчуцький", "Полтавська", 49.25, 33.4},
+	{"Лубенський", "Полтавська", 50.1, 32.9},
+	{"Миргородський", "Полтавська", 50.0, 33.9},
+	{"Полтавський", "Полтавська", 49.55, 34.7},
+
+	{"Вараський", "Рівненська", 51.55, 25.9},
+	{"Дубенський", "Рівненська", 50.35, 25.8},
+	{"Рівненський", "Рівненська", 50.75, 26.4},
+	{"Сарненський", "Рівненська", 51.4, 26.8},
+
+	{"Конотопський", "Сумська", 51.2, 33.4},
+	{"Охтирський", "Сумська", 50.35, 34.9},
+	{"Роменський", "Сумська", 50.8, 33.6},
+	{"Сумський", "Сумська", 50.9, 34.8},
+	{"Шосткинський", "Сумська", 51.85, 33.6},
+
+	{"Кременецький", "Тернопільська", 50.0, 25.8},
+	{"Тернопільський", "Тернопільська", 49.5, 25.5},
+	{"Чортківський", "Тернопільська", 48.95, 25.75},
+
+	{"Берестинський", "Харківська", 49.35, 35.4},
+	{"Богодухівський", "Харківська", 50.2, 35.5},
+	{"Ізюмський", "Харківська", 49.1, 37.2},
+	{"Куп'янський", "Харківська", 49.7, 37.6},
+	{"Лозівський", "Харківська", 49.0, 36.3},
+	{"Харківський", "Харківська", 50.0, 36.2},
+	{"Чугуївський", "Харківська", 49.85, 36.9},
+
+	{"Бериславський", "Херсонська", 47.0, 33.3},
+	{"Генічеський", "Херсонська", 46.2, 34.6},
+	{"Каховський", "Херсонська", 46.85, 34.2},
+	{"Скадовський", "Херсонська", 46.3, 33.0},
+	{"Херсонський", "Херсонська", 46.65, 32.7},
+
+	{"Кам'янець-Подільський", "Хмельницька", 48.75, 26.8},
+	{"Хмельницький", "Хмельницька", 49.45, 27.0},
+	{"Шепетівський", "Хмельницька", 50.2, 27.0},
+
+	{"Звенигородський", "Черкаська", 49.1, 31.0},
+	{"Золотоніський", "Черкаська", 49.7, 32.2},
+	{"Уманський", "Черкаська", 48.95, 30.2},
+	{"Черкаський", "Черкаська", 49.45, 31.7},
+
+	{"Вижницький", "Чернівецька", 47.9, 25.1},
+	{"Дністровський", "Чернівецька", 48.3, 26.8},
+	{"Чернівецький", "Чернівецька", 48.25, 25.85},
+
+	{"Корюківський", "Чернігівська", 51.75, 32.5},
+	{"Новгород-Сіверський", "Чернігівська", 52.0, 33.1},
+	{"Ніжинський", "Чернігівська", 51.0, 31.9},
+	{"Прилуцький", "Чернігівська", 50.6, 32.6},
+	{"Чернігівський", "Чернігівська", 51.5, 31.3},
+
+	{"Бахчисарайський", "Автономна Республіка Крим", 44.75, 33.9},
+	{"Білогірський", "Автономна Республіка Крим", 45.1, 34.6},
+	{"Джанкойський", "Автономна Республіка Крим", 45.7, 34.5},
+	{"Євпаторійський", "Автономна Республіка Крим", 45.35, 33.0},
+	{"Керченський", "Автономна Республіка Крим", 45.3, 36.1},
+	{"Курманський", "Автономна Республіка Крим", 45.45, 34.1},
+	{"Перекопський", "Автономна Республіка Крим", 45.95, 33.8},
+	{"Сімферопольський", "Автономна Республіка Крим", 45.0, 34.0},
+	{"Феодосійський", "Автономна Республіка Крим", 45.1, 35.3},
+	{"Ялтинський", "Автономна Республіка Крим", 44.62, 34.2},
+}
+
+const (
+	// lonScale makes a degree of longitude as long as a degree of latitude in the middle of the country,
+	// for the split to go by distance.
+	lonScale = 0.6626 // cos(48.5°)
+	kmPerDeg = 111.2
+	// touchKm is how close raion borders get to count as touching; oblast borders are simplified and
+	// don't always meet exactly.
+	touchKm = 1.0
+)
+
+type point [2]float64 // [lon, lat], as GeoJSON has it
+
+type ring []point
+
+// polygon is an outer ring followed by holes.
+type polygon []ring
+
+func main() {
+	dir := flag.String("dir", "geo", "directory with areas.geojson and neighbors.json")
+	flag.Parse()
+
+	oblasts, err := readOblasts(filepath.Join(*dir, "areas.geojson"))
+	if err != nil {
+		log.Fatalf("read oblasts: %v", err)
+	}
+
+	oblastNeighbors, err := readNeighbors(filepath.Join(*dir, "neighbors.json"))
+	if err != nil {
+		log.Fatalf("read neighbors: %v", err)
+	}
+
+	cells := make([]polygon, len(raions))
+
+	for i, r := range raions {
+		oblast, ok := oblasts[r.oblast]
+		if !ok {
+			log.Fatalf("%s: unknown oblast %s", r.name, r.oblast)
+		}
+
+		if !oblast.contains(point{r.lon, r.lat}) {
+			log.Fatalf("%s: point %v, %v is out of %s", r.name, r.lat, r.lon, r.oblast)
+		}
+
+		cells[i] = cell(i, oblast)
+	}
+
+	if err := writeAreas(filepath.Join(*dir, "raions.geojson"), cells); err != nil {
+		log.Fatalf("write raions: %v", err)
+	}
+
+	if err := writeNeighbors(filepath.Join(*dir, "raion_neighbors.json"), adjacency(cells, oblastNeighbors)); err != nil {
+		log.Fatalf("write raion neighbors: %v", err)
+	}
+}
+
+// cell is the part of the oblast closer to the point of the raion than to the points of the other raions of it.
+func cell(i int, oblast polygon) polygon {
+	out := oblast
+
+	for j, other := range raions {
+		if j == i || other.oblast != raions[i].oblast {
+			continue
+		}
+
+		a, b := scaled(point{raions[i].lon, raions[i].lat}), scaled(point{other.lon, other.lat})
+		normal := point{b[0] - a[0], b[1] - a[1]}
+		offset := (b[0]*b[0] + b[1]*b[1] - a[0]*a[0] - a[1]*a[1]) / 2
+
+		var clipped polygon
+
+		for k, rng := range out {
+			rng = clip(rng, normal, offset)
+			if len(rng) < 3 {
+				if k == 0 {
+					log.Fatalf("%s: nothing left of %s", raions[i].name, raions[i].oblast)
+				}
+
+				continue
+			}
+
+			clipped = append(clipped, rng)
+		}
+
+		out = clipped
+	}
+
+	return out
+}
+
+// clip keeps the part of the ring where normal·p <= offset in the scaled plane; the ring comes closed,
+// as GeoJSON rings do, and goes out closed.
+func clip(rng ring, normal point, offset float64) ring {
+	inside := func(p point) bool {
+		s := scaled(p)
+
+		return normal[0]*s[0]+normal[1]*s[1] <= offset
+	}
+
+	crossing := func(p, q point) point {
+		sp, sq := scaled(p), scaled(q)
+		dp := normal[0]*sp[0] + normal[1]*sp[1] - offset
+		dq := normal[0]*sq[0] + normal[1]*sq[1] - offset
+		t := dp / (dp - dq)
+
+		return point{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])}
+	}
+
+	var out ring
+
+	open := rng[:len(rng)-1]
+
+	for k, current := range open {
+		previous := open[(k+len(open)-1)%len(open)]
+
+		switch {
+		case inside(current) && inside(previous):
+			out = append(out, current)
+		case inside(current):
+			out = append(out, crossing(previous, current), current)
+		case inside(previous):
+			out = append(out, crossing(previous, current))
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return append(out, out[0])
+}
+
+func scaled(p point) point {
+	return point{p[0] * lonScale, p[1]}
+}
+
+// adjacency links raions whose cells touch, when they are of the same oblast or of neighboring ones.
+func adjacency(cells []polygon, oblastNeighbors map[string][]string) map[string][]string {
+	near := func(a, b string) bool {
+		if a == b {
+			return true
+		}
+
+		for _, neighbor := range oblastNeighbors[a] {
+			if neighbor == b {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	out := make(map[string][]string, len(raions))
+
+	for i := range raions {
+		for j := i + 1; j < len(raions); j++ {
+			if !near(raions[i].oblast, raions[j].oblast) || distanceKm(cells[i], cells[j]) > touchKm {
+				continue
+			}
+
+			out[raions[i].name] = append(out[raions[i].name], raions[j].name)
+			out[raions[j].name] = append(out[raions[j].name], raions[i].name)
+		}
+	}
+
+	return out
+}
+
+// distanceKm is 0 for polygons overlapping, otherwise the shortest way from a vertex of one to the border
+// of the other.
+func distanceKm(a, b polygon) float64 {
+	if b.contains(a[0][0]) || a.contains(b[0][0]) {
+		return 0
+	}
+
+	min := math.Inf(1)
+
+	for _, pair := range [][2]polygon{{a, b}, {b, a}} {
+		for _, p := range pair[0][0] {
+			outer := pair[1][0]
+			for k := 1; k < len(outer); k++ {
+				if d := segmentDistanceKm(p, outer[k-1], outer[k]); d < min {
+					min = d
+				}
+			}
+		}
+	}
+
+	return min
+}
+
+func segmentDistanceKm(p, a, b point) float64 {
+	sp, sa, sb := scaled(p), scaled(a), scaled(b)
+	dx, dy := sb[0]-sa[0], sb[1]-sa[1]
+
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((sp[0]-sa[0])*dx+(sp[1]-sa[1])*dy)/length))
+	}
+
+	return math.Hypot(sp[0]-sa[0]-t*dx, sp[1]-sa[1]-t*dy) * kmPerDeg
+}
+
+func (r polygon) contains(p point) bool {
+	if len(r) == 0 || !r[0].contains(p) {
+		return false
+	}
+
+	for _, hole := range r[1:] {
+		if hole.contains(p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r ring) contains(p point) bool {
+	inside := false
+
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		if (r[i][1] > p[1]) != (r[j][1] > p[1]) &&
+			p[0] < (r[j][0]-r[i][0])*(p[1]-r[i][1])/(r[j][1]-r[i][1])+r[i][0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+func readOblasts(path string) (map[string]polygon, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var collection struct {
+		Features []struct {
+			Properties struct {
+				Name  string `json:"name"`
+				Level string `json:"level"`
+			} `json:"properties"`
+			Geometry struct {
+				Type        string  `json:"type"`
+				Coordinates polygon `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+
+	if err := json.Unmarshal(bts, &collection); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	out := map[string]polygon{}
+
+	for _, feature := range collection.Features {
+		if feature.Properties.Level != "oblast" {
+			continue
+		}
+
+		if feature.Geometry.Type != "Polygon" {
+			return nil, fmt.Errorf("%s: unsupported geometry %s", feature.Properties.Name, feature.Geometry.Type)
+		}
+
+		out[feature.Properties.Name] = feature.Geometry.Coordinates
+	}
+
+	return out, nil
+}
+
+func readNeighbors(path string) (map[string][]string, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var out map[string][]string
+	if err := json.Unmarshal(bts, &out); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	return out, nil
+}
+
+// writeAreas writes a feature a line, like the hand-kept areas.geojson; coordinates are rounded to about 100 m.
+func writeAreas(path string, cells []polygon) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"type":"FeatureCollection","features":[` + "\n")
+
+	for i, r := range raions {
+		rings := make([]string, 0, len(cells[i]))
+
+		for _, rng := range cells[i] {
+			points := make([]string, 0, len(rng))
+			for _, p := range rng {
+				points = append(points, fmt.Sprintf("[%s,%s]", round(p[0]), round(p[1])))
+			}
+
+			rings = append(rings, "["+strings.Join(points, ",")+"]")
+		}
+
+		name, err := json.Marshal(r.name)
+		if err != nil {
+			return fmt.Errorf("json marshal: %w", err)
+		}
+
+		parent, err := json.Marshal(r.oblast)
+		if err != nil {
+			return fmt.Errorf("json marshal: %w", err)
+		}
+
+		fmt.Fprintf(&buf,
+			`{"type":"Feature","properties":{"name":%s,"level":"raion","parent":%s},"geometry":{"type":"Polygon","coordinates":[%s]}}`,
+			name, parent, strings.Join(rings, ","),
+		)
+
+		if i < len(raions)-1 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("]}\n")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+// writeNeighbors writes an area a line, like the hand-kept neighbors.json, in the order of raions.
+func writeNeighbors(path string, graph map[string][]string) error {
+	var buf bytes.Buffer
+
+	buf.WriteString("{\n")
+
+	for i, r := range raions {
+		neighbors := graph[r.name]
+		sort.Strings(neighbors)
+
+		key, err := json.Marshal(r.name)
+		if err != nil {
+			return fmt.Errorf("json marshal: %w", err)
+		}
+
+		list, err := json.Marshal(neighbors)
+		if err != nil {
+			return fmt.Errorf("json marshal: %w", err)
+		}
+
+		fmt.Fprintf(&buf, "  %s: %s", key, strings.ReplaceAll(string(list), `","`, `", "`))
+
+		if i < len(raions)-1 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("}\n")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+func round(v float64) string {
+	return fmt.Sprintf("%.3f", math.Round(v*1000)/1000)
+}
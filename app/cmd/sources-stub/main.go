@@ -0,0 +1,27 @@
+// Command sources-stub serves recorded alert sources, so the bot can be pointed to it with SOURCES_BASE_URL.
+package main
+
+import (
+	"closealerts/app/clients"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8081", "address to listen on")
+	dir := flag.String("dir", "./recordings", "directory with the recordings")
+	speed := flag.Float64("speed", 1, "how many times faster than the recorded pace to replay")
+	flag.Parse()
+
+	replay, err := clients.NewReplayTransport(*dir, *speed)
+	if err != nil {
+		log.Fatalf("new replay transport: %v", err)
+	}
+
+	log.Printf("serving %s at %s, %gx", *dir, *addr, *speed)
+
+	if err := http.ListenAndServe(*addr, replay); err != nil {
+		log.Fatalf("listen and serve: %v", err)
+	}
+}
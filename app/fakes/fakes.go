@@ -0,0 +1,402 @@
+// Package fakes has in-memory implementations of the interfaces services and handlers depend on,
+// for running them without Telegram and a database.
+package fakes
+
+import (
+	"closealerts/app/handlers"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/services"
+	"closealerts/app/tracing"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+var (
+	_ handlers.Bot               = (*Sender)(nil)
+	_ services.AlertStore        = (*AlertStore)(nil)
+	_ services.SubscriptionStore = (*SubscriptionStore)(nil)
+	_ services.NotifyStore       = (*SubscriptionStore)(nil)
+	_ services.TrackingStore     = (*SubscriptionStore)(nil)
+	_ services.MapStore          = (*MapStore)(nil)
+	_ services.LanguageStore     = ChatLanguages(nil)
+	_ services.TemplateStore     = ChatTemplates(nil)
+	_ services.StatusStore       = StatusMessages(nil)
+)
+
+// Sender records what is sent instead of sending; it also fits handlers.Bot.
+type Sender struct {
+	mu     sync.Mutex
+	sent   []tgbotapi.Chattable
+	Admins map[[2]int64]bool
+	// Err fails every Send when set.
+	Err error
+}
+
+func (r *Sender) Send(_ context.Context, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if r.Err != nil {
+		return tgbotapi.Message{}, r.Err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sent = append(r.sent, chattable)
+
+	return tgbotapi.Message{MessageID: len(r.sent)}, nil
+}
+
+func (r *Sender) MaybeSend(ctx context.Context, chattable tgbotapi.Chattable) {
+	_, _ = r.Send(ctx, chattable)
+}
+
+func (r *Sender) MaybeSendText(ctx context.Context, chatID int64, text string) {
+	_, _ = r.Send(ctx, tgbotapi.NewMessage(chatID, text))
+}
+
+func (r *Sender) Username() string {
+	return "fake_bot"
+}
+
+func (r *Sender) IsChatAdmin(_ context.Context, chatID, userID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.Admins[[2]int64{chatID, userID}], nil
+}
+
+func (r *Sender) Sent() []tgbotapi.Chattable {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]tgbotapi.Chattable(nil), r.sent...)
+}
+
+// Texts returns texts of the plain messages sent to the chat.
+func (r *Sender) Texts(chatID int64) types.Stringies {
+	var out types.Stringies
+
+	for _, chattable := range r.Sent() {
+		if msg, ok := chattable.(tgbotapi.MessageConfig); ok && msg.ChatID == chatID {
+			out = append(out, msg.Text)
+		}
+	}
+
+	return out
+}
+
+// Spans installs a global tracer provider keeping spans in memory. Spans are batched,
+// so force flush the provider before looking at them.
+func Spans() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+
+	return tracing.NewProvider(exporter, 1), exporter
+}
+
+type AlertStore struct {
+	mu          sync.Mutex
+	alerts      []types2.Alert
+	refreshedAt time.Time
+}
+
+func (r *AlertStore) ReplaceAlerts(_ context.Context, alerts []types2.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alerts = append([]types2.Alert(nil), alerts...)
+	r.refreshedAt = time.Now()
+
+	return nil
+}
+
+func (r *AlertStore) RefreshedAt(context.Context) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.refreshedAt, nil
+}
+
+func (r *AlertStore) GetActive(context.Context) ([]types2.Alert, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return types2.Alerts(r.alerts).Real(), nil
+}
+
+// SubscriptionStore mirrors repositories.Notification; Testers stands for the tester flag of chats.
+type SubscriptionStore struct {
+	mu            sync.Mutex
+	notifications types2.Notifications
+	Testers       map[int64]bool
+}
+
+func (r *SubscriptionStore) Track(_ context.Context, chatID int64, area string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.find(chatID, area); ok {
+		return fmt.Errorf("%d-%s: %w", chatID, area, types.ErrLinkExists)
+	}
+
+	r.notifications = append(r.notifications, types2.Notification{ChatID: chatID, Area: area})
+
+	return nil
+}
+
+func (r *SubscriptionStore) TrackNear(_ context.Context, chatID int64, near string, areas []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var added []string
+
+	for _, area := range areas {
+		if _, ok := r.find(chatID, area); ok {
+			continue
+		}
+
+		r.notifications = append(r.notifications, types2.Notification{ChatID: chatID, Area: area, Near: near})
+		added = append(added, area)
+	}
+
+	return added, nil
+}
+
+func (r *SubscriptionStore) StopNear(_ context.Context, chatID int64, near string) error {
+	r.remove(func(n types2.Notification) bool { return n.ChatID == chatID && n.Near == near })
+
+	return nil
+}
+
+func (r *SubscriptionStore) Tracking(_ context.Context, id int64) ([]types2.Notification, error) {
+	return r.filter(func(n types2.Notification) bool { return n.ChatID == id }), nil
+}
+
+func (r *SubscriptionStore) ChatIDs(_ context.Context, areas []string) ([]int64, error) {
+	seen := map[int64]struct{}{}
+
+	var ids []int64
+
+	for _, n := range r.filter(func(n types2.Notification) bool {
+		return len(areas) == 0 || types.Stringies(areas).Contains(n.Area)
+	}) {
+		if _, ok := seen[n.ChatID]; !ok {
+			seen[n.ChatID] = struct{}{}
+			ids = append(ids, n.ChatID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *SubscriptionStore) Stop(_ context.Context, id int64, area string) error {
+	r.remove(func(n types2.Notification) bool { return n.ChatID == id && n.Area == area })
+
+	return nil
+}
+
+func (r *SubscriptionStore) Eligible(_ context.Context, alerts []types2.Alert) (types2.Notifications, error) {
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	realAreas := types2.Alerts(alerts).Real().Areas()
+
+	out := r.filter(func(n types2.Notification) bool {
+		return (!n.Notified && r.active(n, alerts)) || (n.NotifiedSimulated && realAreas.Contains(n.Area))
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ChatID < out[j].ChatID })
+
+	return out, nil
+}
+
+func (r *SubscriptionStore) Notified(_ context.Context, eligible types2.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.find(eligible.ChatID, eligible.Area); ok {
+		now := time.Now()
+		r.notifications[i].Notified, r.notifications[i].NotifiedAt = true, &now
+		r.notifications[i].NotifiedSimulated = eligible.NotifiedSimulated
+	}
+
+	return nil
+}
+
+func (r *SubscriptionStore) Unmark(_ context.Context, alerts []types2.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	for i, n := range r.notifications {
+		if r.ended(n, alerts) {
+			r.notifications[i].Notified, r.notifications[i].ClearedAt = false, &now
+			r.notifications[i].NotifiedSimulated = false
+		}
+	}
+
+	return nil
+}
+
+func (r *SubscriptionStore) AlertEnded(_ context.Context, alerts []types2.Alert) (types2.Notifications, error) {
+	return r.filter(func(n types2.Notification) bool { return r.ended(n, alerts) }), nil
+}
+
+// ended is the alert of the notified subscription ending as the kind it was notified about.
+func (r *SubscriptionStore) ended(n types2.Notification, alerts types2.Alerts) bool {
+	if !n.Notified {
+		return false
+	}
+
+	if n.NotifiedSimulated {
+		return !r.active(n, alerts)
+	}
+
+	return !alerts.Real().Areas().Contains(n.Area)
+}
+
+// active is what repositories.Notification checks in SQL: simulated alerts only count for testers.
+func (r *SubscriptionStore) active(n types2.Notification, alerts types2.Alerts) bool {
+	for _, alert := range alerts {
+		if alert.ID == n.Area && (!alert.Simulated || r.Testers[n.ChatID]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *SubscriptionStore) find(chatID int64, area string) (int, bool) {
+	for i, n := range r.notifications {
+		if n.ChatID == chatID && n.Area == area {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (r *SubscriptionStore) filter(keep func(types2.Notification) bool) types2.Notifications {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out types2.Notifications
+
+	for _, n := range r.notifications {
+		if keep(n) {
+			out = append(out, n)
+		}
+	}
+
+	return out
+}
+
+func (r *SubscriptionStore) remove(drop func(types2.Notification) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.notifications[:0]
+
+	for _, n := range r.notifications {
+		if !drop(n) {
+			kept = append(kept, n)
+		}
+	}
+
+	r.notifications = kept
+}
+
+// ChatLanguages maps chat IDs to their languages.
+type ChatLanguages map[int64]string
+
+func (r ChatLanguages) Languages(_ context.Context, ids []int64) (map[int64]string, error) {
+	out := make(map[int64]string, len(ids))
+
+	for _, id := range ids {
+		if lang, ok := r[id]; ok {
+			out[id] = lang
+		}
+	}
+
+	return out, nil
+}
+
+// ChatTemplates returns the templates of the asked chats out of the list.
+type ChatTemplates types2.ChatTemplates
+
+func (r ChatTemplates) Templates(_ context.Context, ids []int64) (types2.ChatTemplates, error) {
+	var out types2.ChatTemplates
+
+	for _, template := range r {
+		for _, id := range ids {
+			if template.ChatID == id {
+				out = append(out, template)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// AreaLevels maps area names to their levels.
+type AreaLevels map[string]string
+
+func (r AreaLevels) Level(name string) string {
+	return r[name]
+}
+
+// StatusMessages returns the status messages of the asked chats out of the list.
+type StatusMessages types2.StatusMessages
+
+func (r StatusMessages) StatusMessages(_ context.Context, ids []int64) (types2.StatusMessages, error) {
+	var out types2.StatusMessages
+
+	for _, msg := range r {
+		for _, id := range ids {
+			if msg.ChatID == id {
+				out = append(out, msg)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+type MapStore struct {
+	mu   sync.Mutex
+	maps map[string]types2.Map
+}
+
+func (r *MapStore) Get(_ context.Context, alertsKey string) (types2.Map, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mapz, ok := r.maps[alertsKey]
+	if !ok {
+		return types2.Map{}, fmt.Errorf("select: %w", gorm.ErrRecordNotFound)
+	}
+
+	return mapz, nil
+}
+
+func (r *MapStore) Save(_ context.Context, key string, fileID string) (types2.Map, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maps == nil {
+		r.maps = map[string]types2.Map{}
+	}
+
+	mapz := types2.Map{ID: int64(len(r.maps) + 1), AlertsKey: key, FileID: fileID}
+	r.maps[key] = mapz
+
+	return mapz, nil
+}
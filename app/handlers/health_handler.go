@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"closealerts/app/services"
+	"encoding/json"
+	"net/http"
+)
+
+type HealthHandler struct {
+	health services.Health
+}
+
+func NewHealth(health services.Health) HealthHandler {
+	return HealthHandler{health: health}
+}
+
+// Healthz tells the process is alive and serving.
+func (h HealthHandler) Healthz(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte(`ok`))
+}
+
+// Readyz reports the state of the subsystems, failing with 503 when alert data is stale or the database is down.
+func (h HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Report(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"closealerts/app/i18n"
+	"closealerts/app/services"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+type Privilege string
+
+// Privileges besides PrivNone and PrivChatAdmin are permissions granted to users.
+const (
+	PrivNone          Privilege = ""
+	PrivChatAdmin     Privilege = "chat_admin"
+	PrivAdmin         Privilege = types.PermAdmin
+	PrivSendFakeEvent Privilege = types.PermSendFakeEvent
+	PrivBroadcast     Privilege = types.PermSendBroadcast
+)
+
+type CommandHandler func(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error)
+
+type Command struct {
+	Name string
+	// Description by language code, from the i18n catalog; commands without one are not published.
+	Description map[string]string
+	Privilege   Privilege
+	// ChatTypes the command works in; any chat if empty.
+	ChatTypes []string
+	// FollowUp commands take the next plain message as their arguments when called without any.
+	FollowUp bool
+	Handler  CommandHandler
+}
+
+func (r Command) AllowedIn(chat *tgbotapi.Chat) bool {
+	if len(r.ChatTypes) == 0 {
+		return true
+	}
+
+	for _, chatType := range r.ChatTypes {
+		if chatType == chat.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Router struct {
+	commands []Command
+	index    map[string]Command
+	followUp CommandHandler
+}
+
+func NewRouter(commander services.Commander) Router {
+	commands := []Command{
+		{
+			Name:        "map",
+			Description: i18n.Texts("command.map"),
+			Handler:     handler(commander.Map),
+		},
+		{
+			Name:        "alerts",
+			Description: i18n.Texts("command.alerts"),
+			Handler:     handler(commander.Alerts),
+		},
+		{
+			Name:        "areas",
+			Description: i18n.Texts("command.areas"),
+			Handler:     handler(commander.Areas),
+		},
+		{
+			Name:        "near",
+			Description: i18n.Texts("command.near"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Near),
+		},
+		{
+			Name:        "location",
+			Description: i18n.Texts("command.location"),
+			ChatTypes:   []string{"private"},
+			Handler:     handler(commander.Location),
+		},
+		{
+			Name:        "track",
+			Description: i18n.Texts("command.track"),
+			Privilege:   PrivChatAdmin,
+			FollowUp:    true,
+			Handler:     handler(commander.Track),
+		},
+		{
+			Name:        "tracking",
+			Description: i18n.Texts("command.tracking"),
+			Handler:     handler(commander.Tracking),
+		},
+		{
+			Name:        "stop",
+			Description: i18n.Texts("command.stop"),
+			Privilege:   PrivChatAdmin,
+			FollowUp:    true,
+			Handler:     handler(commander.Stop),
+		},
+		{
+			Name:        "tester",
+			Description: i18n.Texts("command.tester"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Tester),
+		},
+		{
+			Name:        "lang",
+			Description: i18n.Texts("command.lang"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Lang),
+		},
+		{
+			Name:        "status",
+			Description: i18n.Texts("command.status"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Status),
+		},
+		{
+			Name:        "digest",
+			Description: i18n.Texts("command.digest"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Digest),
+		},
+		{
+			Name:        "template",
+			Description: i18n.Texts("command.template"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.Template),
+		},
+		{
+			Name:        "template_preview",
+			Description: i18n.Texts("command.template_preview"),
+			Privilege:   PrivChatAdmin,
+			Handler:     handler(commander.TemplatePreview),
+		},
+		{
+			Name:        "start",
+			Description: i18n.Texts("command.start"),
+			Handler:     handler(commander.Start),
+		},
+		{
+			Name:        "cancel",
+			Description: i18n.Texts("command.cancel"),
+			Handler:     handler(commander.Cancel),
+		},
+		{
+			Name:        "grant",
+			Description: i18n.Texts("command.grant"),
+			Privilege:   PrivAdmin,
+			Handler:     handler(commander.Grant),
+		},
+		{
+			Name:        "revoke",
+			Description: i18n.Texts("command.revoke"),
+			Privilege:   PrivAdmin,
+			Handler:     handler(commander.Revoke),
+		},
+		{
+			Name:        "admins",
+			Description: i18n.Texts("command.admins"),
+			Privilege:   PrivAdmin,
+			Handler:     handler(commander.Admins),
+		},
+		{
+			Name:        "admin_audit",
+			Description: i18n.Texts("command.admin_audit"),
+			Privilege:   PrivAdmin,
+			Handler:     handler(commander.AdminAudit),
+		},
+		{
+			Name:        "admin_log_level",
+			Description: i18n.Texts("command.admin_log_level"),
+			Privilege:   PrivAdmin,
+			Handler:     handler(commander.AdminLogLevel),
+		},
+		{
+			Name:        "admin_fake_alert_in",
+			Description: i18n.Texts("command.admin_fake_alert_in"),
+			Privilege:   PrivSendFakeEvent,
+			ChatTypes:   []string{"private"},
+			Handler:     handler(commander.AdminFakeAlertIn),
+		},
+		{
+			Name:        "admin_fake_all_clear",
+			Description: i18n.Texts("command.admin_fake_all_clear"),
+			Privilege:   PrivSendFakeEvent,
+			ChatTypes:   []string{"private"},
+			Handler:     handler(commander.AdminFakeAllClear),
+		},
+		{
+			Name:        "admin_fake_scenario",
+			Description: i18n.Texts("command.admin_fake_scenario"),
+			Privilege:   PrivSendFakeEvent,
+			ChatTypes:   []string{"private"},
+			Handler:     handler(commander.AdminFakeScenario),
+		},
+		{
+			Name:        "admin_broadcast",
+			Description: i18n.Texts("command.admin_broadcast"),
+			Privilege:   PrivBroadcast,
+			ChatTypes:   []string{"private"},
+			FollowUp:    true,
+			Handler:     handler(commander.Broadcast),
+		},
+		{
+			Name:        "admin_schedule",
+			Description: i18n.Texts("command.admin_schedule"),
+			Privilege:   PrivBroadcast,
+			ChatTypes:   []string{"private"},
+			FollowUp:    true,
+			Handler:     handler(commander.Schedule),
+		},
+		{
+			Name:        "admin_schedules",
+			Description: i18n.Texts("command.admin_schedules"),
+			Privilege:   PrivBroadcast,
+			ChatTypes:   []string{"private"},
+			Handler:     handler(commander.Schedules),
+		},
+	}
+
+	index := make(map[string]Command, len(commands))
+	for _, command := range commands {
+		index[command.Name] = command
+	}
+
+	return Router{commands: commands, index: index, followUp: handler(commander.Continue)}
+}
+
+func handler[T tgbotapi.Chattable](fn func(context.Context, *tgbotapi.Message, string) (T, error)) CommandHandler {
+	return func(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+		return fn(ctx, msg, args)
+	}
+}
+
+func (r Router) Command(name string) (Command, bool) {
+	command, ok := r.index[name]
+
+	return command, ok
+}
+
+// FollowUp returns the command whose conversation a plain message continues, if there is such.
+// Its handler feeds the message to the conversation.
+func (r Router) FollowUp(flow string) (Command, bool) {
+	command, ok := r.index[flow]
+	if !ok || !command.FollowUp {
+		return Command{}, false
+	}
+
+	command.Handler = r.followUp
+
+	return command, true
+}
+
+// BotCommands lists published commands requiring one of the given privileges, described in the language.
+func (r Router) BotCommands(lang string, privs ...Privilege) []tgbotapi.BotCommand {
+	var out []tgbotapi.BotCommand
+
+	for _, command := range r.commands {
+		if !hasPrivilege(privs, command.Privilege) {
+			continue
+		}
+
+		desc, ok := command.Description[lang]
+		if !ok {
+			desc, ok = command.Description[i18n.Default]
+		}
+
+		if !ok {
+			continue
+		}
+
+		out = append(out, tgbotapi.BotCommand{Command: command.Name, Description: desc})
+	}
+
+	return out
+}
+
+func (r Router) Languages() []string {
+	return i18n.Languages()
+}
+
+func hasPrivilege(privs []Privilege, priv Privilege) bool {
+	for _, p := range privs {
+		if p == priv {
+			return true
+		}
+	}
+
+	return false
+}
+
+// publicPrivileges are the ones everybody has: non-admins of groups just get a refusal from the bot.
+var publicPrivileges = []Privilege{PrivNone, PrivChatAdmin}
+
+// Requester makes Bot API requests that are not messages, like setting the commands; clients.Telegram implements it.
+type Requester interface {
+	Request(ctx context.Context, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
+type commandScope struct {
+	scope tgbotapi.BotCommandScope
+	privs []Privilege
+}
+
+// RegisterTelegramCommands publishes the public commands for everyone, and full lists in private chats of users
+// having permissions.
+func RegisterTelegramCommands(
+	log *zap.SugaredLogger, bot Requester, router Router, permissions PermissionReader,
+) error {
+	ctx := context.Background()
+	scopes := []commandScope{{scope: tgbotapi.NewBotCommandScopeDefault(), privs: publicPrivileges}}
+
+	list, err := permissions.All(ctx)
+	if err != nil {
+		log.Errorw("set commands: list permissions", "err", err)
+	}
+
+	userIDs := permissions.BootstrapIDs()
+	for userID := range list.GroupByUserID() {
+		if !permissions.Bootstrap(userID) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		granted, err := permissions.Of(ctx, userID)
+		if err != nil {
+			log.Errorw("set commands: permissions of", "user_id", userID, "err", err)
+
+			continue
+		}
+
+		scopes = append(scopes, commandScope{
+			scope: tgbotapi.NewBotCommandScopeChat(userID),
+			privs: userPrivileges(granted),
+		})
+	}
+
+	for _, scope := range scopes {
+		requests := []tgbotapi.SetMyCommandsConfig{
+			tgbotapi.NewSetMyCommandsWithScope(scope.scope, router.BotCommands(i18n.Default, scope.privs...)...),
+		}
+
+		for _, lang := range router.Languages() {
+			requests = append(requests, tgbotapi.NewSetMyCommandsWithScopeAndLanguage(
+				scope.scope, lang, router.BotCommands(lang, scope.privs...)...,
+			))
+		}
+
+		for _, request := range requests {
+			resp, err := bot.Request(ctx, request)
+			if err != nil {
+				log.Errorw("set commands", "scope", scope.scope.Type, "lang", request.LanguageCode, "err", err)
+
+				continue
+			}
+
+			log.Infow(
+				"set commands",
+				"scope", scope.scope.Type,
+				"chat_id", scope.scope.ChatID,
+				"lang", request.LanguageCode,
+				"ok", resp.Ok,
+				"desc", resp.Description,
+			)
+		}
+	}
+
+	return nil
+}
+
+func userPrivileges(permissions types.Stringies) []Privilege {
+	privs := make([]Privilege, len(publicPrivileges), len(publicPrivileges)+len(permissions))
+	copy(privs, publicPrivileges)
+
+	for _, permission := range permissions {
+		privs = append(privs, Privilege(permission))
+	}
+
+	return privs
+}
+
+// allowed checks privileges of the user, not of the chat, so group members do not share them.
+func (r UpdateHandler) allowed(ctx context.Context, command Command, msg *tgbotapi.Message) (bool, error) {
+	switch command.Privilege {
+	case PrivNone:
+		return true, nil
+
+	case PrivChatAdmin:
+		ok, err := r.canConfigure(ctx, msg.Chat, msg.From, msg.SenderChat)
+		if err != nil {
+			return false, fmt.Errorf("can configure: %w", err)
+		}
+
+		return ok, nil
+	}
+
+	if msg.From == nil {
+		return false, nil
+	}
+
+	ok, err := r.permissions.Has(ctx, msg.From.ID, string(command.Privilege))
+	if err != nil {
+		return false, fmt.Errorf("has: %w", err)
+	}
+
+	return ok, nil
+}
@@ -1,45 +1,85 @@
 package handlers
 
 import (
-	"closealerts/app/clients"
+	"closealerts/app/i18n"
+	"closealerts/app/metrics"
+	types2 "closealerts/app/repositories/types"
 	"closealerts/app/services"
+	"closealerts/app/tracing"
 	"closealerts/app/types"
 	"context"
 	"fmt"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// Bot is the part of clients.Telegram updates are handled with.
+type Bot interface {
+	services.Sender
+	Username() string
+	IsChatAdmin(ctx context.Context, chatID, userID int64) (bool, error)
+}
+
+// ChatRegistry keeps the chats updates come from; services.Chats implements it.
+type ChatRegistry interface {
+	FirstOrCreate(ctx context.Context, tgChat *tgbotapi.Chat, languageCode string) (types2.Chat, error)
+	Language(ctx context.Context, id int64) (string, error)
+}
+
+// PendingConversations tells the flow a chat is in the middle of; services.Conversations implements it.
+type PendingConversations interface {
+	Pending(ctx context.Context, chatID int64) (string, bool, error)
+}
+
+// PermissionReader tells the permissions of users; services.Permissions implements it.
+type PermissionReader interface {
+	Bootstrap(userID int64) bool
+	BootstrapIDs() []int64
+	All(ctx context.Context) (types2.Permissions, error)
+	Of(ctx context.Context, userID int64) (types.Stringies, error)
+	Has(ctx context.Context, userID int64, permission string) (bool, error)
+}
+
 type UpdateHandler struct {
-	bot          clients.Telegram
-	alerts       services.Alerts
-	log          *zap.SugaredLogger
-	notification services.Notification
-	chat         services.Chats
-	commander    services.Commander
+	bot           Bot
+	log           *zap.SugaredLogger
+	chat          ChatRegistry
+	commander     services.Commander
+	router        Router
+	conversations PendingConversations
+	permissions   PermissionReader
 }
 
 func NewUpdate(
 	log *zap.SugaredLogger,
-	bot clients.Telegram,
-	alerts services.Alerts,
-	notification services.Notification,
-	chat services.Chats,
+	bot Bot,
+	chat ChatRegistry,
 	commander services.Commander,
+	router Router,
+	conversations PendingConversations,
+	permissions PermissionReader,
 ) UpdateHandler {
 	return UpdateHandler{
-		log:          log,
-		bot:          bot,
-		alerts:       alerts,
-		chat:         chat,
-		notification: notification,
-		commander:    commander,
+		log:           log,
+		bot:           bot,
+		chat:          chat,
+		commander:     commander,
+		router:        router,
+		conversations: conversations,
+		permissions:   permissions,
 	}
 }
 
 func (r UpdateHandler) Handle(ctx context.Context, update types.Update) {
+	ctx, span := tracing.Start(trace.ContextWithSpanContext(ctx, update.SpanContext), "handle update",
+		attribute.Int("update.id", update.UpdateID),
+	)
+	defer span.End()
+
 	msg := update.Message
 	cq := update.CallbackQuery
 
@@ -47,97 +87,147 @@ func (r UpdateHandler) Handle(ctx context.Context, update types.Update) {
 	case msg != nil:
 		r.handleMessage(ctx, msg)
 
+	case update.ChannelPost != nil:
+		r.handleMessage(ctx, update.ChannelPost)
+
 	case cq != nil:
 		r.handleCallbackQuery(ctx, cq)
+
+	case update.MyChatMember != nil:
+		r.handleMyChatMember(ctx, update.MyChatMember)
 	}
 }
 
 func (r UpdateHandler) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
 	r.log.Infow("msg", "user", msg.Chat.UserName, "text", msg.Text)
 
-	chat, err := r.chat.FirstOrCreate(ctx, msg.Chat)
-	if err != nil {
-		r.log.Errorw("load or create chat", "err", err)
+	if msg.From != nil {
+		ctx = types.WithActor(ctx, msg.From.ID)
+	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("chat.id", msg.Chat.ID))
+
+	if msg.IsCommand() && !r.addressedToBot(msg) {
 		return
 	}
 
-	command := chat.Command
-	args := msg.Text
-	clearCmd := true
+	// channels get registered by any post, so they can be broadcast to
+	chat, err := r.chat.FirstOrCreate(ctx, msg.Chat, languageCode(msg.From))
+	if err != nil {
+		r.log.Errorw("load or create chat", "err", err)
 
-	if msg.IsCommand() {
-		command = msg.Command()
-		args = msg.CommandArguments()
-		clearCmd = false
+		return
 	}
 
-	var chattable tgbotapi.Chattable
+	ctx = i18n.WithLang(ctx, chatLanguage(chat.Language, msg.From))
+	p := i18n.From(ctx)
 
-	switch command {
-	case "start":
-		chattable, err = r.commander.Start(ctx, msg, args)
+	var (
+		command Command
+		ok      bool
+		args    = msg.Text
+	)
 
-	case "areas":
-		chattable, err = r.commander.Areas(ctx, msg, args)
+	switch {
+	case msg.IsCommand():
+		command, ok = r.router.Command(msg.Command())
+		args = msg.CommandArguments()
 
-	case "alerts":
-		chattable, err = r.commander.Alerts(ctx, msg, args)
+	case msg.Chat.IsPrivate() && (msg.Location != nil || msg.Venue != nil):
+		command, ok = r.router.Command("location")
+		args = ""
 
-	case "track":
-		chattable, err = r.commander.Track(ctx, msg, args)
+	default:
+		flow, pending, err := r.conversations.Pending(ctx, chat.ID)
+		if err != nil {
+			r.log.Errorw("pending conversation", "err", err)
 
-	case "tracking":
-		chattable, err = r.commander.Tracking(ctx, msg, args)
+			return
+		}
 
-	case "stop":
-		chattable, err = r.commander.Stop(ctx, msg, args)
+		if pending {
+			command, ok = r.router.FollowUp(flow)
+		}
+	}
 
-	case "map":
-		chattable, err = r.commander.Map(ctx, msg, args)
+	if !ok {
+		// people in groups talk to each other, not to the bot, and commands may be meant for other bots
+		if !msg.Chat.IsPrivate() {
+			return
+		}
 
-	case "auth":
-		chattable, err = r.commander.Auth(ctx, msg, args)
+		r.bot.MaybeSendText(ctx, chat.ID, p.T("update.unknown_command"))
 
-	case "admin_fake_alert_in":
-		if !chat.PrivSendFakeEvent {
-			chattable = tgbotapi.NewMessage(chat.ID, "Please auth first")
+		return
+	}
 
-			break
-		}
+	if !command.AllowedIn(msg.Chat) {
+		r.bot.MaybeSendText(ctx, chat.ID, p.T("update.wrong_chat"))
+
+		return
+	}
 
-		chattable, err = r.commander.AdminFakeAlertIn(ctx, msg, args)
+	allowed, err := r.allowed(ctx, command, msg)
+	if err != nil {
+		r.log.Errorw("allowed", "command", command.Name, "err", err)
 
-	case "admin_broadcast":
-		if !chat.PrivBroadcast {
-			chattable = tgbotapi.NewMessage(chat.ID, "Please auth first")
+		return
+	}
 
-			break
+	if !allowed {
+		// a follow-up from someone else in a group should not break the flow
+		if msg.IsCommand() {
+			r.bot.MaybeSendText(ctx, chat.ID, deniedText(p, command.Privilege))
 		}
 
-		chattable, err = r.commander.Broadcast(ctx, msg, args)
-
-	default:
-		chattable = tgbotapi.NewMessage(chat.ID, "я такої команди не знаю")
+		return
 	}
 
+	metrics.Commands.WithLabelValues(command.Name).Inc()
+
+	cmdCtx, span := tracing.Start(ctx, "command "+command.Name,
+		attribute.String("command", command.Name),
+		attribute.Int64("chat.id", chat.ID),
+	)
+	chattable, err := command.Handler(cmdCtx, msg, args)
+	tracing.End(span, err)
+
 	if err != nil {
-		r.log.Errorw(command, "err", err)
-		r.bot.MaybeSendText(ctx, chat.ID, "в мене щось пішло не так, спробуй ще раз")
+		r.log.Errorw(command.Name, "err", err)
+		r.bot.MaybeSendText(ctx, chat.ID, p.T("update.failed"))
 	} else {
 		// skip sending message if message text is empty
 		if msgConfig, ok := chattable.(tgbotapi.MessageConfig); !ok || len(msgConfig.Text) > 0 {
 			r.bot.MaybeSend(ctx, chattable)
 		}
 	}
+}
 
-	if clearCmd {
-		if err := r.chat.ClearCommand(ctx, chat.ID); err != nil {
-			r.log.Errorw("clear command", "err", err)
+func deniedText(p i18n.Printer, priv Privilege) string {
+	if priv == PrivChatAdmin {
+		return p.T("update.chat_admins")
+	}
 
-			return
-		}
+	return p.T("update.denied")
+}
+
+// chatLanguage is the one chosen for the chat; chats without one are answered in the language of the sender.
+func chatLanguage(chatLang string, from *tgbotapi.User) string {
+	if len(chatLang) > 0 {
+		return chatLang
+	}
+
+	lang, _ := i18n.Match(languageCode(from))
+
+	return lang
+}
+
+func languageCode(from *tgbotapi.User) string {
+	if from == nil {
+		return ""
 	}
+
+	return from.LanguageCode
 }
 
 func (r UpdateHandler) handleCallbackQuery(ctx context.Context, cq *tgbotapi.CallbackQuery) {
@@ -145,6 +235,15 @@ func (r UpdateHandler) handleCallbackQuery(ctx context.Context, cq *tgbotapi.Cal
 	chat := msg.Chat
 	split := strings.SplitN(cq.Data, ":", 2)
 
+	if cq.From != nil {
+		ctx = types.WithActor(ctx, cq.From.ID)
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int64("chat.id", chat.ID),
+		attribute.String("callback.data", cq.Data),
+	)
+
 	if len(split) != 2 {
 		r.log.Errorw(
 			"bad data",
@@ -156,15 +255,43 @@ func (r UpdateHandler) handleCallbackQuery(ctx context.Context, cq *tgbotapi.Cal
 		return
 	}
 
+	lang, err := r.chat.Language(ctx, chat.ID)
+	if err != nil {
+		r.log.Errorw("chat language", "err", err)
+	}
+
+	ctx = i18n.WithLang(ctx, chatLanguage(lang, cq.From))
+
+	allowed, err := r.canConfigure(ctx, chat, cq.From, nil)
+	if err != nil {
+		r.log.Errorw("can configure", "err", err)
+
+		return
+	}
+
+	if !allowed {
+		r.bot.MaybeSend(ctx, tgbotapi.NewCallbackWithAlert(cq.ID, i18n.From(ctx).T("update.chat_admins")))
+
+		return
+	}
+
 	action, payload := split[0], split[1]
-	var (
-		chattable tgbotapi.Chattable
-		err       error
-	)
+
+	var chattable tgbotapi.Chattable
 
 	switch action {
 	case "toggle_area":
 		chattable, err = r.commander.ToggleArea(ctx, cq, payload)
+	case "toggle_loc":
+		chattable, err = r.commander.ToggleLocationArea(ctx, cq, payload)
+	case "track_loc":
+		chattable, err = r.commander.TrackLocationAreas(ctx, cq, payload)
+	case "near_stop":
+		chattable, err = r.commander.StopNear(ctx, cq, payload)
+	case "set_lang":
+		chattable, err = r.commander.SetLanguage(ctx, cq, payload)
+	case "bc_confirm", "bc_cancel", "sched_cancel":
+		chattable, err = r.handleBroadcastCallback(ctx, cq, action, payload)
 	default:
 		err = fmt.Errorf("%s: %w", action, types.ErrUnknownCBAction)
 	}
@@ -177,3 +304,77 @@ func (r UpdateHandler) handleCallbackQuery(ctx context.Context, cq *tgbotapi.Cal
 
 	return
 }
+
+// handleBroadcastCallback checks the permission again: the preview may outlive the grant.
+func (r UpdateHandler) handleBroadcastCallback(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, action, payload string,
+) (tgbotapi.Chattable, error) {
+	ok, err := r.permissions.Has(ctx, cq.From.ID, types.PermSendBroadcast)
+	if err != nil {
+		return nil, fmt.Errorf("has: %w", err)
+	}
+
+	if !ok {
+		return tgbotapi.NewCallbackWithAlert(cq.ID, i18n.From(ctx).T("update.denied")), nil
+	}
+
+	switch action {
+	case "bc_confirm":
+		return r.commander.ConfirmBroadcast(ctx, cq, payload)
+	case "sched_cancel":
+		return r.commander.CancelScheduled(ctx, cq, payload)
+	default:
+		return r.commander.CancelBroadcast(ctx, cq, payload)
+	}
+}
+
+func (r UpdateHandler) handleMyChatMember(ctx context.Context, member *tgbotapi.ChatMemberUpdated) {
+	r.log.Infow("my chat member", "chat_id", member.Chat.ID, "status", member.NewChatMember.Status)
+
+	if status := member.NewChatMember.Status; status != "administrator" && status != "member" {
+		return
+	}
+
+	if _, err := r.chat.FirstOrCreate(ctx, &member.Chat, member.From.LanguageCode); err != nil {
+		r.log.Errorw("load or create chat", "err", err)
+	}
+}
+
+// addressedToBot tells apart /cmd@otherbot in groups; a plain /cmd is addressed to everyone.
+func (r UpdateHandler) addressedToBot(msg *tgbotapi.Message) bool {
+	command := msg.CommandWithAt()
+
+	idx := strings.Index(command, "@")
+	if idx == -1 {
+		return true
+	}
+
+	return strings.EqualFold(command[idx+1:], r.bot.Username())
+}
+
+func (r UpdateHandler) canConfigure(
+	ctx context.Context, chat *tgbotapi.Chat, from *tgbotapi.User, senderChat *tgbotapi.Chat,
+) (bool, error) {
+	switch {
+	case chat.IsPrivate():
+		return true, nil
+
+	case chat.IsChannel() && from == nil:
+		// a channel post, and only admins can post there
+		return true, nil
+
+	case senderChat != nil && senderChat.ID == chat.ID:
+		// anonymous group admin
+		return true, nil
+
+	case from == nil:
+		return false, nil
+	}
+
+	ok, err := r.bot.IsChatAdmin(ctx, chat.ID, from.ID)
+	if err != nil {
+		return false, fmt.Errorf("is chat admin: %w", err)
+	}
+
+	return ok, nil
+}
@@ -1,20 +1,24 @@
 package handlers
 
 import (
+	"closealerts/app/tracing"
 	"closealerts/app/types"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 type WebhookHandler struct {
+	log     *zap.SugaredLogger
 	Updates chan types.Update
 }
 
-func NewWebhook(config types.Config) WebhookHandler {
+func NewWebhook(log *zap.SugaredLogger, config types.Config) WebhookHandler {
 	return WebhookHandler{
+		log:     log,
 		Updates: config.Updates,
 	}
 }
@@ -22,20 +26,26 @@ func NewWebhook(config types.Config) WebhookHandler {
 func (h WebhookHandler) Pipe(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = r.Body.Close() }()
 
+	_, span := tracing.Start(r.Context(), "webhook")
+	defer span.End()
+
 	bts, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Println(fmt.Errorf("read all: %w", err))
+		h.log.Errorw("read webhook body", "err", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	var update types.Update
 	if err = json.Unmarshal(bts, &update); err != nil {
-		log.Println(fmt.Errorf("unmarshal: %w", err))
+		h.log.Errorw("unmarshal update", "err", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	span.SetAttributes(attribute.Int("update.id", update.UpdateID))
+	update.SpanContext = span.SpanContext()
+
 	h.Updates <- update
 
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,32 @@
+package i18n
+
+// areas translates the oblasts, named as the sources name them. Raions and cities are left as they are.
+var areas = map[string]map[string]string{
+	"Автономна Республіка Крим": {EN: "Crimea"},
+	"Вінницька":         {EN: "Vinnytsia"},
+	"Волинська":         {EN: "Volyn"},
+	"Дніпропетровська":  {EN: "Dnipropetrovsk"},
+	"Донецька":          {EN: "Donetsk"},
+	"Житомирська":       {EN: "Zhytomyr"},
+	"Закарпатська":      {EN: "Zakarpattia"},
+	"Запорізька":        {EN: "Zaporizhzhia"},
+	"Івано-Франківська": {EN: "Ivano-Frankivsk"},
+	"Київська":          {EN: "Kyiv Oblast"},
+	"м. Київ":           {EN: "Kyiv City"},
+	"м. Севастополь":    {EN: "Sevastopol"},
+	"Кіровоградська":    {EN: "Kirovohrad"},
+	"Луганська":         {EN: "Luhansk"},
+	"Львівська":         {EN: "Lviv"},
+	"Миколаївська":      {EN: "Mykolaiv"},
+	"Одеська":           {EN: "Odesa"},
+	"Полтавська":        {EN: "Poltava"},
+	"Рівненська":        {EN: "Rivne"},
+	"Сумська":           {EN: "Sumy"},
+	"Тернопільська":     {EN: "Ternopil"},
+	"Харківська":        {EN: "Kharkiv"},
+	"Херсонська":        {EN: "Kherson"},
+	"Хмельницька":       {EN: "Khmelnytskyi"},
+	"Черкаська":         {EN: "Cherkasy"},
+	"Чернівецька":       {EN: "Chernivtsi"},
+	"Чернігівська":      {EN: "Chernihiv"},
+}
@@ -0,0 +1,166 @@
+// Package i18n keeps the texts the bot shows to people in the languages it speaks,
+// and picks the one a chat is talked to in.
+package i18n
+
+import (
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	UK = "uk"
+	EN = "en"
+
+	// Default is spoken to chats whose language is unknown or not supported.
+	Default = UK
+)
+
+// names of the languages in themselves, for the language picker.
+var names = map[string]string{
+	UK: "Українська",
+	EN: "English",
+}
+
+func Languages() []string {
+	return []string{UK, EN}
+}
+
+// Match picks the supported language of a Telegram language code like en or en-GB.
+func Match(code string) (string, bool) {
+	code = strings.ToLower(code)
+	if idx := strings.IndexAny(code, "-_"); idx > 0 {
+		code = code[:idx]
+	}
+
+	_, ok := names[code]
+
+	return code, ok
+}
+
+// Name is the language named in itself.
+func Name(lang string) string {
+	return names[lang]
+}
+
+// Texts returns the translations of the key by language.
+func Texts(key string) map[string]string {
+	out := make(map[string]string, len(messages[key]))
+	for lang, text := range messages[key] {
+		out[lang] = text
+	}
+
+	return out
+}
+
+// Printer renders the catalog in one language, falling back to Default for the missing texts.
+type Printer struct {
+	lang string
+}
+
+// New returns the printer of the language, or of Default if the language is not supported.
+func New(lang string) Printer {
+	if _, ok := names[lang]; !ok {
+		lang = Default
+	}
+
+	return Printer{lang: lang}
+}
+
+func (r Printer) Lang() string {
+	return r.lang
+}
+
+// T formats the text of the key with the args; a missing key is printed as is, so it shows up in the chat.
+func (r Printer) T(key string, args ...interface{}) string {
+	return r.format(lookup(messages[key], r.lang), key, args)
+}
+
+// N is T for texts depending on a number: n picks the plural form, args still go to the text.
+func (r Printer) N(key string, n int, args ...interface{}) string {
+	form := pluralRules[r.lang](n)
+
+	byLang, ok := plurals[key][r.lang]
+	if !ok {
+		byLang, form = plurals[key][Default], pluralRules[Default](n)
+	}
+
+	text, ok := byLang[form]
+	if !ok {
+		text = byLang[Other]
+	}
+
+	return r.format(text, key, args)
+}
+
+func (r Printer) format(text, key string, args []interface{}) string {
+	if len(text) == 0 {
+		return key
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+
+	return fmt.Sprintf(text, args...)
+}
+
+func lookup(byLang map[string]string, lang string) string {
+	if text, ok := byLang[lang]; ok {
+		return text
+	}
+
+	return byLang[Default]
+}
+
+// Area names the area in the language; areas without a translation keep the names the sources give them.
+func (r Printer) Area(area string) string {
+	if r.lang == UK {
+		return area
+	}
+
+	if name, ok := areas[area][r.lang]; ok {
+		return name
+	}
+
+	return area
+}
+
+func (r Printer) Areas(list []string) types.Stringies {
+	out := make(types.Stringies, 0, len(list))
+	for _, area := range list {
+		out = append(out, r.Area(area))
+	}
+
+	return out
+}
+
+// AreaID turns the name of an area in any language into the one the sources use.
+func AreaID(name string) string {
+	name = strings.TrimSpace(name)
+
+	for id, byLang := range areas {
+		for _, translated := range byLang {
+			if strings.EqualFold(translated, name) {
+				return id
+			}
+		}
+	}
+
+	return name
+}
+
+type langKey struct{}
+
+// WithLang makes the language the replies to the update are written in.
+func WithLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, langKey{}, lang)
+}
+
+// From returns the printer of the language set by WithLang, or of Default.
+func From(ctx context.Context) Printer {
+	lang, _ := ctx.Value(langKey{}).(string)
+
+	return New(lang)
+}
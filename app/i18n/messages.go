@@ -0,0 +1,333 @@
+package i18n
+
+// messages maps keys to texts by language. Texts are fmt formats when they take arguments.
+// Adding a language means adding its texts here, its name above and its plural rule.
+var messages = map[string]map[string]string{
+	// command descriptions published to Telegram
+	"command.map":                  {UK: "Показати тривоги на мапі", EN: "Show alerts on the map"},
+	"command.alerts":               {UK: "Місця, де оголошена тривога", EN: "Places under alert"},
+	"command.areas":                {UK: "Список відслідковуваних областей, разом з налаштуванням", EN: "Tracked oblasts and their settings"},
+	"command.near":                 {UK: "Підписатись на область разом з сусідніми", EN: "Track an oblast together with its neighbors"},
+	"command.location":             {UK: "Підписатись на області за локацією", EN: "Track oblasts by location"},
+	"command.track":                {UK: "Пильнувати за територією", EN: "Track an area"},
+	"command.tracking":             {UK: "Території, за якими пильную", EN: "Tracked areas"},
+	"command.stop":                 {UK: "Відписатись від території", EN: "Stop tracking an area"},
+	"command.tester":               {UK: "Отримувати тестові тривоги", EN: "Receive simulated alerts"},
+	"command.lang":                 {UK: "Мова бота", EN: "Bot language"},
+	"command.status":               {UK: "Закріплене повідомлення зі станом тривог", EN: "Pinned message with the alert status"},
+	"command.digest":               {UK: "Щоденний або щотижневий підсумок тривог", EN: "Daily or weekly alert digest"},
+	"command.template":             {UK: "Свій текст сповіщень", EN: "Custom notification texts"},
+	"command.template_preview":     {UK: "Подивитись, як виглядатиме сповіщення", EN: "Preview a notification"},
+	"command.start":                {UK: "Коротко про те, як працює бот.", EN: "How the bot works"},
+	"command.cancel":               {UK: "Скасувати розпочату дію", EN: "Cancel the current action"},
+	"command.grant":                {UK: "Надати дозвіл користувачу", EN: "Grant a permission to a user"},
+	"command.revoke":               {UK: "Забрати дозвіл у користувача", EN: "Revoke a permission from a user"},
+	"command.admins":               {UK: "Користувачі з дозволами", EN: "Users with permissions"},
+	"command.admin_audit":          {UK: "Журнал адміністративних дій", EN: "Audit log of administrative actions"},
+	"command.admin_log_level":      {UK: "Показати або змінити рівень логування", EN: "Show or change the log level"},
+	"command.admin_fake_alert_in":  {UK: "Надіслати фейкову тривогу", EN: "Send a fake alert"},
+	"command.admin_fake_all_clear": {UK: "Завершити фейкові тривоги", EN: "End fake alerts"},
+	"command.admin_fake_scenario":  {UK: "Програти сценарій фейкових тривог", EN: "Play a fake alerts scenario"},
+	"command.admin_broadcast":      {UK: "Розіслати повідомлення всім чатам", EN: "Broadcast a message to every chat"},
+	"command.admin_schedule":       {UK: "Запланувати розсилку", EN: "Schedule a broadcast"},
+	"command.admin_schedules":      {UK: "Заплановані розсилки", EN: "Scheduled broadcasts"},
+
+	// handling updates
+	"update.unknown_command": {UK: "я такої команди не знаю", EN: "I don't know this command"},
+	"update.wrong_chat":      {UK: "ця команда тут не працює", EN: "this command doesn't work here"},
+	"update.failed":          {UK: "в мене щось пішло не так, спробуй ще раз", EN: "something went wrong, try again"},
+	"update.chat_admins":     {UK: "налаштовувати підписки можуть лише адміни чату", EN: "only chat admins can change subscriptions"},
+	"update.denied":          {UK: "недостатньо прав", EN: "not enough permissions"},
+
+	// conversations
+	"conversation.none":    {UK: "нема розпочатої розмови", EN: "there's nothing going on"},
+	"conversation.expired": {UK: "час на відповідь вийшов, почни спочатку", EN: "the time to answer is out, start over"},
+	"conversation.retry":   {UK: "%s\n\n/cancel — щоб скасувати", EN: "%s\n\n/cancel to cancel"},
+	"conversation.empty":   {UK: "чекаю на текст", EN: "waiting for the text"},
+
+	// notifications
+	"notify.alert":          {UK: "%s: тривога!", EN: "%s: air raid alert!"},
+	"notify.test":           {UK: "🧪 тест: %s: тривога!", EN: "🧪 test: %s: air raid alert!"},
+	"notify.all_clear":      {UK: "відбій: %s", EN: "all clear: %s"},
+	"notify.test_all_clear": {UK: "🧪 тест: відбій: %s", EN: "🧪 test: all clear: %s"},
+	"notify.near":           {UK: "%s (поруч: %s)", EN: "%s (next to %s)"},
+	"notify.near_oblast":    {UK: "%s (сусідня область, поруч: %s)", EN: "%s (neighboring oblast, next to %s)"},
+	"notify.near_raion":     {UK: "%s (сусідній район, поруч: %s)", EN: "%s (neighboring raion, next to %s)"},
+	"notify.near_hromada":   {UK: "%s (сусідня громада, поруч: %s)", EN: "%s (neighboring hromada, next to %s)"},
+
+	"start": {
+		UK: `Пильнуй сповіщення в сусідніх областях.
+
+Приклад, як створити сповіщення:
+/areas
+І далі наклацати області, які цікавлять.
+
+Або одразу разом з сусідніми областями:
+/near Київська
+
+Або надіслати свою локацію (/location) — підкажу, які області поруч.
+
+/lang — змінити мову.
+
+Дані беруться з карт:
+- https://alerts.in.ua/
+- https://vadimklimenko.com/map/
+- https://alarmmap.online/
+`,
+		EN: `Watch for alerts in the neighboring oblasts.
+
+To get notified, call
+/areas
+and pick the oblasts you care about.
+
+Or take an oblast together with its neighbors:
+/near Kyiv Oblast
+
+Or send your location (/location) and I'll suggest the oblasts around.
+
+/lang to change the language.
+
+The data comes from the maps:
+- https://alerts.in.ua/
+- https://vadimklimenko.com/map/
+- https://alarmmap.online/
+`,
+	},
+
+	// tracking
+	"track.prompt":      {UK: "вкажи територію, за якою пильнувати", EN: "name the area to track"},
+	"track.exists":      {UK: "вже пильную за %s", EN: "already tracking %s"},
+	"track.done":        {UK: "буду пильнувати за %s", EN: "will keep an eye on %s"},
+	"tracking.none":     {UK: "ще нічого не трекаєш", EN: "you don't track anything yet"},
+	"stop.prompt":       {UK: "вкажи територію від якої відписатись", EN: "name the area to stop tracking"},
+	"stop.not_tracking": {UK: "не пильную за %s, обери одну з: %s", EN: "I don't track %s, pick one of: %s"},
+	"stop.done":         {UK: "відписуюсь від %s", EN: "stopped tracking %s"},
+	"cancel.nothing":    {UK: "нема чого скасовувати", EN: "nothing to cancel"},
+	"cancel.done":       {UK: "скасовано", EN: "cancelled"},
+
+	// alerts
+	"alerts.none":   {UK: "все тихо", EN: "all quiet"},
+	"as_of":         {UK: "дані станом на %s", EN: "data as of %s"},
+	"as_of.stale":   {UK: "⚠️ дані станом на %s: джерела тривог не відповідають, дані можуть бути неактуальні", EN: "⚠️ data as of %s: the alert sources don't answer, the data may be outdated"},
+	"as_of.no_data": {UK: "⚠️ даних про тривоги ще немає, джерела не відповідають", EN: "⚠️ no alert data yet, the sources don't answer"},
+	"stale.data":    {UK: "дані про тривоги застаріли: останнє оновлення %s тому", EN: "alert data is stale: last refreshed %s ago"},
+	"stale.error":   {UK: "%s, остання помилка: %v", EN: "%s, last error: %v"},
+	"stale.fresh":   {UK: "дані про тривоги знову свіжі, оновлено о %s", EN: "alert data is fresh again, refreshed at %s"},
+
+	// areas and location
+	"areas.pick":         {UK: "можеш обрати на які області підписатись", EN: "pick the oblasts to track"},
+	"areas.tracking":     {UK: "Підписки: %s", EN: "Tracking: %s"},
+	"areas.none":         {UK: "Нема підписок", EN: "Not tracking anything"},
+	"location.ask":       {UK: "надішли локацію або місце — підкажу, на які області підписатись", EN: "send a location or a place and I'll suggest the oblasts to track"},
+	"location.button":    {UK: "📍 Надіслати локацію", EN: "📍 Send location"},
+	"location.not_found": {UK: "не знайшов жодної області за цією локацією", EN: "found no oblast at this location"},
+	"location.found":     {UK: "Локація: %s", EN: "Location: %s"},
+	"location.around":    {UK: "Поруч: %s", EN: "Around: %s"},
+	"location.pick":      {UK: "можеш обрати на що підписатись", EN: "pick what to track"},
+	"location.track_all": {UK: "Підписатись на всі", EN: "Track them all"},
+
+	// neighbors
+	"near.depth":   {UK: "глибина має бути від 1 до %d", EN: "the depth must be from 1 to %d"},
+	"near.unknown": {UK: "не знаю сусідів для %s", EN: "I don't know the neighbors of %s"},
+	"near.exists":  {UK: "вже пильную за %s", EN: "already tracking %s"},
+	"near.done":    {UK: "пильную за %s і сусідніми областями: %s", EN: "tracking %s and the oblasts next to it: %s"},
+	"near.skipped": {UK: "вже були в підписках, тож лишаться і без групи: %s", EN: "these were tracked before, so they stay without the group: %s"},
+	"near.remove":  {UK: "Прибрати групу %s", EN: "Remove the %s group"},
+	"near.usage":   {UK: "вкажи область і, за бажанням, глибину сусідства: /near Київська 2", EN: "name an oblast and, optionally, how far the neighbors go: /near Kyiv Oblast 2"},
+	"near.groups":  {UK: "Групи сусідніх областей: %s", EN: "Neighbor groups: %s"},
+	"near.stopped": {UK: "прибрав групу %s разом з сусідніми областями", EN: "removed the %s group together with its neighbors"},
+
+	// chat settings
+	"tester.usage": {UK: "/tester on — отримувати тестові тривоги, /tester off — не отримувати", EN: "/tester on to receive simulated alerts, /tester off to stop"},
+	"tester.on":    {UK: "тепер сюди приходитимуть і тестові тривоги", EN: "simulated alerts will come here too"},
+	"tester.off":   {UK: "тестові тривоги більше не приходитимуть", EN: "simulated alerts won't come here anymore"},
+	"lang.current": {UK: "мова: %s", EN: "language: %s"},
+	"lang.unknown": {UK: "такої мови не знаю, обери одну з: %s", EN: "I don't speak it, pick one of: %s"},
+	"lang.set":     {UK: "тепер розмовляю українською", EN: "I'll speak English now"},
+
+	// status messages
+	"status.usage": {
+		UK: "/status on — закріпити повідомлення зі станом відслідковуваних територій, яке я оновлюватиму замість окремих сповіщень, /status off — повернути сповіщення",
+		EN: "/status on pins a message with the state of the tracked areas, which I keep updated instead of sending notifications, /status off brings the notifications back",
+	},
+	"status.on": {
+		UK: "стан тепер у закріпленому повідомленні, окремих сповіщень більше не буде. Зміни в ньому не сповіщають, тож перевіряй його",
+		EN: "the state is in the pinned message now, no more separate notifications. Its updates don't notify anyone, so check it yourself",
+	},
+	"status.off":           {UK: "закріплене повідомлення більше не оновлюю, сповіщення знову приходитимуть", EN: "the pinned message won't be updated anymore, notifications are back"},
+	"status.not_on":        {UK: "закріпленого повідомлення й так нема", EN: "there is no pinned message"},
+	"status.title":         {UK: "Стан тривог", EN: "Alert status"},
+	"status.empty":         {UK: "поки нічого не відслідковую: /track або /areas", EN: "nothing tracked yet: /track or /areas"},
+	"status.alert":         {UK: "🔴 %s — тривога з %s", EN: "🔴 %s: alert since %s"},
+	"status.alert_unknown": {UK: "🔴 %s — тривога", EN: "🔴 %s: alert"},
+	"status.calm":          {UK: "🟢 %s — спокійно з %s", EN: "🟢 %s: calm since %s"},
+	"status.calm_unknown":  {UK: "🟢 %s", EN: "🟢 %s"},
+
+	// digests
+	"digest.usage": {
+		UK: `/digest day 21:00 — щодня о 21:00 надсилати підсумок тривог за добу у відслідковуваних територіях
+/digest week 09:00 — щотижня, у понеділок о 09:00, підсумок за тиждень; інший день можна вказати: /digest week 09:00 нд
+Додай chart, щоб отримувати й графік: /digest day 21:00 chart
+/digest off — більше не надсилати`,
+		EN: `/digest day 21:00 sends the digest of the alerts in the tracked areas over the day, every day at 21:00
+/digest week 09:00 sends the digest of the week on Mondays at 09:00; another day can be given: /digest week 09:00 sun
+Add chart to get a chart too: /digest day 21:00 chart
+/digest off stops the digests`,
+	},
+	"digest.schedule.day":  {UK: "щодня о %s", EN: "daily at %s"},
+	"digest.schedule.week": {UK: "щотижня %s о %s", EN: "weekly %s at %s"},
+	"digest.with_chart":    {UK: ", з графіком", EN: ", with a chart"},
+	"digest.current":       {UK: "підсумок: %s, наступний — %s", EN: "digest: %s, the next one at %s"},
+	"digest.set":           {UK: "надсилатиму підсумок %s, наступний — %s", EN: "I'll send the digest %s, the next one at %s"},
+	"digest.off":           {UK: "більше не надсилатиму підсумків", EN: "no more digests"},
+	"digest.not_on":        {UK: "підсумків і так не надсилаю", EN: "there are no digests to stop"},
+	"digest.no_areas":      {UK: "підсумку нема: поки нічого не відслідковую, /track або /areas", EN: "no digest: nothing tracked yet, /track or /areas"},
+	"digest.title.day":     {UK: "📊 Підсумок доби: %s", EN: "📊 Digest of the day: %s"},
+	"digest.title.week":    {UK: "📊 Підсумок тижня: %s", EN: "📊 Digest of the week: %s"},
+	"digest.count":         {UK: "тривог: %d, %s", EN: "alerts: %d, %s"},
+	"digest.total":         {UK: "під тривогою: %s, %s", EN: "under alert: %s, %s"},
+	"digest.longest":       {UK: "найдовша: %s, %s, з %s", EN: "the longest: %s, %s, since %s"},
+	"digest.more":          {UK: "на %s більше, ніж %s", EN: "%s more than %s"},
+	"digest.less":          {UK: "на %s менше, ніж %s", EN: "%s less than %s"},
+	"digest.same":          {UK: "як і %s", EN: "same as %s"},
+	"digest.prev.day":      {UK: "напередодні", EN: "the day before"},
+	"digest.prev.week":     {UK: "попереднього тижня", EN: "the week before"},
+	"digest.chart.day":     {UK: "На графіку — час під тривогою щогодини, сірим — напередодні.", EN: "The chart shows the time under alert by hour, the day before in grey."},
+	"digest.chart.week":    {UK: "На графіку — час під тривогою щодня, сірим — попереднього тижня.", EN: "The chart shows the time under alert by day, the week before in grey."},
+	"weekday.0":            {UK: "у неділю", EN: "on Sundays"},
+	"weekday.1":            {UK: "у понеділок", EN: "on Mondays"},
+	"weekday.2":            {UK: "у вівторок", EN: "on Tuesdays"},
+	"weekday.3":            {UK: "у середу", EN: "on Wednesdays"},
+	"weekday.4":            {UK: "у четвер", EN: "on Thursdays"},
+	"weekday.5":            {UK: "у п'ятницю", EN: "on Fridays"},
+	"weekday.6":            {UK: "у суботу", EN: "on Saturdays"},
+
+	// notification templates
+	"template.usage": {
+		UK: `/template alert <шаблон> — свій текст тривоги, /template all_clear <шаблон> — відбою.
+Перед шаблоном можна вказати формат: text (типово), markdown (MarkdownV2) або html.
+/template alert reset — повернути типовий текст.
+/template_preview alert — подивитись сповіщення на прикладі, /template_preview alert <шаблон> — спробувати шаблон, не зберігаючи.
+
+У шаблоні можна писати:
+{{.Areas}} — території
+{{.Count}} — скільки їх
+{{.Type}} — тип території, лише для тривог
+{{.Start}} — коли почалась тривога
+{{.Duration}} — скільки тривала, лише для відбою
+
+Наприклад: /template alert markdown 🚨 *{{.Areas}}*: тривога з {{.Start}}`,
+		EN: `/template alert <template> sets the alert text, /template all_clear <template> the all-clear one.
+The template may start with the format: text (the default), markdown (MarkdownV2) or html.
+/template alert reset brings back the default text.
+/template_preview alert shows a sample notification, /template_preview alert <template> tries a template without saving it.
+
+Templates can show:
+{{.Areas}}: the areas
+{{.Count}}: how many of them
+{{.Type}}: the kind of area, alerts only
+{{.Start}}: when the alert started
+{{.Duration}}: how long it lasted, all-clear only
+
+For example: /template alert markdown 🚨 *{{.Areas}}*: alert since {{.Start}}`,
+	},
+	"template.current":        {UK: "%s (%s):\n%s", EN: "%s (%s):\n%s"},
+	"template.default":        {UK: "%s: типовий текст", EN: "%s: the default text"},
+	"template.saved":          {UK: "зберіг шаблон %s\n/template_preview %s — подивитись", EN: "saved the %s template\n/template_preview %s to see it"},
+	"template.reset":          {UK: "повернув типовий текст %s", EN: "brought back the default %s text"},
+	"template.not_set":        {UK: "у %s і так типовий текст", EN: "%s already has the default text"},
+	"template.invalid":        {UK: "шаблон не підходить: %s", EN: "the template won't do: %s"},
+	"template.unknown_kind":   {UK: "не знаю такого сповіщення, є: %s", EN: "no such notification, there are: %s"},
+	"template.unknown_format": {UK: "не знаю такого формату, є: %s", EN: "no such format, there are: %s"},
+	"template.syntax":         {UK: "у {{ }} можна писати лише %s", EN: "{{ }} can only be %s"},
+	"template.unknown_field":  {UK: "не знаю {{.%s}}, є: %s", EN: "there's no {{.%s}}, there are %s"},
+	"template.empty":          {UK: "виходить порожнє повідомлення", EN: "the message comes out empty"},
+	"template.too_long":       {UK: "виходить %d символів, а можна до %d", EN: "it comes out %d characters long, at most %d are allowed"},
+	"template.markdown_escape": {
+		UK: "символ %s у MarkdownV2 треба екранувати зворотним слешем",
+		EN: "%s has to be escaped with a backslash in MarkdownV2",
+	},
+	"template.markdown_unclosed": {UK: "не закрито %s", EN: "%s is not closed"},
+	"template.html_escape":       {UK: "символ %s у HTML треба писати як сутність: &lt; &gt; &amp;", EN: "write %s as an entity in HTML: &lt; &gt; &amp;"},
+	"template.html_tag":          {UK: "тег <%s> Telegram не підтримує", EN: "Telegram doesn't support the <%s> tag"},
+	"template.html_unclosed":     {UK: "не закрито тег <%s>", EN: "the <%s> tag is not closed"},
+	"template.html_unexpected":   {UK: "зайвий закривальний тег </%s>", EN: "unexpected closing tag </%s>"},
+	"duration.minutes":           {UK: "%d хв", EN: "%dm"},
+	"duration.hours_minutes":     {UK: "%d год %d хв", EN: "%dh %dm"},
+	"area_type.o":                {UK: "область", EN: "oblast"},
+	"area_type.r":                {UK: "район", EN: "raion"},
+	"area_type.c":                {UK: "місто", EN: "city"},
+	"area_type.h":                {UK: "громада", EN: "hromada"},
+
+	// permissions and audit
+	"grant.usage":        {UK: "використання: /grant <user_id> <дозвіл>\nдозволи: %s", EN: "usage: /grant <user_id> <permission>\npermissions: %s"},
+	"grant.done":         {UK: "надано %s користувачу %d", EN: "granted %s to %d"},
+	"revoke.usage":       {UK: "використання: /revoke <user_id> <дозвіл>\nдозволи: %s", EN: "usage: /revoke <user_id> <permission>\npermissions: %s"},
+	"revoke.bootstrap":   {UK: "цей адмін заданий у конфігу, прибери його там", EN: "this admin comes from the config, remove them there"},
+	"revoke.not_granted": {UK: "у %d нема %s", EN: "%d has no %s"},
+	"revoke.done":        {UK: "забрано %s у %d", EN: "revoked %s from %d"},
+	"admins.config":      {UK: "%d: %s (конфіг)", EN: "%d: %s (config)"},
+	"admins.none":        {UK: "адмінів нема", EN: "no admins"},
+	"audit.usage":        {UK: "використання: /admin_audit [сторінка]", EN: "usage: /admin_audit [page]"},
+	"audit.empty":        {UK: "на сторінці %d подій нема", EN: "no audit events on page %d"},
+	"audit.page":         {UK: "сторінка %d з %d", EN: "page %d of %d"},
+	"audit.older":        {UK: "давніші: /admin_audit %d", EN: "older: /admin_audit %d"},
+	"log_level.current":  {UK: "рівень логування: %s", EN: "log level: %s"},
+	"log_level.usage":    {UK: "використання: /admin_log_level <debug|info|warn|error>", EN: "usage: /admin_log_level <debug|info|warn|error>"},
+
+	// simulations
+	"fake.alert_usage":    {UK: "використання: /admin_fake_alert_in [тривалість] область[, область...]", EN: "usage: /admin_fake_alert_in [duration] area[, area...]"},
+	"fake.alerting":       {UK: "імітую тривоги на %s в: %s", EN: "simulating alerts for %s in %s"},
+	"fake.nothing_to_end": {UK: "імітованих тривог нема", EN: "no simulated alerts to end"},
+	"fake.ended":          {UK: "завершено імітовані тривоги в: %s", EN: "ended simulated alerts in %s"},
+	"fake.scenario_usage": {UK: "використання: /admin_fake_scenario назва", EN: "usage: /admin_fake_scenario name"},
+	"fake.scenario_error": {UK: "не вдалося завантажити сценарій: %s", EN: "can't load scenario: %s"},
+
+	// broadcasts
+	"broadcast.target_prompt": {UK: "кому розсилаємо? all — всім, active — чатам з підписками, або області через кому", EN: "whom to send to? all for everyone, active for chats tracking anything, or oblasts separated by commas"},
+	"broadcast.target_retry":  {UK: "напиши all, active або області через кому", EN: "write all, active or oblasts separated by commas"},
+	"broadcast.text_prompt":   {UK: "що будемо броадкастити?", EN: "what to broadcast?"},
+	"broadcast.preview":       {UK: "Кому: %s\n%s\n\n%s", EN: "To: %s\n%s\n\n%s"},
+	"broadcast.send":          {UK: "✅ Розіслати", EN: "✅ Send"},
+	"broadcast.cancel":        {UK: "❌ Скасувати", EN: "❌ Cancel"},
+	"broadcast.not_found":     {UK: "такої розсилки нема", EN: "no such broadcast"},
+	"broadcast.not_draft":     {UK: "цю розсилку вже запущено або скасовано", EN: "this broadcast is already running or cancelled"},
+	"broadcast.cancelled":     {UK: "розсилку скасовано", EN: "broadcast cancelled"},
+	"broadcast.sending":       {UK: "розсилаю", EN: "sending"},
+	"broadcast.finished":      {UK: "розсилку завершено", EN: "broadcast finished"},
+	"broadcast.status":        {UK: "%s: %d з %d\nдоставлено: %d\nзаблокували бота: %d\nпомилки: %d", EN: "%s: %d of %d\ndelivered: %d\nblocked the bot: %d\nfailed: %d"},
+	"broadcast.target_all":    {UK: "всі чати", EN: "every chat"},
+	"broadcast.target_active": {UK: "чати з підписками", EN: "chats tracking anything"},
+	"broadcast.target_areas":  {UK: "підписники %s", EN: "tracking %s"},
+
+	// scheduled broadcasts
+	"schedule.when_prompt": {
+		UK: "коли розіслати? час як 2006-01-02 15:04 за Києвом, або cron на кшталт «0 10 * * 1» для повторень",
+		EN: "when to send? time like 2006-01-02 15:04, Kyiv time, or cron like «0 10 * * 1» to repeat",
+	},
+	"schedule.when_retry":        {UK: "не розумію коли: %s", EN: "can't tell when: %s"},
+	"schedule.done":              {UK: "заплановано #%d, перша розсилка %s\n/admin_schedules — переглянути і скасувати", EN: "scheduled #%d, first sent at %s\n/admin_schedules to see and cancel"},
+	"schedule.none":              {UK: "запланованих розсилок нема", EN: "no scheduled broadcasts"},
+	"schedule.once":              {UK: "одноразово", EN: "once"},
+	"schedule.cron":              {UK: "cron %s", EN: "cron %s"},
+	"schedule.item":              {UK: "#%d %s, наступна %s, %s:\n%s", EN: "#%d %s, next at %s, %s:\n%s"},
+	"schedule.cancel":            {UK: "❌ Скасувати #%d", EN: "❌ Cancel #%d"},
+	"schedule.already_cancelled": {UK: "цю розсилку вже скасовано", EN: "this broadcast is already cancelled"},
+	"schedule.cancelled":         {UK: "розсилку #%d скасовано", EN: "broadcast #%d cancelled"},
+}
+
+// plurals are texts depending on a number, by language and plural form.
+var plurals = map[string]map[string]map[string]string{
+	"broadcast.recipients": {
+		UK: {One: "%d отримувач", Few: "%d отримувачі", Many: "%d отримувачів"},
+		EN: {One: "%d recipient", Other: "%d recipients"},
+	},
+	"fake.playing": {
+		UK: {One: "програю %s: %d подія", Few: "програю %s: %d події", Many: "програю %s: %d подій"},
+		EN: {One: "playing %s: %d event", Other: "playing %s: %d events"},
+	},
+	"tracking.count": {
+		UK: {One: "%d територія: %s", Few: "%d території: %s", Many: "%d територій: %s"},
+		EN: {One: "%d area: %s", Other: "%d areas: %s"},
+	},
+}
@@ -0,0 +1,38 @@
+package i18n
+
+// Plural forms, named as in CLDR; a language uses only some of them.
+const (
+	One   = "one"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+var pluralRules = map[string]func(n int) string{
+	UK: pluralUK,
+	EN: pluralEN,
+}
+
+// pluralUK: 1, 21, 31 областю; 2-4, 22-24 області; 0, 5-20, 25-30 областей.
+func pluralUK(n int) string {
+	if n < 0 {
+		n = -n
+	}
+
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && mod100 != 11:
+		return One
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+func pluralEN(n int) string {
+	if n == 1 {
+		return One
+	}
+
+	return Other
+}
@@ -1,82 +1,252 @@
 package jobs
 
 import (
+	"closealerts/app/i18n"
+	"closealerts/app/metrics"
+	types2 "closealerts/app/repositories/types"
 	"closealerts/app/services"
+	"closealerts/app/tracing"
 	"closealerts/app/types"
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// AlertRefresher fetches the alerts from the sources and keeps them; services.Alerts implements it.
+type AlertRefresher interface {
+	GetActiveFromRemote(ctx context.Context) ([]types2.Alert, error)
+	ReplaceAlerts(ctx context.Context, alerts []types2.Alert) error
+	Freshness(ctx context.Context) (time.Time, bool, error)
+}
+
+// Notifier notifies chats about the alerts in the areas they track; services.Notification implements it.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []types2.Alert) error
+}
+
+// Simulations give the simulated alerts and play scenarios under the job; services.Fakes implements it.
+type Simulations interface {
+	Alerts(ctx context.Context) types2.Alerts
+	Start(ctx context.Context)
+	Wait()
+}
+
+// AdminLister tells who the admins are; services.Permissions implements it.
+type AdminLister interface {
+	Admins(ctx context.Context) ([]int64, error)
+}
+
+// StatusRefresher edits status messages into the state of the areas; services.StatusMessages implements it.
+type StatusRefresher interface {
+	Refresh(ctx context.Context)
+}
+
+// AlertRecorder keeps the history of the alerts; services.Digests implements it.
+type AlertRecorder interface {
+	Record(ctx context.Context, alerts types2.Alerts) error
+}
+
 type Alerts struct {
 	tick         time.Duration
 	done         chan struct{}
-	alertSvc     services.Alerts
+	refreshes    *sync.WaitGroup
+	alertSvc     AlertRefresher
 	log          *zap.SugaredLogger
-	notification services.Notification
-	fake         services.Fakes
+	notification Notifier
+	fake         Simulations
+	health       services.Health
+	permissions  AdminLister
+	telegram     services.Sender
+	languages    services.LanguageStore
+	status       StatusRefresher
+	digests      AlertRecorder
+
+	staleNotifyAfter time.Duration
 }
 
 func NewAlerts(
 	log *zap.SugaredLogger,
 	cfg types.Config,
-	alertSvc services.Alerts,
-	notification services.Notification,
-	fake services.Fakes,
+	alertSvc AlertRefresher,
+	notification Notifier,
+	fake Simulations,
+	health services.Health,
+	permissions AdminLister,
+	telegram services.Sender,
+	languages services.LanguageStore,
+	status StatusRefresher,
+	digests AlertRecorder,
 ) Alerts {
 	return Alerts{
-		tick: cfg.TickInterval,
-		done: make(chan struct{}),
-		log:  log,
+		tick:      cfg.TickInterval,
+		done:      make(chan struct{}),
+		refreshes: &sync.WaitGroup{},
+		log:       log,
+
+		staleNotifyAfter: cfg.StaleNotifyAfter,
 
 		fake:         fake,
+		health:       health,
 		alertSvc:     alertSvc,
 		notification: notification,
+		permissions:  permissions,
+		telegram:     telegram,
+		languages:    languages,
+		status:       status,
+		digests:      digests,
 	}
 }
 
 func (r Alerts) Run(ctx context.Context) error {
+	// simulation scenarios feed the ticks, so they play as long as the job runs
+	r.fake.Start(ctx)
+
 	go func() {
 		ticker := time.NewTicker(r.tick)
 		defer func() { ticker.Stop() }()
 
+		// data never refreshed counts from the start, so admins are not told on every restart
+		started := time.Now()
+		staleNotified := false
+
 		for {
 			select {
 			case <-ctx.Done():
+				// status messages are edited and scenarios played under the same context, so they stop soon
+				r.refreshes.Wait()
+				r.fake.Wait()
 				close(r.done)
+
 				return
 
 			case <-ticker.C:
-				alerts, err := r.alertSvc.GetActiveFromRemote(ctx)
-				if err != nil {
-					r.log.Errorw("get active alerts", "err", err)
+				tickStarted := time.Now()
+				tickCtx, span := tracing.Start(ctx, "alerts tick")
+				err := r.runTick(tickCtx)
+				tracing.End(span, err)
 
-					break
+				if err != nil {
+					r.log.Errorw("tick", "err", err)
+					r.health.TickFailed(err)
+				} else {
+					r.health.TickSucceeded()
+
+					// editing many status messages takes a while, the next tick must not wait for it
+					r.refreshes.Add(1)
+
+					go func() {
+						defer r.refreshes.Done()
+						r.status.Refresh(ctx)
+					}()
 				}
 
-				if alert, ok := r.fake.Alert(ctx); ok {
-					alerts = append(alerts, alert)
-				}
+				metrics.TickDuration.Observe(time.Since(tickStarted).Seconds())
 
-				if err := r.alertSvc.ReplaceAlerts(ctx, alerts); err != nil {
-					r.log.Errorw("replace alerts", "err", err)
+				staleNotified = r.watchStale(ctx, started, staleNotified, err)
+			}
+		}
+	}()
 
-					break
-				}
+	return nil
+}
 
-				if err := r.notification.Notify(ctx, alerts); err != nil {
-					r.log.Errorw("notify", "err", err)
+func (r Alerts) runTick(ctx context.Context) error {
+	alerts, err := r.alertSvc.GetActiveFromRemote(ctx)
+	if err != nil {
+		return fmt.Errorf("get active alerts: %w", err)
+	}
 
-					break
-				}
-			}
+	// a real alert wins over a simulated one in the same area
+	realAreas := types2.Alerts(alerts).Areas()
+	for _, alert := range r.fake.Alerts(ctx) {
+		if !realAreas.Contains(alert.ID) {
+			alerts = append(alerts, alert)
 		}
-	}()
+	}
+
+	metrics.ActiveAlerts.Set(float64(len(alerts)))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("alerts.count", len(alerts)))
+
+	if err := r.alertSvc.ReplaceAlerts(ctx, alerts); err != nil {
+		return fmt.Errorf("replace alerts: %w", err)
+	}
+
+	// a gap in the history only makes digests less exact, chats must still be notified
+	if err := r.digests.Record(ctx, alerts); err != nil {
+		r.log.Errorw("record alerts", "err", err)
+	}
+
+	if err := r.notification.Notify(ctx, alerts); err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
 
 	return nil
 }
 
+// watchStale tells admins when the alert data gets older than the threshold, and once more when it is fresh again.
+// It returns whether admins know the data is stale.
+func (r Alerts) watchStale(ctx context.Context, started time.Time, notified bool, tickErr error) bool {
+	refreshedAt, _, err := r.alertSvc.Freshness(ctx)
+	if err != nil {
+		r.log.Errorw("freshness", "err", err)
+
+		return notified
+	}
+
+	since := refreshedAt
+	if since.IsZero() {
+		since = started
+	}
+
+	stale := time.Since(since) > r.staleNotifyAfter
+
+	var text func(p i18n.Printer) string
+
+	switch {
+	case stale && !notified:
+		text = func(p i18n.Printer) string {
+			out := p.T("stale.data", services.FormatDuration(p, time.Since(since)))
+			if tickErr != nil {
+				out = p.T("stale.error", out, tickErr)
+			}
+
+			return out
+		}
+	case !stale && notified:
+		text = func(p i18n.Printer) string {
+			return p.T("stale.fresh", refreshedAt.In(services.ScheduleLocation).Format("15:04:05"))
+		}
+	default:
+		return notified
+	}
+
+	admins, err := r.permissions.Admins(ctx)
+	if err != nil {
+		r.log.Errorw("admins", "err", err)
+
+		return notified
+	}
+
+	// admins talk to the bot in private chats, which have the IDs of the users
+	langs, err := r.languages.Languages(ctx, admins)
+	if err != nil {
+		r.log.Errorw("admin languages", "err", err)
+	}
+
+	for _, id := range admins {
+		r.telegram.MaybeSendText(ctx, id, text(i18n.New(langs[id])))
+	}
+
+	r.log.Infow("stale data", "stale", stale, "refreshed_at", refreshedAt)
+
+	return stale
+}
+
 func (r Alerts) Done() <-chan struct{} {
 	return r.done
 }
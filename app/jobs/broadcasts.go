@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+)
+
+// BroadcastRunner resumes broadcasts and waits for them to stop; services.Broadcasts implements it.
+type BroadcastRunner interface {
+	Resume(ctx context.Context) error
+	Wait()
+}
+
+// Broadcasts resumes broadcasts interrupted by a restart and runs the ones confirmed while it is up. They stop
+// with the context and continue from their cursor on the next start; Done closes once they have stopped.
+type Broadcasts struct {
+	done      chan struct{}
+	broadcast BroadcastRunner
+}
+
+func NewBroadcasts(broadcast BroadcastRunner) Broadcasts {
+	return Broadcasts{
+		done:      make(chan struct{}),
+		broadcast: broadcast,
+	}
+}
+
+func (r Broadcasts) Run(ctx context.Context) error {
+	if err := r.broadcast.Resume(ctx); err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		r.broadcast.Wait()
+		close(r.done)
+	}()
+
+	return nil
+}
+
+func (r Broadcasts) Done() <-chan struct{} {
+	return r.done
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const schedulerTick = 30 * time.Second
+
+// ScheduleRunner launches the scheduled broadcasts that are due; services.ScheduledBroadcasts implements it.
+type ScheduleRunner interface {
+	RunDue(ctx context.Context) error
+}
+
+// DigestRunner sends the digests that are due; services.Digests implements it.
+type DigestRunner interface {
+	RunDue(ctx context.Context) error
+}
+
+// Scheduler launches scheduled broadcasts and sends digests when they are due.
+type Scheduler struct {
+	tick      time.Duration
+	done      chan struct{}
+	log       *zap.SugaredLogger
+	scheduled ScheduleRunner
+	digests   DigestRunner
+}
+
+func NewScheduler(
+	log *zap.SugaredLogger,
+	scheduled ScheduleRunner,
+	digests DigestRunner,
+) Scheduler {
+	return Scheduler{
+		tick:      schedulerTick,
+		done:      make(chan struct{}),
+		log:       log,
+		scheduled: scheduled,
+		digests:   digests,
+	}
+}
+
+func (r Scheduler) Run(ctx context.Context) error {
+	go func() {
+		ticker := time.NewTicker(r.tick)
+		defer func() { ticker.Stop() }()
+
+		for {
+			select {
+			case <-ctx.Done():
+				close(r.done)
+				return
+
+			case <-ticker.C:
+				if err := r.scheduled.RunDue(ctx); err != nil {
+					r.log.Errorw("run due scheduled broadcasts", "err", err)
+				}
+
+				if err := r.digests.RunDue(ctx); err != nil {
+					r.log.Errorw("run due digests", "err", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r Scheduler) Done() <-chan struct{} {
+	return r.done
+}
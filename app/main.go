@@ -4,13 +4,17 @@ import (
 	"closealerts/app/clients"
 	"closealerts/app/handlers"
 	"closealerts/app/jobs"
+	"closealerts/app/migrations"
 	"closealerts/app/repositories"
-	types2 "closealerts/app/repositories/types"
 	"closealerts/app/server"
 	"closealerts/app/services"
+	"closealerts/app/tracing"
 	"closealerts/app/types"
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
@@ -18,66 +22,260 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate()
+
+		return
+	}
+
 	app := fx.New(
+		bot(),
+
+		fx.WithLogger(func(log *zap.Logger) fxevent.Logger {
+			return &fxevent.ZapLogger{Logger: log}
+		}),
+	)
+
+	app.Run()
+}
+
+// bot is the whole of the bot, configured from the environment; end-to-end tests run it against fakebot.
+func bot() fx.Option {
+	return fx.Options(
 		fx.Provide(
 			types.NewConfig,
 
-			clients.NewDBFromSQLite,
+			clients.NewDB,
+			clients.NewLogLevel,
 			clients.NewLogger,
 			clients.NewSugaredLogger,
-			clients.NewTelegram,
-
-			repositories.NewAlerts,
-			repositories.NewNotification,
-			repositories.NewChats,
-			repositories.NewMaps,
-
-			services.NewFakes,
-			services.NewAlerts,
-			services.NewNotification,
-			services.NewChats,
-			services.NewMaps,
+			fx.Annotate(
+				clients.NewTelegram,
+				fx.As(new(services.Sender)),
+				fx.As(new(services.Webhook)),
+				fx.As(new(handlers.Bot)),
+				fx.As(new(handlers.Requester)),
+				fx.As(new(clients.WebhookEndpoint)),
+				fx.As(new(rateLimiter)),
+			),
+			fx.Annotate(clients.NewSources, fx.As(new(services.SourceFetcher)), fx.As(new(sourcesReloader))),
+
+			fx.Annotate(repositories.NewAlerts, fx.As(new(services.AlertStore))),
+			// commands change subscriptions, notifications mark them, status messages and digests only read them
+			fx.Annotate(
+				repositories.NewNotification,
+				fx.As(new(services.SubscriptionStore)),
+				fx.As(new(services.NotifyStore)),
+				fx.As(new(services.TrackingStore)),
+			),
+			fx.Annotate(repositories.NewChats, fx.As(new(services.ChatStore)), fx.As(new(services.LanguageStore))),
+			fx.Annotate(
+				repositories.NewTemplates,
+				fx.As(new(services.TemplateStore)),
+				fx.As(new(services.ChatTemplateStore)),
+			),
+			fx.Annotate(
+				repositories.NewStatusMessages,
+				fx.As(new(services.StatusStore)),
+				fx.As(new(services.StatusMessageStore)),
+			),
+			fx.Annotate(repositories.NewAlertRecords, fx.As(new(services.AlertRecordStore))),
+			fx.Annotate(repositories.NewDigests, fx.As(new(services.DigestStore))),
+			fx.Annotate(repositories.NewMaps, fx.As(new(services.MapStore))),
+			fx.Annotate(repositories.NewConversations, fx.As(new(services.ConversationStore))),
+			fx.Annotate(repositories.NewPermissions, fx.As(new(services.PermissionStore))),
+			fx.Annotate(repositories.NewAudit, fx.As(new(services.AuditStore))),
+			fx.Annotate(repositories.NewBroadcasts, fx.As(new(services.BroadcastStore))),
+			fx.Annotate(repositories.NewScheduledBroadcasts, fx.As(new(services.ScheduledBroadcastStore))),
+
+			services.NewHealth,
+			fx.Annotate(services.NewFakes, fx.As(new(services.Simulator)), fx.As(new(jobs.Simulations))),
+			fx.Annotate(
+				services.NewAlerts,
+				fx.As(new(services.AlertReader)),
+				fx.As(new(jobs.AlertRefresher)),
+				fx.As(new(alertsReloader)),
+			),
+			fx.Annotate(
+				services.NewNotification,
+				fx.As(new(services.Subscriber)),
+				fx.As(new(services.TrackingChats)),
+				fx.As(new(jobs.Notifier)),
+			),
+			fx.Annotate(
+				services.NewChats,
+				fx.As(new(services.ChatSettings)),
+				fx.As(new(services.ChatDirectory)),
+				fx.As(new(handlers.ChatRegistry)),
+			),
+			fx.Annotate(services.NewMaps, fx.As(new(services.MapRenderer))),
+			fx.Annotate(services.NewGeo, fx.As(new(services.Locator)), fx.As(new(services.AreaLevels))),
+			fx.Annotate(services.NewNeighbors, fx.As(new(services.AreaGraph))),
+			fx.Annotate(
+				services.NewConversations,
+				fx.As(new(services.ConversationRunner)),
+				fx.As(new(handlers.PendingConversations)),
+			),
+			fx.Annotate(
+				services.NewPermissions,
+				fx.As(new(services.PermissionManager)),
+				fx.As(new(jobs.AdminLister)),
+				fx.As(new(handlers.PermissionReader)),
+			),
+			fx.Annotate(services.NewAudit, fx.As(new(services.AuditLog)), fx.As(new(services.AuditRecorder))),
+			fx.Annotate(
+				services.NewBroadcasts,
+				fx.As(new(services.BroadcastDrafts)),
+				fx.As(new(services.BroadcastLauncher)),
+				fx.As(new(jobs.BroadcastRunner)),
+			),
+			fx.Annotate(
+				services.NewScheduledBroadcasts,
+				fx.As(new(services.BroadcastScheduler)),
+				fx.As(new(jobs.ScheduleRunner)),
+			),
+			fx.Annotate(services.NewTemplates, fx.As(new(services.TemplateEditor))),
+			fx.Annotate(
+				services.NewStatusMessages,
+				fx.As(new(services.StatusSwitch)),
+				fx.As(new(jobs.StatusRefresher)),
+			),
+			fx.Annotate(
+				services.NewDigests,
+				fx.As(new(services.DigestSettings)),
+				fx.As(new(jobs.AlertRecorder)),
+				fx.As(new(jobs.DigestRunner)),
+			),
 			services.NewCommander,
 
 			jobs.NewAlerts,
+			jobs.NewBroadcasts,
+			jobs.NewScheduler,
 
 			handlers.NewWebhook,
 			handlers.NewUpdate,
+			handlers.NewRouter,
+			handlers.NewHealth,
 
 			server.NewMux,
 			server.NewServer,
 		),
 
 		fx.Invoke(
-			migrate,
+			tracing.Register,
+			migrations.Run,
 			startAlertsJob,
+			startBroadcastsJob,
+			startSchedulerJob,
 			server.RegisterWebhook,
+			server.RegisterHealth,
+			server.RegisterMetrics,
 			server.RegisterListeningWebhooks,
 			server.RegisterServer,
 			clients.RegisterTelegram,
-			clients.RegisterTelegramCommands,
+			handlers.RegisterTelegramCommands,
+			registerConfigReload,
 		),
+	)
+}
 
-		fx.WithLogger(func(log *zap.Logger) fxevent.Logger {
-			return &fxevent.ZapLogger{Logger: log}
-		}),
+// migrate brings the database schema up to date without starting the bot.
+func migrate() {
+	app := fx.New(
+		fx.Provide(
+			types.NewConfig,
+			clients.NewDB,
+			clients.NewLogLevel,
+			clients.NewLogger,
+			clients.NewSugaredLogger,
+		),
+		fx.Invoke(migrations.Run),
+		fx.NopLogger,
 	)
 
-	app.Run()
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func migrate(db clients.DB) error {
-	err := db.AutoMigrate(
-		&types2.Alert{},
-		&types2.Notification{},
-		&types2.Chat{},
-		&types2.Map{},
-	)
-	if err != nil {
-		return fmt.Errorf("db auto migrate trend: %w", err)
+// sourcesReloader, rateLimiter and alertsReloader are what the config reload changes at runtime;
+// clients.Sources, clients.Telegram and services.Alerts implement them.
+type sourcesReloader interface {
+	Reload(config types.Config) error
+}
+
+type rateLimiter interface {
+	SetRateLimit(perSecond int)
+}
+
+type alertsReloader interface {
+	Reload(config types.Config)
+}
+
+// registerConfigReload re-reads the config on SIGHUP and applies the fields that are safe to change at runtime.
+// An invalid config is rejected as a whole, and changes to other fields are logged as waiting for a restart.
+func registerConfigReload(
+	lc fx.Lifecycle,
+	log *zap.SugaredLogger,
+	config types.Config,
+	level zap.AtomicLevel,
+	telegram rateLimiter,
+	sources sourcesReloader,
+	alerts alertsReloader,
+) {
+	hup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	reload := func() {
+		next, err := types.LoadConfig(os.Getenv("CONFIG_FILE"))
+		if err != nil {
+			log.Errorw("reload config", "err", err)
+
+			return
+		}
+
+		if err := sources.Reload(next); err != nil {
+			log.Errorw("reload sources", "err", err)
+
+			return
+		}
+
+		alerts.Reload(next)
+		telegram.SetRateLimit(next.TelegramRateLimit)
+		level.SetLevel(next.LogLevel)
+
+		// compared with the config the bot runs with, so pending changes are reminded of on every reload
+		if fields := types.RestartNeeded(config, next); len(fields) > 0 {
+			log.Warnw("config changes need a restart", "fields", fields)
+		}
+
+		log.Infow("reloaded config")
 	}
 
-	return nil
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			signal.Notify(hup, syscall.SIGHUP)
+
+			go func() {
+				for range hup {
+					reload()
+				}
+
+				close(done)
+			}()
+
+			return nil
+		},
+
+		OnStop: func(context.Context) error {
+			signal.Stop(hup)
+			close(hup)
+			<-done
+
+			return nil
+		},
+	})
 }
 
 func startAlertsJob(lc fx.Lifecycle, alerts jobs.Alerts) {
@@ -100,3 +298,45 @@ func startAlertsJob(lc fx.Lifecycle, alerts jobs.Alerts) {
 		},
 	})
 }
+
+func startBroadcastsJob(lc fx.Lifecycle, broadcasts jobs.Broadcasts) {
+	cctx, cancel := context.WithCancel(context.Background())
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if err := broadcasts.Run(cctx); err != nil {
+				return fmt.Errorf("run: %w", err)
+			}
+
+			return nil
+		},
+
+		OnStop: func(context.Context) error {
+			cancel()
+			<-broadcasts.Done()
+
+			return nil
+		},
+	})
+}
+
+func startSchedulerJob(lc fx.Lifecycle, scheduler jobs.Scheduler) {
+	cctx, cancel := context.WithCancel(context.Background())
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if err := scheduler.Run(cctx); err != nil {
+				return fmt.Errorf("run: %w", err)
+			}
+
+			return nil
+		},
+
+		OnStop: func(context.Context) error {
+			cancel()
+			<-scheduler.Done()
+
+			return nil
+		},
+	})
+}
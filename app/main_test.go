@@ -0,0 +1,214 @@
+package main
+
+import (
+	"closealerts/app/clients/fakebot"
+	"closealerts/app/i18n"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/fx"
+)
+
+// sourcesStub serves the alerts.in.ua active alerts the test sets.
+type sourcesStub struct {
+	mu     sync.Mutex
+	alerts []map[string]string
+}
+
+func (r *sourcesStub) set(areas ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alerts = nil
+	for _, area := range areas {
+		r.alerts = append(r.alerts, map[string]string{"n": area, "t": "o"})
+	}
+}
+
+func (r *sourcesStub) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"alerts": r.alerts})
+}
+
+// startBot runs the whole bot against fakebot and the sources stub, with an in-memory database.
+func startBot(t *testing.T) (*fakebot.Server, *sourcesStub) {
+	t.Helper()
+
+	telegram := fakebot.NewServer()
+	t.Cleanup(telegram.Close)
+
+	sources := &sourcesStub{}
+	sourcesServer := httptest.NewServer(sources)
+	t.Cleanup(sourcesServer.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	dir := t.TempDir()
+
+	for key, value := range map[string]string{
+		"CONFIG_FILE":           "",
+		"TELEGRAM_BOT_API":      fakebot.Token,
+		"TELEGRAM_API_ENDPOINT": telegram.Endpoint(),
+		"WEBHOOK_ENDPOINT":      "http://" + addr + "/tgwebhook",
+		"SERVER_ADDR":           addr,
+		"DB_DRIVER":             "sqlite",
+		"SQLITE3_DB_PATH":       ":memory:",
+		"SOURCES_MODE":          "live",
+		"SOURCES_BASE_URL":      sourcesServer.URL,
+		"TICK_INTERVAL":         "100ms",
+		"STALE_AFTER":           "1h",
+		"LOG_LEVEL":             "warn",
+		"LOG_OUTPUTS":           filepath.Join(dir, "log.log"),
+		"LOG_ERROR_OUTPUTS":     filepath.Join(dir, "internal.log"),
+		"TRACING_EXPORTER":      "none",
+		"ADMIN_USER_IDS":        "100",
+	} {
+		t.Setenv(key, value)
+	}
+
+	app := fx.New(bot(), fx.NopLogger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := app.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := app.Stop(ctx); err != nil {
+			t.Errorf("stop: %v", err)
+		}
+	})
+
+	waitFor(t, "the webhook", func() bool { return len(telegram.Calls("setWebhook")) > 0 })
+
+	return telegram, sources
+}
+
+// waitFor polls the condition until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitReply waits for a message to the chat with the text; it must match exactly, as a test alert contains
+// the text of a real one.
+func waitReply(t *testing.T, bot *fakebot.Server, chatID int64, text string) {
+	t.Helper()
+
+	waitFor(t, strconv.Quote(text), func() bool {
+		for _, sent := range bot.Sent(chatID) {
+			if sent == text {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+func push(t *testing.T, bot *fakebot.Server, updates ...tgbotapi.Update) {
+	t.Helper()
+
+	if err := bot.Push(updates...); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+}
+
+func TestBot(t *testing.T) {
+	bot, sources := startBot(t)
+	p := i18n.New(i18n.UK)
+
+	user := tgbotapi.User{ID: 100, FirstName: "Tester"}
+	private := tgbotapi.Chat{ID: 100, Type: "private"}
+
+	t.Run("unknown command", func(t *testing.T) {
+		push(t, bot, fakebot.Message(private, user, "/nosuchcommand"))
+
+		waitReply(t, bot, private.ID, p.T("update.unknown_command"))
+	})
+
+	t.Run("track and get notified", func(t *testing.T) {
+		push(t, bot, fakebot.Message(private, user, "/track Київська"))
+		waitReply(t, bot, private.ID, p.T("track.done", "Київська"))
+
+		sources.set("Київська")
+		waitReply(t, bot, private.ID, p.T("notify.alert", "Київська"))
+
+		sources.set()
+		waitReply(t, bot, private.ID, p.T("notify.all_clear", "Київська"))
+	})
+
+	t.Run("testers get the real alert replacing a simulated one", func(t *testing.T) {
+		bot.Reset()
+
+		push(t, bot, fakebot.Message(private, user, "/tester on"))
+		waitReply(t, bot, private.ID, p.T("tester.on"))
+
+		push(t, bot, fakebot.Message(private, user, "/admin_fake_alert_in 1h Київська"))
+		waitReply(t, bot, private.ID, p.T("notify.test", "Київська"))
+
+		sources.set("Київська")
+		waitReply(t, bot, private.ID, p.T("notify.alert", "Київська"))
+
+		// the simulated alert goes on after the real one, and ends with a test all-clear
+		bot.Reset()
+		sources.set()
+		waitReply(t, bot, private.ID, p.T("notify.all_clear", "Київська"))
+		waitReply(t, bot, private.ID, p.T("notify.test", "Київська"))
+
+		push(t, bot, fakebot.Message(private, user, "/admin_fake_all_clear"))
+		waitReply(t, bot, private.ID, p.T("notify.test_all_clear", "Київська"))
+	})
+
+	t.Run("track asks for the area", func(t *testing.T) {
+		push(t, bot, fakebot.Message(private, user, "/track"))
+		waitReply(t, bot, private.ID, p.T("track.prompt"))
+
+		push(t, bot, fakebot.Message(private, user, "Львівська"))
+		waitReply(t, bot, private.ID, p.T("track.done", "Львівська"))
+	})
+
+	t.Run("group settings are for chat admins", func(t *testing.T) {
+		group := tgbotapi.Chat{ID: -200, Type: "group", Title: "Group"}
+		member := tgbotapi.User{ID: 201, FirstName: "Member"}
+
+		push(t, bot, fakebot.Message(group, member, "/track Харківська"))
+		waitReply(t, bot, group.ID, p.T("update.chat_admins"))
+
+		bot.SetAdmin(group.ID, member.ID, true)
+
+		push(t, bot, fakebot.Message(group, member, "/track Харківська"))
+		waitReply(t, bot, group.ID, p.T("track.done", "Харківська"))
+	})
+}
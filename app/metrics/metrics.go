@@ -0,0 +1,95 @@
+// Package metrics holds the Prometheus collectors of the bot, served at /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "closealerts"
+
+var (
+	SourceFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "source_fetch_duration_seconds",
+		Help:      "Time to fetch and parse active alerts from a source.",
+	}, []string{"source"})
+
+	SourceFetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "source_fetch_errors_total",
+		Help:      "Failed fetches of active alerts by source.",
+	}, []string{"source"})
+
+	TickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tick_duration_seconds",
+		Help:      "Time of one alerts job tick: fetching, storing and notifying.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	ActiveAlerts = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_alerts",
+		Help:      "Alerts going on as of the last tick, simulated ones included.",
+	})
+
+	// Notifications are labeled by type (alert, all_clear, test_alert, test_all_clear) and result (sent, failed).
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notifications sent to chats.",
+	}, []string{"type", "result"})
+
+	TelegramRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_request_duration_seconds",
+		Help:      "Bot API request latency by request type.",
+	}, []string{"request"})
+
+	TelegramRateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_rate_limited_total",
+		Help:      "Bot API requests answered with 429 Too Many Requests.",
+	})
+
+	Commands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Handled commands by name.",
+	}, []string{"command"})
+
+	MapRenderDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "map_render_duration_seconds",
+		Help:      "Time to paint and convert the alerts map.",
+		Buckets:   []float64{.25, .5, 1, 2.5, 5, 10, 30},
+	})
+
+	// MapCache is labeled with hit or miss; the hit ratio is hit / (hit + miss).
+	MapCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "map_cache_total",
+		Help:      "Lookups of rendered maps by result.",
+	}, []string{"result"})
+)
+
+// queueDepth is the gauge of the queue registered last.
+var queueDepth prometheus.Collector
+
+// RegisterQueueDepth reports the number of updates waiting in the queue. A bot started again in the same process,
+// as tests do, reports its own queue instead of the previous one.
+func RegisterQueueDepth(queue func() int) {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "update_queue_depth",
+		Help:      "Updates received but not picked up for handling yet.",
+	}, func() float64 { return float64(queue()) })
+
+	if queueDepth != nil {
+		prometheus.Unregister(queueDepth)
+	}
+
+	prometheus.MustRegister(gauge)
+	queueDepth = gauge
+}
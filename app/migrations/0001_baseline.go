@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baseline creates the schema as AutoMigrate left it, so databases created before versioning simply
+// get recorded at version 1. The models are frozen copies: later changes go to later migrations.
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(
+			&baselineAlert{},
+			&baselineNotification{},
+			&baselineChat{},
+			&baselineMap{},
+			&baselineConversation{},
+			&baselinePermission{},
+			&baselineAuditEvent{},
+			&baselineBroadcast{},
+			&baselineScheduledBroadcast{},
+		)
+	},
+}
+
+type baselineAlert struct {
+	ID        string `gorm:"column:id;primaryKey"`
+	Type      string `gorm:"column:type"`
+	Simulated bool   `gorm:"column:simulated"`
+}
+
+func (baselineAlert) TableName() string { return "alerts" }
+
+type baselineNotification struct {
+	ChatID   int64  `gorm:"column:chat_id"`
+	Area     string `gorm:"column:area"`
+	Notified bool   `gorm:"column:notified"`
+	Near     string `gorm:"column:near"`
+}
+
+func (baselineNotification) TableName() string { return "notifications" }
+
+type baselineChat struct {
+	ID       int64  `gorm:"column:id;primaryKey;autoIncrement:false"`
+	Username string `gorm:"column:username"`
+	Title    string `gorm:"column:title"`
+	Type     string `gorm:"column:type"`
+	Tester   bool   `gorm:"column:tester"`
+}
+
+func (baselineChat) TableName() string { return "chats" }
+
+type baselineMap struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	FileID    string    `gorm:"column:file_id"`
+	AlertsKey string    `gorm:"column:alerts_key;unique"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (baselineMap) TableName() string { return "maps" }
+
+type baselineConversation struct {
+	ChatID    int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	Flow      string    `gorm:"column:flow"`
+	Step      string    `gorm:"column:step"`
+	Data      string    `gorm:"column:data"`
+	ExpiresAt time.Time `gorm:"column:expires_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (baselineConversation) TableName() string { return "conversations" }
+
+type baselinePermission struct {
+	ID         int64     `gorm:"column:id;primaryKey"`
+	UserID     int64     `gorm:"column:user_id;uniqueIndex:idx_permissions_user_permission"`
+	Permission string    `gorm:"column:permission;uniqueIndex:idx_permissions_user_permission"`
+	GrantedBy  int64     `gorm:"column:granted_by"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+func (baselinePermission) TableName() string { return "permissions" }
+
+type baselineAuditEvent struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at;index:idx_audit_events_created_at"`
+	ActorID   int64     `gorm:"column:actor_id"`
+	ChatID    int64     `gorm:"column:chat_id"`
+	Action    string    `gorm:"column:action"`
+	Args      string    `gorm:"column:args"`
+	Result    string    `gorm:"column:result"`
+}
+
+func (baselineAuditEvent) TableName() string { return "audit_events" }
+
+type baselineBroadcast struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+	CreatedBy int64     `gorm:"column:created_by"`
+	ChatID    int64     `gorm:"column:chat_id"`
+	MessageID int       `gorm:"column:message_id"`
+	Text      string    `gorm:"column:text"`
+	Target    string    `gorm:"column:target"`
+	Status    string    `gorm:"column:status;index:idx_broadcasts_status"`
+	Total     int       `gorm:"column:total"`
+	Delivered int       `gorm:"column:delivered"`
+	Blocked   int       `gorm:"column:blocked"`
+	Failed    int       `gorm:"column:failed"`
+	Cursor    int64     `gorm:"column:cursor"`
+}
+
+func (baselineBroadcast) TableName() string { return "broadcasts" }
+
+type baselineScheduledBroadcast struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	CreatedBy int64     `gorm:"column:created_by"`
+	ChatID    int64     `gorm:"column:chat_id"`
+	Text      string    `gorm:"column:text"`
+	Target    string    `gorm:"column:target"`
+	Cron      string    `gorm:"column:cron"`
+	NextRunAt time.Time `gorm:"column:next_run_at;index:idx_scheduled_broadcasts_next_run_at"`
+	Active    bool      `gorm:"column:active;index:idx_scheduled_broadcasts_active"`
+}
+
+func (baselineScheduledBroadcast) TableName() string { return "scheduled_broadcasts" }
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// notificationKeys gives notifications a primary key and makes (chat_id, area) unique. Tables can't get
+// a primary key added in place everywhere, so the rows move to a new table, duplicates merged.
+var notificationKeys = Migration{
+	Version: 2,
+	Name:    "notification keys",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&keyedNotification{}); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		// a duplicate counts as notified if any of the copies was, so nobody gets notified twice
+		err := tx.Exec(`
+			insert into notifications_keyed (chat_id, area, notified, near)
+			select chat_id, area, sum(case when notified then 1 else 0 end) > 0, min(near)
+			from notifications
+			group by chat_id, area
+		`).Error
+		if err != nil {
+			return fmt.Errorf("copy rows: %w", err)
+		}
+
+		if err := tx.Migrator().DropTable("notifications"); err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		if err := tx.Migrator().RenameTable("notifications_keyed", "notifications"); err != nil {
+			return fmt.Errorf("rename table: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type keyedNotification struct {
+	ID       int64  `gorm:"column:id;primaryKey"`
+	ChatID   int64  `gorm:"column:chat_id;uniqueIndex:idx_notifications_chat_area"`
+	Area     string `gorm:"column:area;uniqueIndex:idx_notifications_chat_area"`
+	Notified bool   `gorm:"column:notified"`
+	Near     string `gorm:"column:near"`
+}
+
+func (keyedNotification) TableName() string { return "notifications_keyed" }
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// alertRefreshes adds the table keeping the time alerts were last fetched, the age of the stored alert set.
+var alertRefreshes = Migration{
+	Version: 3,
+	Name:    "alert refreshes",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&alertRefresh{}); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type alertRefresh struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement:false"`
+	RefreshedAt time.Time `gorm:"column:refreshed_at"`
+}
+
+func (alertRefresh) TableName() string { return "alert_refreshes" }
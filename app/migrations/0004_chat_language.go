@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// chatLanguage adds the language chats are talked to in; empty means not chosen yet.
+var chatLanguage = Migration{
+	Version: 4,
+	Name:    "chat language",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&languageChat{}, "Language"); err != nil {
+			return fmt.Errorf("add column: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type languageChat struct {
+	Language string `gorm:"column:language;not null;default:''"`
+}
+
+func (languageChat) TableName() string { return "chats" }
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// notificationTemplates adds custom notification templates of chats, and the time subscriptions were notified
+// at, which the templates show as the start and the duration of alerts.
+var notificationTemplates = Migration{
+	Version: 5,
+	Name:    "notification templates",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&chatTemplate{}); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		if err := tx.Migrator().AddColumn(&notifiedAtNotification{}, "NotifiedAt"); err != nil {
+			return fmt.Errorf("add column: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type chatTemplate struct {
+	ChatID    int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	Kind      string    `gorm:"column:kind;primaryKey"`
+	Format    string    `gorm:"column:format"`
+	Text      string    `gorm:"column:text"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (chatTemplate) TableName() string { return "chat_templates" }
+
+type notifiedAtNotification struct {
+	NotifiedAt *time.Time `gorm:"column:notified_at"`
+}
+
+func (notifiedAtNotification) TableName() string { return "notifications" }
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statusMessages adds the live status messages of chats, and the time subscriptions saw their alert end,
+// which the status shows as the time the area has been calm since.
+var statusMessages = Migration{
+	Version: 6,
+	Name:    "status messages",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&statusMessage{}); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		if err := tx.Migrator().AddColumn(&clearedAtNotification{}, "ClearedAt"); err != nil {
+			return fmt.Errorf("add column: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type statusMessage struct {
+	ChatID    int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	MessageID int       `gorm:"column:message_id"`
+	Text      string    `gorm:"column:text"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (statusMessage) TableName() string { return "status_messages" }
+
+type clearedAtNotification struct {
+	ClearedAt *time.Time `gorm:"column:cleared_at"`
+}
+
+func (clearedAtNotification) TableName() string { return "notifications" }
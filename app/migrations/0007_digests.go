@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// digests adds the history of real alerts and the digest settings of chats, which summarize it.
+var digests = Migration{
+	Version: 7,
+	Name:    "digests",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&alertRecord{}); err != nil {
+			return fmt.Errorf("create alert_records: %w", err)
+		}
+
+		if err := tx.Migrator().CreateTable(&digest{}); err != nil {
+			return fmt.Errorf("create digests: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type alertRecord struct {
+	ID        int64      `gorm:"column:id;primaryKey"`
+	Area      string     `gorm:"column:area;index"`
+	Type      string     `gorm:"column:type"`
+	StartedAt time.Time  `gorm:"column:started_at;index"`
+	EndedAt   *time.Time `gorm:"column:ended_at;index"`
+}
+
+func (alertRecord) TableName() string { return "alert_records" }
+
+type digest struct {
+	ChatID  int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	Period  string    `gorm:"column:period"`
+	Hour    int       `gorm:"column:hour"`
+	Minute  int       `gorm:"column:minute"`
+	Weekday int       `gorm:"column:weekday"`
+	Chart   bool      `gorm:"column:chart"`
+	NextAt  time.Time `gorm:"column:next_at;index"`
+}
+
+func (digest) TableName() string { return "digests" }
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// notifiedSimulated remembers whether a subscription was notified about a simulated alert, for a real alert
+// replacing it to be notified about too.
+var notifiedSimulated = Migration{
+	Version: 8,
+	Name:    "notified simulated",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&simulatedNotification{}, "NotifiedSimulated"); err != nil {
+			return fmt.Errorf("add column: %w", err)
+		}
+
+		return nil
+	},
+}
+
+type simulatedNotification struct {
+	NotifiedSimulated bool `gorm:"column:notified_simulated;not null;default:false"`
+}
+
+func (simulatedNotification) TableName() string { return "notifications" }
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// chatPrivileges turns the privileges /auth used to set on chats into permissions of the users. Only private
+// chats count, their ID being the user's; group members were never meant to inherit them. Every permission the
+// user did not have already is written to the audit log, for admins to see who got what. The columns are dropped
+// after.
+var chatPrivileges = Migration{
+	Version: 9,
+	Name:    "chat privileges",
+	Up: func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// databases created after the columns were gone from the model never had them
+		var columns []string
+
+		grants := map[string][]int64{}
+
+		for _, privilege := range chatPrivilegeColumns {
+			if !tx.Migrator().HasColumn(&privilegedChat{}, privilege.column) {
+				continue
+			}
+
+			var ids []int64
+
+			err := tx.Model(&privilegedChat{}).Where(privilege.column+" = ? and id > 0", true).Pluck("id", &ids).Error
+			if err != nil {
+				return fmt.Errorf("select %s: %w", privilege.column, err)
+			}
+
+			columns = append(columns, privilege.column)
+			grants[privilege.column] = ids
+		}
+
+		for _, privilege := range chatPrivilegeColumns {
+			for _, id := range grants[privilege.column] {
+				permission := baselinePermission{UserID: id, Permission: privilege.permission, CreatedAt: now}
+
+				created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&permission)
+				if created.Error != nil {
+					return fmt.Errorf("grant %s to %d: %w", privilege.permission, id, created.Error)
+				}
+
+				// the user has the permission already
+				if created.RowsAffected == 0 {
+					continue
+				}
+
+				event := baselineAuditEvent{
+					CreatedAt: now,
+					ChatID:    id,
+					Action:    "grant",
+					Args:      fmt.Sprintf("%d %s", id, privilege.permission),
+					Result:    "granted from chats." + privilege.column,
+				}
+				if err := tx.Create(&event).Error; err != nil {
+					return fmt.Errorf("audit %s of %d: %w", privilege.permission, id, err)
+				}
+			}
+		}
+
+		// dropping a column rebuilds the table on SQLite, so it comes after all of them are read
+		for _, column := range columns {
+			if err := tx.Migrator().DropColumn(&privilegedChat{}, column); err != nil {
+				return fmt.Errorf("drop %s: %w", column, err)
+			}
+		}
+
+		return nil
+	},
+}
+
+var chatPrivilegeColumns = []struct{ column, permission string }{
+	{column: "priv_send_fake_event", permission: "send_fake_event"},
+	{column: "priv_broadcast", permission: "send_broadcast"},
+}
+
+type privilegedChat struct {
+	ID                int64 `gorm:"column:id;primaryKey;autoIncrement:false"`
+	PrivSendFakeEvent bool  `gorm:"column:priv_send_fake_event"`
+	PrivBroadcast     bool  `gorm:"column:priv_broadcast"`
+}
+
+func (privilegedChat) TableName() string { return "chats" }
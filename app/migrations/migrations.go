@@ -0,0 +1,103 @@
+// Package migrations evolves the database schema through ordered, versioned steps. Each applied version is
+// recorded in schema_migrations, so every database knows where it is and only runs what it misses.
+package migrations
+
+import (
+	"closealerts/app/clients"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	// Up runs in a transaction together with recording the version.
+	Up func(tx *gorm.DB) error
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// all lists the migrations; append new ones, never edit or reorder the applied ones.
+var all = []Migration{
+	baseline,
+	notificationKeys,
+	alertRefreshes,
+	chatLanguage,
+	notificationTemplates,
+	statusMessages,
+	digests,
+	notifiedSimulated,
+	chatPrivileges,
+}
+
+// Run applies pending migrations in the order of versions.
+func Run(log *zap.SugaredLogger, db clients.DB) error {
+	conn := db.DB()
+
+	if err := conn.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return fmt.Errorf("version: %w", err)
+	}
+
+	list := append([]Migration(nil), all...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	for _, migration := range list {
+		if migration.Version <= current {
+			continue
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			record := schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("record version: %w", err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		log.Infow("applied migration", "version", migration.Version, "name", migration.Name)
+	}
+
+	return nil
+}
+
+// Version returns the latest applied migration, 0 for a fresh database.
+func Version(db clients.DB) (int, error) {
+	var last schemaMigration
+
+	err := db.DB().Order("version desc").Take(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("select: %w", err)
+	}
+
+	return last.Version, nil
+}
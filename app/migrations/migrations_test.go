@@ -0,0 +1,247 @@
+package migrations
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newDB opens an SQLite database in a file of its own, as the bot runs with.
+func newDB(t *testing.T) clients.DB {
+	t.Helper()
+
+	db, err := clients.NewDB(types.Config{
+		DBDriver:      clients.DBDriverSQLite,
+		SQLite3DBPath: filepath.Join(t.TempDir(), "bot.db"),
+	})
+	if err != nil {
+		t.Fatalf("new db: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB().DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+func versions(t *testing.T, db clients.DB) []int {
+	t.Helper()
+
+	var list []int
+	if err := db.DB().Model(&schemaMigration{}).Order("version").Pluck("version", &list).Error; err != nil {
+		t.Fatalf("select versions: %v", err)
+	}
+
+	return list
+}
+
+// runUpTo brings the database to the version, as an older bot would have left it.
+func runUpTo(t *testing.T, db clients.DB, version int) {
+	t.Helper()
+
+	migrations := all
+	all = migrations[:version]
+
+	err := Run(zap.NewNop().Sugar(), db)
+
+	all = migrations
+
+	if err != nil {
+		t.Fatalf("run up to %d: %v", version, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db := newDB(t)
+	log := zap.NewNop().Sugar()
+
+	want := make([]int, 0, len(all))
+	for i := range all {
+		want = append(want, i+1)
+	}
+
+	// a second run finds nothing to do
+	for run := 1; run <= 2; run++ {
+		if err := Run(log, db); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+
+		if got := versions(t, db); !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: versions %v, want %v", run, got, want)
+		}
+	}
+
+	version, err := Version(db)
+	if err != nil {
+		t.Fatalf("version: %v", err)
+	}
+
+	if version != len(all) {
+		t.Errorf("version %d, want %d", version, len(all))
+	}
+
+	if !db.DB().Migrator().HasIndex(&types2.Notification{}, "idx_notifications_chat_area") {
+		t.Fatalf("no idx_notifications_chat_area index on notifications")
+	}
+
+	if err := db.DB().Create(&types2.Notification{ChatID: 1, Area: "Київська"}).Error; err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if err := db.DB().Create(&types2.Notification{ChatID: 1, Area: "Київська"}).Error; err == nil {
+		t.Errorf("inserted a second notification for the same chat and area")
+	}
+
+	if err := db.DB().Create(&types2.Notification{ChatID: 2, Area: "Київська"}).Error; err != nil {
+		t.Errorf("insert for another chat: %v", err)
+	}
+}
+
+func TestRunMergesDuplicateNotifications(t *testing.T) {
+	db := newDB(t)
+	log := zap.NewNop().Sugar()
+
+	// bring the database to the baseline, where notifications had no key yet
+	runUpTo(t, db, 1)
+
+	rows := []baselineNotification{
+		{ChatID: 1, Area: "Київська"},
+		{ChatID: 1, Area: "Київська", Notified: true},
+		{ChatID: 1, Area: "Львівська"},
+		{ChatID: 2, Area: "Київська", Near: "Житомирська"},
+		{ChatID: 2, Area: "Київська", Near: "Житомирська"},
+	}
+	if err := db.DB().Create(&rows).Error; err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if err := Run(log, db); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var got []types2.Notification
+	if err := db.DB().Order("chat_id, area").Find(&got).Error; err != nil {
+		t.Fatalf("select: %v", err)
+	}
+
+	type row struct {
+		ChatID   int64
+		Area     string
+		Notified bool
+		Near     string
+	}
+
+	want := []row{
+		// a chat notified through any of the copies is not notified again
+		{ChatID: 1, Area: "Київська", Notified: true},
+		{ChatID: 1, Area: "Львівська"},
+		{ChatID: 2, Area: "Київська", Near: "Житомирська"},
+	}
+
+	gotRows := make([]row, 0, len(got))
+	for _, n := range got {
+		gotRows = append(gotRows, row{ChatID: n.ChatID, Area: n.Area, Notified: n.Notified, Near: n.Near})
+	}
+
+	if !reflect.DeepEqual(gotRows, want) {
+		t.Errorf("notifications %+v, want %+v", gotRows, want)
+	}
+}
+
+func TestRunMovesChatPrivilegesToPermissions(t *testing.T) {
+	db := newDB(t)
+
+	// /auth set the privileges on chats, which the baseline model no longer knows
+	runUpTo(t, db, 8)
+
+	for _, column := range []string{"PrivSendFakeEvent", "PrivBroadcast"} {
+		if err := db.DB().Migrator().AddColumn(&privilegedChat{}, column); err != nil {
+			t.Fatalf("add %s: %v", column, err)
+		}
+	}
+
+	chats := []privilegedChat{
+		{ID: 10, PrivSendFakeEvent: true},
+		{ID: 11, PrivSendFakeEvent: true, PrivBroadcast: true},
+		{ID: 12},
+		// group members never inherit the privileges of the group
+		{ID: -20, PrivBroadcast: true},
+	}
+	if err := db.DB().Create(&chats).Error; err != nil {
+		t.Fatalf("insert chats: %v", err)
+	}
+
+	// a permission granted already is kept as it is
+	granted := baselinePermission{UserID: 11, Permission: "send_broadcast", GrantedBy: 1}
+	if err := db.DB().Create(&granted).Error; err != nil {
+		t.Fatalf("insert permission: %v", err)
+	}
+
+	if err := Run(zap.NewNop().Sugar(), db); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var got []types2.Permission
+	if err := db.DB().Order("user_id, permission").Find(&got).Error; err != nil {
+		t.Fatalf("select permissions: %v", err)
+	}
+
+	type row struct {
+		UserID     int64
+		Permission string
+		GrantedBy  int64
+	}
+
+	want := []row{
+		{UserID: 10, Permission: "send_fake_event"},
+		{UserID: 11, Permission: "send_broadcast", GrantedBy: 1},
+		{UserID: 11, Permission: "send_fake_event"},
+	}
+
+	gotRows := make([]row, 0, len(got))
+	for _, permission := range got {
+		gotRows = append(gotRows, row{UserID: permission.UserID, Permission: permission.Permission, GrantedBy: permission.GrantedBy})
+	}
+
+	if !reflect.DeepEqual(gotRows, want) {
+		t.Errorf("permissions %+v, want %+v", gotRows, want)
+	}
+
+	var audited []string
+	if err := db.DB().Model(&baselineAuditEvent{}).Order("args").Pluck("args", &audited).Error; err != nil {
+		t.Fatalf("select audit events: %v", err)
+	}
+
+	if want := []string{"10 send_fake_event", "11 send_fake_event"}; !reflect.DeepEqual(audited, want) {
+		t.Errorf("audit events %v, want %v", audited, want)
+	}
+
+	for _, column := range []string{"priv_send_fake_event", "priv_broadcast"} {
+		if db.DB().Migrator().HasColumn(&privilegedChat{}, column) {
+			t.Errorf("chats still have %s", column)
+		}
+	}
+
+	for _, column := range []string{"username", "title", "type", "tester", "language"} {
+		if !db.DB().Migrator().HasColumn(&privilegedChat{}, column) {
+			t.Errorf("chats lost %s", column)
+		}
+	}
+
+	var ids []int64
+	if err := db.DB().Table("chats").Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("select chats: %v", err)
+	}
+
+	if want := []int64{-20, 10, 11, 12}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("chats %v, want %v", ids, want)
+	}
+}
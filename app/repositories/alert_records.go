@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlertRecords struct {
+	db clients.DB
+}
+
+func NewAlertRecords(db clients.DB) AlertRecords {
+	return AlertRecords{db: db}
+}
+
+// Record ends the alerts no longer active and starts the new ones, at the given time.
+// Times are kept in UTC, as sqlite compares them as text.
+func (r AlertRecords) Record(ctx context.Context, alerts types2.Alerts, at time.Time) error {
+	at = at.UTC()
+
+	err := r.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var open types2.AlertRecords
+		if err := tx.Where("ended_at is null").Find(&open).Error; err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+
+		active, recorded := alerts.Areas(), types.Stringies{}
+
+		for _, record := range open {
+			if active.Contains(record.Area) {
+				recorded = append(recorded, record.Area)
+
+				continue
+			}
+
+			if err := tx.Model(&record).Update("ended_at", at).Error; err != nil {
+				return fmt.Errorf("end %s: %w", record.Area, err)
+			}
+		}
+
+		for _, alert := range alerts {
+			if recorded.Contains(alert.ID) {
+				continue
+			}
+
+			if err := tx.Create(&types2.AlertRecord{Area: alert.ID, Type: alert.Type, StartedAt: at}).Error; err != nil {
+				return fmt.Errorf("start %s: %w", alert.ID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("tx: %w", err)
+	}
+
+	return nil
+}
+
+// Between returns the alerts in the areas that went on at some point of [from, to).
+func (r AlertRecords) Between(ctx context.Context, areas []string, from, to time.Time) (types2.AlertRecords, error) {
+	if len(areas) == 0 {
+		return nil, nil
+	}
+
+	var list types2.AlertRecords
+
+	err := r.db.DB().WithContext(ctx).
+		Where("area in ? and started_at < ? and (ended_at is null or ended_at > ?)", areas, to.UTC(), from.UTC()).
+		Order("started_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
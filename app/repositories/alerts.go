@@ -5,7 +5,9 @@ import (
 	types2 "closealerts/app/repositories/types"
 	"context"
 	"fmt"
+	"time"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -17,9 +19,30 @@ func NewAlerts(db clients.DB) Alerts {
 	return Alerts{db: db}
 }
 
+// ReplaceAlerts stores the alerts along with the time of the refresh, in one transaction.
 func (r Alerts) ReplaceAlerts(ctx context.Context, alerts []types2.Alert) error {
+	err := r.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := replaceAlerts(tx, alerts); err != nil {
+			return err
+		}
+
+		refresh := types2.AlertRefresh{ID: 1, RefreshedAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}}, UpdateAll: true}).Create(&refresh).Error; err != nil {
+			return fmt.Errorf("save refresh: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+
+	return nil
+}
+
+func replaceAlerts(tx *gorm.DB, alerts []types2.Alert) error {
 	if len(alerts) == 0 {
-		if err := r.db.DB().WithContext(ctx).Where("1 = 1").Delete(&types2.Alert{}).Error; err != nil {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&types2.Alert{}).Error; err != nil {
 			return fmt.Errorf("clear alerts: %w", err)
 		}
 
@@ -31,7 +54,7 @@ func (r Alerts) ReplaceAlerts(ctx context.Context, alerts []types2.Alert) error
 		UpdateAll: true,
 	}
 
-	if err := r.db.DB().WithContext(ctx).Clauses(cond).Create(alerts).Error; err != nil {
+	if err := tx.Clauses(cond).Create(alerts).Error; err != nil {
 		return fmt.Errorf("create: %w", err)
 	}
 
@@ -40,16 +63,31 @@ func (r Alerts) ReplaceAlerts(ctx context.Context, alerts []types2.Alert) error
 		ids = append(ids, alert.ID)
 	}
 
-	if err := r.db.DB().WithContext(ctx).Where("id not in (?)", ids).Delete(&types2.Alert{}).Error; err != nil {
+	if err := tx.Where("id not in (?)", ids).Delete(&types2.Alert{}).Error; err != nil {
 		return fmt.Errorf("delete rest: %w", err)
 	}
 
 	return nil
 }
 
+// RefreshedAt returns when the alerts were last stored, zero if never.
+func (r Alerts) RefreshedAt(ctx context.Context) (time.Time, error) {
+	var refreshes []types2.AlertRefresh
+	if err := r.db.DB().WithContext(ctx).Where("id = ?", 1).Limit(1).Find(&refreshes).Error; err != nil {
+		return time.Time{}, fmt.Errorf("select: %w", err)
+	}
+
+	if len(refreshes) == 0 {
+		return time.Time{}, nil
+	}
+
+	return refreshes[0].RefreshedAt, nil
+}
+
+// GetActive returns real alerts only, simulated ones are never shown in lists and on the map.
 func (r Alerts) GetActive(ctx context.Context) ([]types2.Alert, error) {
 	var list []types2.Alert
-	if err := r.db.DB().WithContext(ctx).Find(&list).Error; err != nil {
+	if err := r.db.DB().WithContext(ctx).Where("simulated = ?", false).Find(&list).Error; err != nil {
 		return nil, fmt.Errorf("get alerts from db: %w", err)
 	}
 
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+)
+
+type Audit struct {
+	db clients.DB
+}
+
+func NewAudit(db clients.DB) Audit {
+	return Audit{db: db}
+}
+
+func (r Audit) Record(ctx context.Context, event types2.AuditEvent) error {
+	if err := r.db.DB().WithContext(ctx).Create(&event).Error; err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	return nil
+}
+
+func (r Audit) Recent(ctx context.Context, limit, offset int) (types2.AuditEvents, error) {
+	var list types2.AuditEvents
+
+	err := r.db.DB().WithContext(ctx).Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r Audit) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.DB().WithContext(ctx).Model(&types2.AuditEvent{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type Broadcasts struct {
+	db clients.DB
+}
+
+func NewBroadcasts(db clients.DB) Broadcasts {
+	return Broadcasts{db: db}
+}
+
+func (r Broadcasts) Create(ctx context.Context, broadcast types2.Broadcast) (types2.Broadcast, error) {
+	if err := r.db.DB().WithContext(ctx).Create(&broadcast).Error; err != nil {
+		return types2.Broadcast{}, fmt.Errorf("create: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (r Broadcasts) Get(ctx context.Context, id int64) (types2.Broadcast, bool, error) {
+	var broadcast types2.Broadcast
+
+	err := r.db.DB().WithContext(ctx).Where("id = ?", id).Take(&broadcast).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types2.Broadcast{}, false, nil
+	}
+
+	if err != nil {
+		return types2.Broadcast{}, false, fmt.Errorf("select %d: %w", id, err)
+	}
+
+	return broadcast, true, nil
+}
+
+func (r Broadcasts) Save(ctx context.Context, broadcast types2.Broadcast) error {
+	if err := r.db.DB().WithContext(ctx).Save(&broadcast).Error; err != nil {
+		return fmt.Errorf("save %d: %w", broadcast.ID, err)
+	}
+
+	return nil
+}
+
+// Transition moves the broadcast from one status to another, reporting false if it was not in the former one,
+// so a broadcast cannot be confirmed twice.
+func (r Broadcasts) Transition(ctx context.Context, id int64, from, to string, messageID int) (bool, error) {
+	res := r.db.DB().WithContext(ctx).
+		Model(&types2.Broadcast{}).
+		Where("id = ? and status = ?", id, from).
+		Updates(map[string]interface{}{"status": to, "message_id": messageID})
+	if res.Error != nil {
+		return false, fmt.Errorf("update %d: %w", id, res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (r Broadcasts) Running(ctx context.Context) (types2.Broadcasts, error) {
+	var list types2.Broadcasts
+	if err := r.db.DB().WithContext(ctx).Where("status = ?", types2.BroadcastRunning).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
@@ -16,50 +16,56 @@ func NewChats(db clients.DB) Chats {
 }
 
 func (r Chats) CreateOrSelect(ctx context.Context, chat types2.Chat) (types2.Chat, error) {
-	if err := r.db.DB().WithContext(ctx).FirstOrCreate(&chat).Error; err != nil {
+	// keep the name and type up to date: groups get renamed and upgraded to supergroups
+	// the language is only guessed for new chats, it must not override the one chosen with /lang
+	attrs := types2.Chat{Username: chat.Username, Title: chat.Title, Type: chat.Type}
+	created := types2.Chat{Language: chat.Language}
+
+	err := r.db.DB().WithContext(ctx).Where(types2.Chat{ID: chat.ID}).Attrs(created).Assign(attrs).FirstOrCreate(&chat).Error
+	if err != nil {
 		return types2.Chat{}, fmt.Errorf("first or create: %w", err)
 	}
 
 	return chat, nil
 }
 
-func (r Chats) ClearCommand(ctx context.Context, id int64) error {
-	err := r.db.DB().WithContext(ctx).Model(&types2.Chat{}).Where("id = ?", id).UpdateColumn("command", "").Error
+func (r Chats) SetTester(ctx context.Context, id int64, tester bool) error {
+	err := r.db.DB().WithContext(ctx).Model(&types2.Chat{}).Where("id = ?", id).UpdateColumn("tester", tester).Error
 	if err != nil {
-		return fmt.Errorf("clear command: %w", err)
+		return fmt.Errorf("update %d: %w", id, err)
 	}
 
 	return nil
 }
 
-func (r Chats) SetCommand(ctx context.Context, id int64, command string) error {
-	err := r.db.DB().WithContext(ctx).Model(&types2.Chat{}).Where("id = ?", id).UpdateColumn("command", command).Error
+func (r Chats) SetLanguage(ctx context.Context, id int64, lang string) error {
+	err := r.db.DB().WithContext(ctx).Model(&types2.Chat{}).Where("id = ?", id).UpdateColumn("language", lang).Error
 	if err != nil {
-		return fmt.Errorf("set command: %w", err)
+		return fmt.Errorf("update %d: %w", id, err)
 	}
 
 	return nil
 }
 
-func (r Chats) Grant(ctx context.Context, id int64, priv string) error {
-	var col string
-
-	switch priv {
-	case "send_fake_event":
-		col = "priv_send_fake_event"
-	case "send_broadcast":
-		col = "priv_broadcast"
-	default:
-		// silent noop
-		return nil
+// Languages returns the languages of the chats having one.
+func (r Chats) Languages(ctx context.Context, ids []int64) (map[int64]string, error) {
+	if len(ids) == 0 {
+		return map[int64]string{}, nil
 	}
 
-	err := r.db.DB().WithContext(ctx).Model(&types2.Chat{}).Where("id = ?", id).UpdateColumn(col, true).Error
+	var list types2.Chats
+
+	err := r.db.DB().WithContext(ctx).Select("id", "language").Where("id in ? and language <> ''", ids).Find(&list).Error
 	if err != nil {
-		return fmt.Errorf("set priv %s: %w", priv, err)
+		return nil, fmt.Errorf("find: %w", err)
 	}
 
-	return nil
+	out := make(map[int64]string, len(list))
+	for _, chat := range list {
+		out[chat.ID] = chat.Language
+	}
+
+	return out, nil
 }
 
 func (r Chats) All(ctx context.Context) (types2.Chats, error) {
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Conversations struct {
+	db clients.DB
+}
+
+func NewConversations(db clients.DB) Conversations {
+	return Conversations{db: db}
+}
+
+func (r Conversations) Get(ctx context.Context, chatID int64) (types2.Conversation, bool, error) {
+	var conv types2.Conversation
+
+	err := r.db.DB().WithContext(ctx).Where("chat_id = ?", chatID).Take(&conv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types2.Conversation{}, false, nil
+	}
+
+	if err != nil {
+		return types2.Conversation{}, false, fmt.Errorf("select %d: %w", chatID, err)
+	}
+
+	return conv, true, nil
+}
+
+func (r Conversations) Save(ctx context.Context, conv types2.Conversation) error {
+	cond := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}},
+		UpdateAll: true,
+	}
+
+	if err := r.db.DB().WithContext(ctx).Clauses(cond).Create(&conv).Error; err != nil {
+		return fmt.Errorf("upsert %d: %w", conv.ChatID, err)
+	}
+
+	return nil
+}
+
+func (r Conversations) Delete(ctx context.Context, chatID int64) error {
+	if err := r.db.DB().WithContext(ctx).Where("chat_id = ?", chatID).Delete(&types2.Conversation{}).Error; err != nil {
+		return fmt.Errorf("delete %d: %w", chatID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Digests struct {
+	db clients.DB
+}
+
+func NewDigests(db clients.DB) Digests {
+	return Digests{db: db}
+}
+
+// Get returns the digest settings of the chat, if it wants digests.
+func (r Digests) Get(ctx context.Context, chatID int64) (types2.Digest, bool, error) {
+	var digest types2.Digest
+
+	err := r.db.DB().WithContext(ctx).Where("chat_id = ?", chatID).Take(&digest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return types2.Digest{}, false, nil
+	}
+
+	if err != nil {
+		return types2.Digest{}, false, fmt.Errorf("take %d: %w", chatID, err)
+	}
+
+	return digest, true, nil
+}
+
+// Save keeps the time in UTC, as sqlite compares times as text.
+func (r Digests) Save(ctx context.Context, digest types2.Digest) error {
+	digest.NextAt = digest.NextAt.UTC()
+
+	if err := r.db.DB().WithContext(ctx).Save(&digest).Error; err != nil {
+		return fmt.Errorf("save %d: %w", digest.ChatID, err)
+	}
+
+	return nil
+}
+
+func (r Digests) Delete(ctx context.Context, chatID int64) (bool, error) {
+	tx := r.db.DB().WithContext(ctx).Where("chat_id = ?", chatID).Delete(&types2.Digest{})
+	if tx.Error != nil {
+		return false, fmt.Errorf("delete %d: %w", chatID, tx.Error)
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+func (r Digests) Due(ctx context.Context, now time.Time) (types2.Digests, error) {
+	var list types2.Digests
+
+	if err := r.db.DB().WithContext(ctx).Where("next_at <= ?", now.UTC()).Order("next_at").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -26,11 +27,12 @@ func (r Notification) Track(ctx context.Context, chatID int64, area string) erro
 			return fmt.Errorf("select: %w", err)
 		}
 
-		if notif.ChatID > 0 {
+		// group chats have negative IDs, so the found row is told by its key
+		if notif.ID > 0 {
 			return fmt.Errorf("%d-%s: %w", chatID, area, types.ErrLinkExists)
 		}
 
-		if err := tx.WithContext(ctx).Create(types2.Notification{ChatID: chatID, Area: area}).Error; err != nil {
+		if err := tx.WithContext(ctx).Create(&types2.Notification{ChatID: chatID, Area: area}).Error; err != nil {
 			return fmt.Errorf("track %d %s: %w", chatID, area, err)
 		}
 
@@ -44,6 +46,50 @@ func (r Notification) Track(ctx context.Context, chatID int64, area string) erro
 	return nil
 }
 
+func (r Notification) TrackNear(ctx context.Context, chatID int64, near string, areas []string) ([]string, error) {
+	var added []string
+
+	err := r.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing types2.Notifications
+
+		if err := tx.WithContext(ctx).Where("chat_id = ? and area in (?)", chatID, areas).Find(&existing).Error; err != nil {
+			return fmt.Errorf("select: %w", err)
+		}
+
+		tracking := existing.Areas()
+
+		for _, area := range areas {
+			if tracking.Contains(area) {
+				continue
+			}
+
+			notif := types2.Notification{ChatID: chatID, Area: area, Near: near}
+			if err := tx.WithContext(ctx).Create(&notif).Error; err != nil {
+				return fmt.Errorf("track %d %s near %s: %w", chatID, area, near, err)
+			}
+
+			added = append(added, area)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("tx: %w", err)
+	}
+
+	return added, nil
+}
+
+func (r Notification) StopNear(ctx context.Context, chatID int64, near string) error {
+	err := r.db.DB().WithContext(ctx).Where("chat_id = ? and near = ?", chatID, near).Delete(&types2.Notification{}).Error
+	if err != nil {
+		return fmt.Errorf("delete %d near %s: %w", chatID, near, err)
+	}
+
+	return nil
+}
+
 func (r Notification) Tracking(ctx context.Context, id int64) ([]types2.Notification, error) {
 	var out []types2.Notification
 
@@ -54,6 +100,21 @@ func (r Notification) Tracking(ctx context.Context, id int64) ([]types2.Notifica
 	return out, nil
 }
 
+// ChatIDs returns chats subscribed to any of the areas, or to anything at all when no areas are given.
+func (r Notification) ChatIDs(ctx context.Context, areas []string) ([]int64, error) {
+	query := r.db.DB().WithContext(ctx).Model(&types2.Notification{}).Distinct("chat_id")
+	if len(areas) > 0 {
+		query = query.Where("area in ?", areas)
+	}
+
+	var ids []int64
+	if err := query.Pluck("chat_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("pluck: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r Notification) Stop(ctx context.Context, id int64, area string) error {
 	err := r.db.DB().WithContext(ctx).Where("chat_id = ? and area = ?", id, area).Delete(&types2.Notification{}).Error
 	if err != nil {
@@ -68,15 +129,16 @@ func (r Notification) Eligible(ctx context.Context, alerts []types2.Alert) (type
 		return nil, nil
 	}
 
-	areas := make([]string, 0, len(alerts))
-	for _, alert := range alerts {
-		areas = append(areas, alert.ID)
-	}
+	active, args := activeFor(alerts)
+	inReal, realArgs := inAreas(types2.Alerts(alerts).Real().Areas())
+
+	// testers notified about a simulated alert are notified again when a real one replaces it
+	args = append(append(args, false, true, true), realArgs...)
 
 	var notif types2.Notifications
 
 	err := r.db.DB().WithContext(ctx).
-		Where("area in (?) and notified = false", areas).
+		Where("("+active+" and notified = ?) or (notified = ? and notified_simulated = ? and "+inReal+")", args...).
 		Order("chat_id").
 		Find(&notif).
 		Error
@@ -89,12 +151,47 @@ func (r Notification) Eligible(ctx context.Context, alerts []types2.Alert) (type
 	return notif, nil
 }
 
+// activeFor is the condition of a notification's area being under alert for its chat:
+// simulated alerts only count for tester chats.
+func activeFor(alerts types2.Alerts) (string, []interface{}) {
+	inReal, realArgs := inAreas(alerts.Real().Areas())
+	inAll, allArgs := inAreas(alerts.Areas())
+
+	args := append(append(realArgs, allArgs...), true)
+
+	return "(" + inReal + " or (" + inAll + " and chat_id in (select id from chats where tester = ?)))", args
+}
+
+// endedFor is the condition of a notified subscription's alert having ended, as the kind of alert it was
+// notified about: a real one ends with the real alert even if a simulated one goes on in the area.
+func endedFor(alerts types2.Alerts) (string, []interface{}) {
+	inReal, realArgs := inAreas(alerts.Real().Areas())
+	active, activeArgs := activeFor(alerts)
+
+	args := append(append(append([]interface{}{true, false}, realArgs...), true), activeArgs...)
+
+	return "notified = ? and ((notified_simulated = ? and not " + inReal + ") or " +
+		"(notified_simulated = ? and not " + active + "))", args
+}
+
+func inAreas(areas types.Stringies) (string, []interface{}) {
+	if len(areas) == 0 {
+		return "1 = 0", nil
+	}
+
+	return "area in (?)", []interface{}{[]string(areas)}
+}
+
 func (r Notification) Notified(ctx context.Context, eligible types2.Notification) error {
 	err := r.db.DB().
 		WithContext(ctx).
 		Model(&types2.Notification{}).
 		Where("chat_id = ? and area = ?", eligible.ChatID, eligible.Area).
-		UpdateColumn("notified", true).
+		UpdateColumns(map[string]interface{}{
+			"notified":           true,
+			"notified_simulated": eligible.NotifiedSimulated,
+			"notified_at":        time.Now(),
+		}).
 		Error
 
 	if err != nil {
@@ -105,20 +202,12 @@ func (r Notification) Notified(ctx context.Context, eligible types2.Notification
 }
 
 func (r Notification) Unmark(ctx context.Context, alerts []types2.Alert) error {
-	areas := make([]string, 0, len(alerts))
-	for _, alert := range alerts {
-		areas = append(areas, alert.ID)
-	}
+	ended, args := endedFor(alerts)
 
-	tx := r.db.DB().WithContext(ctx).Model(&types2.Notification{})
+	tx := r.db.DB().WithContext(ctx).Model(&types2.Notification{}).Where(ended, args...)
 
-	if len(areas) > 0 {
-		tx = tx.Where("area not in (?)", areas)
-	} else {
-		tx = tx.Where("1 = 1")
-	}
-
-	if err := tx.UpdateColumn("notified", false).Error; err != nil {
+	update := map[string]interface{}{"notified": false, "notified_simulated": false, "cleared_at": time.Now()}
+	if err := tx.UpdateColumns(update).Error; err != nil {
 		return fmt.Errorf("unmark: %w", err)
 	}
 
@@ -128,29 +217,17 @@ func (r Notification) Unmark(ctx context.Context, alerts []types2.Alert) error {
 }
 
 func (r Notification) AlertEnded(ctx context.Context, alerts []types2.Alert) (types2.Notifications, error) {
-	areas := make([]string, 0, len(alerts))
-	for _, alert := range alerts {
-		areas = append(areas, alert.ID)
-	}
-
-	var (
-		endedFor types2.Notifications
-		err      error
-	)
+	ended, args := endedFor(alerts)
 
-	if len(alerts) == 0 {
-		err = r.db.DB().WithContext(ctx).Where("notified = true").Find(&endedFor).Error
-	} else {
-		err = r.db.DB().WithContext(ctx).Where("area not in (?) and notified = true", areas).Find(&endedFor).Error
-	}
+	var list types2.Notifications
 
-	if err != nil {
+	if err := r.db.DB().WithContext(ctx).Where(ended, args...).Find(&list).Error; err != nil {
 		return nil, fmt.Errorf("alert ended: %w", err)
 	}
 
 	r.log.Debug("mark which alerts has ended")
 
-	return endedFor, nil
+	return list, nil
 }
 
 func NewNotification(log *zap.SugaredLogger, db clients.DB) Notification {
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+type Permissions struct {
+	db clients.DB
+}
+
+func NewPermissions(db clients.DB) Permissions {
+	return Permissions{db: db}
+}
+
+func (r Permissions) Grant(ctx context.Context, permission types2.Permission) error {
+	cond := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "permission"}},
+		DoNothing: true,
+	}
+
+	if err := r.db.DB().WithContext(ctx).Clauses(cond).Create(&permission).Error; err != nil {
+		return fmt.Errorf("grant %s to %d: %w", permission.Permission, permission.UserID, err)
+	}
+
+	return nil
+}
+
+func (r Permissions) Revoke(ctx context.Context, userID int64, permission string) (bool, error) {
+	res := r.db.DB().WithContext(ctx).
+		Where("user_id = ? and permission = ?", userID, permission).
+		Delete(&types2.Permission{})
+	if res.Error != nil {
+		return false, fmt.Errorf("revoke %s from %d: %w", permission, userID, res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+func (r Permissions) ByUserID(ctx context.Context, userID int64) (types2.Permissions, error) {
+	var list types2.Permissions
+	if err := r.db.DB().WithContext(ctx).Where("user_id = ?", userID).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find %d: %w", userID, err)
+	}
+
+	return list, nil
+}
+
+func (r Permissions) All(ctx context.Context) (types2.Permissions, error) {
+	var list types2.Permissions
+	if err := r.db.DB().WithContext(ctx).Order("user_id, permission").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
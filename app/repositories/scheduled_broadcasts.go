@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+	"time"
+)
+
+type ScheduledBroadcasts struct {
+	db clients.DB
+}
+
+func NewScheduledBroadcasts(db clients.DB) ScheduledBroadcasts {
+	return ScheduledBroadcasts{db: db}
+}
+
+func (r ScheduledBroadcasts) Create(
+	ctx context.Context, scheduled types2.ScheduledBroadcast,
+) (types2.ScheduledBroadcast, error) {
+	if err := r.db.DB().WithContext(ctx).Create(&scheduled).Error; err != nil {
+		return types2.ScheduledBroadcast{}, fmt.Errorf("create: %w", err)
+	}
+
+	return scheduled, nil
+}
+
+func (r ScheduledBroadcasts) Save(ctx context.Context, scheduled types2.ScheduledBroadcast) error {
+	if err := r.db.DB().WithContext(ctx).Save(&scheduled).Error; err != nil {
+		return fmt.Errorf("save %d: %w", scheduled.ID, err)
+	}
+
+	return nil
+}
+
+func (r ScheduledBroadcasts) Active(ctx context.Context) (types2.ScheduledBroadcasts, error) {
+	var list types2.ScheduledBroadcasts
+
+	err := r.db.DB().WithContext(ctx).Where("active = ?", true).Order("next_run_at").Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r ScheduledBroadcasts) Due(ctx context.Context, now time.Time) (types2.ScheduledBroadcasts, error) {
+	var list types2.ScheduledBroadcasts
+
+	err := r.db.DB().WithContext(ctx).
+		Where("active = ? and next_run_at <= ?", true, now).
+		Order("next_run_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r ScheduledBroadcasts) Deactivate(ctx context.Context, id int64) (bool, error) {
+	res := r.db.DB().WithContext(ctx).
+		Model(&types2.ScheduledBroadcast{}).
+		Where("id = ? and active = ?", id, true).
+		Update("active", false)
+	if res.Error != nil {
+		return false, fmt.Errorf("update %d: %w", id, res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
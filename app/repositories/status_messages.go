@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+type StatusMessages struct {
+	db clients.DB
+}
+
+func NewStatusMessages(db clients.DB) StatusMessages {
+	return StatusMessages{db: db}
+}
+
+func (r StatusMessages) All(ctx context.Context) (types2.StatusMessages, error) {
+	var list types2.StatusMessages
+	if err := r.db.DB().WithContext(ctx).Order("chat_id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+// StatusMessages returns the status messages of the chats that have one.
+func (r StatusMessages) StatusMessages(ctx context.Context, ids []int64) (types2.StatusMessages, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var list types2.StatusMessages
+	if err := r.db.DB().WithContext(ctx).Where("chat_id in ?", ids).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r StatusMessages) Save(ctx context.Context, msg types2.StatusMessage) error {
+	err := r.db.DB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message_id", "text", "updated_at"}),
+	}).Create(&msg).Error
+	if err != nil {
+		return fmt.Errorf("upsert %d: %w", msg.ChatID, err)
+	}
+
+	return nil
+}
+
+func (r StatusMessages) Delete(ctx context.Context, chatID int64) error {
+	if err := r.db.DB().WithContext(ctx).Where("chat_id = ?", chatID).Delete(&types2.StatusMessage{}).Error; err != nil {
+		return fmt.Errorf("delete %d: %w", chatID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"closealerts/app/clients"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+type Templates struct {
+	db clients.DB
+}
+
+func NewTemplates(db clients.DB) Templates {
+	return Templates{db: db}
+}
+
+// Templates returns the templates of the chats.
+func (r Templates) Templates(ctx context.Context, ids []int64) (types2.ChatTemplates, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var list types2.ChatTemplates
+	if err := r.db.DB().WithContext(ctx).Where("chat_id in ?", ids).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("find: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r Templates) Save(ctx context.Context, template types2.ChatTemplate) error {
+	err := r.db.DB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"format", "text", "updated_at"}),
+	}).Create(&template).Error
+	if err != nil {
+		return fmt.Errorf("upsert %d %s: %w", template.ChatID, template.Kind, err)
+	}
+
+	return nil
+}
+
+func (r Templates) Delete(ctx context.Context, chatID int64, kind string) (bool, error) {
+	tx := r.db.DB().WithContext(ctx).Where("chat_id = ? and kind = ?", chatID, kind).Delete(&types2.ChatTemplate{})
+	if tx.Error != nil {
+		return false, fmt.Errorf("delete %d %s: %w", chatID, kind, tx.Error)
+	}
+
+	return tx.RowsAffected > 0, nil
+}
@@ -1,14 +1,26 @@
 package types
 
-import "closealerts/app/types"
+import (
+	"closealerts/app/types"
+	"time"
+)
 
 type Alert struct {
 	ID   string `gorm:"column:id"`
 	Type string `gorm:"column:type"`
+
+	// Simulated alerts come from services.Fakes and only reach tester chats.
+	Simulated bool `gorm:"column:simulated"`
 }
 
 type Alerts []Alert
 
+// AlertRefresh is the single row keeping when the alerts were last fetched from a source.
+type AlertRefresh struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement:false"`
+	RefreshedAt time.Time `gorm:"column:refreshed_at"`
+}
+
 func (r Alerts) Areas() types.Stringies {
 	if len(r) == 0 {
 		return nil
@@ -21,3 +33,23 @@ func (r Alerts) Areas() types.Stringies {
 
 	return areas
 }
+
+func (r Alerts) Real() Alerts {
+	return r.filter(false)
+}
+
+func (r Alerts) Simulated() Alerts {
+	return r.filter(true)
+}
+
+func (r Alerts) filter(simulated bool) Alerts {
+	var out Alerts
+
+	for _, alert := range r {
+		if alert.Simulated == simulated {
+			out = append(out, alert)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+type AuditEvent struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index"`
+	ActorID   int64     `gorm:"column:actor_id"`
+	ChatID    int64     `gorm:"column:chat_id"`
+	Action    string    `gorm:"column:action"`
+	Args      string    `gorm:"column:args"`
+	Result    string    `gorm:"column:result"`
+}
+
+type AuditEvents []AuditEvent
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+const (
+	BroadcastDraft     = "draft"
+	BroadcastRunning   = "running"
+	BroadcastDone      = "done"
+	BroadcastCancelled = "cancelled"
+)
+
+const (
+	BroadcastTargetAll    = "all"
+	BroadcastTargetActive = "active"
+	// BroadcastTargetAreas prefixes the comma-separated areas whose subscribers get the broadcast.
+	BroadcastTargetAreas = "areas:"
+)
+
+type Broadcast struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	CreatedBy int64     `gorm:"column:created_by"`
+
+	// ChatID and MessageID point to the status message the progress is reported in.
+	ChatID    int64 `gorm:"column:chat_id"`
+	MessageID int   `gorm:"column:message_id"`
+
+	Text   string `gorm:"column:text"`
+	Target string `gorm:"column:target"`
+	Status string `gorm:"column:status;index"`
+
+	Total     int `gorm:"column:total"`
+	Delivered int `gorm:"column:delivered"`
+	Blocked   int `gorm:"column:blocked"`
+	Failed    int `gorm:"column:failed"`
+
+	// Cursor is the last chat ID handled; recipients are processed in the ascending order of IDs.
+	Cursor int64 `gorm:"column:cursor"`
+}
+
+type Broadcasts []Broadcast
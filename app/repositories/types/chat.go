@@ -1,12 +1,15 @@
 package types
 
 type Chat struct {
-	ID       int64  `gorm:"column:id"`
+	ID       int64  `gorm:"column:id;primaryKey;autoIncrement:false"`
 	Username string `gorm:"column:username"`
-	Command  string `gorm:"column:command"`
+	Title    string `gorm:"column:title"`
+	Type     string `gorm:"column:type"`
 
-	PrivSendFakeEvent bool `gorm:"column:priv_send_fake_event"`
-	PrivBroadcast     bool `gorm:"column:priv_broadcast"`
+	// Tester chats get simulated alerts along with the real ones.
+	Tester bool `gorm:"column:tester"`
+	// Language is the i18n language of the texts sent to the chat, empty until known.
+	Language string `gorm:"column:language"`
 }
 
 type Chats []Chat
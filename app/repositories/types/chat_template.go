@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// ChatTemplate replaces the default text of a notification kind in the chat.
+type ChatTemplate struct {
+	ChatID int64  `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	Kind   string `gorm:"column:kind;primaryKey"`
+	// Format is text, markdown or html, telling how to escape the values and which parse mode to send with.
+	Format    string    `gorm:"column:format"`
+	Text      string    `gorm:"column:text"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type ChatTemplates []ChatTemplate
+
+// Of returns the template of the chat for the kind, if it has one.
+func (r ChatTemplates) Of(chatID int64, kind string) (ChatTemplate, bool) {
+	for _, template := range r {
+		if template.ChatID == chatID && template.Kind == kind {
+			return template, true
+		}
+	}
+
+	return ChatTemplate{}, false
+}
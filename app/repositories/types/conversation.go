@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+type Conversation struct {
+	ChatID    int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	Flow      string    `gorm:"column:flow"`
+	Step      string    `gorm:"column:step"`
+	Data      string    `gorm:"column:data"`
+	ExpiresAt time.Time `gorm:"column:expires_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+// AlertRecord is a real alert in an area, from its start to its end; EndedAt is nil while it goes on.
+type AlertRecord struct {
+	ID        int64      `gorm:"column:id;primaryKey"`
+	Area      string     `gorm:"column:area;index"`
+	Type      string     `gorm:"column:type"`
+	StartedAt time.Time  `gorm:"column:started_at;index"`
+	EndedAt   *time.Time `gorm:"column:ended_at;index"`
+}
+
+type AlertRecords []AlertRecord
+
+// Digest is when the chat wants the summary of the alerts in its areas, and whether with a chart.
+type Digest struct {
+	ChatID int64 `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	// Period is day or week.
+	Period string `gorm:"column:period"`
+	Hour   int    `gorm:"column:hour"`
+	Minute int    `gorm:"column:minute"`
+	// Weekday is the day weekly digests come on, as time.Weekday.
+	Weekday int       `gorm:"column:weekday"`
+	Chart   bool      `gorm:"column:chart"`
+	NextAt  time.Time `gorm:"column:next_at;index"`
+}
+
+type Digests []Digest
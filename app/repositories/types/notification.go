@@ -3,16 +3,41 @@ package types
 import (
 	"closealerts/app/types"
 	"sort"
+	"time"
 )
 
 type Notification struct {
-	ChatID   int64  `gorm:"column:chat_id"`
-	Area     string `gorm:"column:area"`
+	ID       int64  `gorm:"column:id;primaryKey"`
+	ChatID   int64  `gorm:"column:chat_id;uniqueIndex:idx_notifications_chat_area"`
+	Area     string `gorm:"column:area;uniqueIndex:idx_notifications_chat_area"`
 	Notified bool   `gorm:"column:notified"`
+	// NotifiedSimulated tells the chat was notified about a simulated alert, which a real one must not be
+	// mistaken for.
+	NotifiedSimulated bool `gorm:"column:notified_simulated"`
+
+	// Near is the area the subscription was made around with /near; empty for direct subscriptions.
+	Near string `gorm:"column:near"`
+	// NotifiedAt is when the chat was last notified about an alert in the area.
+	NotifiedAt *time.Time `gorm:"column:notified_at"`
+	// ClearedAt is when the alert in the area last ended for the chat.
+	ClearedAt *time.Time `gorm:"column:cleared_at"`
 }
 
 type Notifications []Notification
 
+// NotifiedSince is the earliest time the chat was notified about the alerts, if it is known.
+func (r Notifications) NotifiedSince() (time.Time, bool) {
+	var since time.Time
+
+	for _, notification := range r {
+		if notification.NotifiedAt != nil && (since.IsZero() || notification.NotifiedAt.Before(since)) {
+			since = *notification.NotifiedAt
+		}
+	}
+
+	return since, !since.IsZero()
+}
+
 func (r Notifications) Areas() types.Stringies {
 	if len(r) == 0 {
 		return nil
@@ -57,6 +82,18 @@ func (r Notifications) GroupByChatID() map[int64]Notifications {
 	return out
 }
 
+func (r Notifications) NearGroups() types.Stringies {
+	var groups types.Stringies
+
+	for _, notification := range r {
+		if len(notification.Near) > 0 && !groups.Contains(notification.Near) {
+			groups = append(groups, notification.Near)
+		}
+	}
+
+	return groups
+}
+
 func (r Notifications) Tracking(payload string) bool {
 	for _, notification := range r {
 		if notification.Area == payload {
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotificationsGroupByChatID(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Notifications
+		want map[int64]Notifications
+	}{
+		{name: "nil", in: nil, want: nil},
+		{
+			name: "one chat",
+			in:   Notifications{{ChatID: 1, Area: "a"}, {ChatID: 1, Area: "b"}},
+			want: map[int64]Notifications{1: {{ChatID: 1, Area: "a"}, {ChatID: 1, Area: "b"}}},
+		},
+		{
+			name: "one per chat",
+			in:   Notifications{{ChatID: 2, Area: "a"}, {ChatID: 1, Area: "a"}, {ChatID: 3, Area: "a"}},
+			want: map[int64]Notifications{
+				1: {{ChatID: 1, Area: "a"}},
+				2: {{ChatID: 2, Area: "a"}},
+				3: {{ChatID: 3, Area: "a"}},
+			},
+		},
+		{
+			name: "interleaved chats",
+			in: Notifications{
+				{ChatID: 2, Area: "a"}, {ChatID: 1, Area: "a"}, {ChatID: 2, Area: "b"},
+				{ChatID: -5, Area: "a"}, {ChatID: 1, Area: "b"}, {ChatID: 2, Area: "c"},
+			},
+			want: map[int64]Notifications{
+				-5: {{ChatID: -5, Area: "a"}},
+				1:  {{ChatID: 1, Area: "a"}, {ChatID: 1, Area: "b"}},
+				2:  {{ChatID: 2, Area: "a"}, {ChatID: 2, Area: "b"}, {ChatID: 2, Area: "c"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append(Notifications(nil), tt.in...)
+
+			if got := tt.in.GroupByChatID(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupByChatID() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.in, in) {
+				t.Errorf("GroupByChatID() changed the receiver to %v", tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+type Permission struct {
+	ID         int64     `gorm:"column:id;primaryKey"`
+	UserID     int64     `gorm:"column:user_id;uniqueIndex:idx_permissions_user_permission"`
+	Permission string    `gorm:"column:permission;uniqueIndex:idx_permissions_user_permission"`
+	GrantedBy  int64     `gorm:"column:granted_by"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+type Permissions []Permission
+
+func (r Permissions) GroupByUserID() map[int64][]string {
+	if len(r) == 0 {
+		return nil
+	}
+
+	out := make(map[int64][]string)
+	for _, permission := range r {
+		out[permission.UserID] = append(out[permission.UserID], permission.Permission)
+	}
+
+	return out
+}
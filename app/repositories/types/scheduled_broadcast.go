@@ -0,0 +1,21 @@
+package types
+
+import "time"
+
+type ScheduledBroadcast struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	CreatedBy int64     `gorm:"column:created_by"`
+
+	// ChatID is where the admin scheduled the broadcast and gets its progress reported.
+	ChatID int64  `gorm:"column:chat_id"`
+	Text   string `gorm:"column:text"`
+	Target string `gorm:"column:target"`
+
+	// Cron is a standard five-field schedule for recurring broadcasts; empty for one-off ones.
+	Cron      string    `gorm:"column:cron"`
+	NextRunAt time.Time `gorm:"column:next_run_at;index"`
+	Active    bool      `gorm:"column:active;index"`
+}
+
+type ScheduledBroadcasts []ScheduledBroadcast
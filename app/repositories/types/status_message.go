@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// StatusMessage is the pinned message the bot keeps editing to show the state of the areas the chat tracks;
+// chats having one get no separate notifications.
+type StatusMessage struct {
+	ChatID    int64 `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	MessageID int   `gorm:"column:message_id"`
+	// Text is what the message shows, so it is only edited when that changes.
+	Text      string    `gorm:"column:text"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+type StatusMessages []StatusMessage
+
+// Has tells if the chat has a status message.
+func (r StatusMessages) Has(chatID int64) bool {
+	for _, msg := range r {
+		if msg.ChatID == chatID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,7 +2,11 @@ package server
 
 import (
 	"closealerts/app/handlers"
+	"closealerts/app/metrics"
+	"closealerts/app/types"
 	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func NewMux() *http.ServeMux {
@@ -13,3 +17,14 @@ func RegisterWebhook(mux *http.ServeMux, webhook handlers.WebhookHandler) {
 	mux.HandleFunc("/tgwebhook", webhook.Pipe)
 	mux.HandleFunc("/helloworld", webhook.HelloWorld)
 }
+
+func RegisterHealth(mux *http.ServeMux, health handlers.HealthHandler) {
+	mux.HandleFunc("/healthz", health.Healthz)
+	mux.HandleFunc("/readyz", health.Readyz)
+}
+
+func RegisterMetrics(mux *http.ServeMux, config types.Config) {
+	metrics.RegisterQueueDepth(func() int { return len(config.Updates) })
+
+	mux.Handle("/metrics", promhttp.Handler())
+}
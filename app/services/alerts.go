@@ -1,27 +1,71 @@
 package services
 
 import (
-	"closealerts/app/repositories"
+	"closealerts/app/metrics"
 	types2 "closealerts/app/repositories/types"
+	"closealerts/app/tracing"
+	"closealerts/app/types"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
+// AlertStore keeps the alerts going on; repositories.Alerts implements it.
+type AlertStore interface {
+	ReplaceAlerts(ctx context.Context, alerts []types2.Alert) error
+	GetActive(ctx context.Context) ([]types2.Alert, error)
+	RefreshedAt(ctx context.Context) (time.Time, error)
+}
+
+// SourceFetcher fetches the alert sources by URL; clients.Sources implements it.
+type SourceFetcher interface {
+	Get(ctx context.Context, rawURL string) ([]byte, error)
+}
+
 type Alerts struct {
-	log    *zap.SugaredLogger
-	alerts repositories.Alerts
+	log     *zap.SugaredLogger
+	alerts  AlertStore
+	sources SourceFetcher
+	health  Health
+	// staleAfter is the age alert data is shown with a warning from
+	staleAfter time.Duration
+	keys       *sourceKeys
 }
 
-func NewAlerts(log *zap.SugaredLogger, alerts repositories.Alerts) Alerts {
-	return Alerts{log: log, alerts: alerts}
+// sourceKeys are the API keys of the sources, changed on config reload.
+type sourceKeys struct {
+	mu     sync.RWMutex
+	ukrzen string
+}
+
+func NewAlerts(
+	log *zap.SugaredLogger,
+	config types.Config,
+	alerts AlertStore,
+	sources SourceFetcher,
+	health Health,
+) Alerts {
+	return Alerts{
+		log:        log,
+		alerts:     alerts,
+		sources:    sources,
+		health:     health,
+		staleAfter: config.StaleAfter,
+		keys:       &sourceKeys{ukrzen: config.UkrzenAPIKey},
+	}
+}
+
+// Reload takes the source API keys of the config.
+func (r Alerts) Reload(config types.Config) {
+	r.keys.mu.Lock()
+	r.keys.ukrzen = config.UkrzenAPIKey
+	r.keys.mu.Unlock()
 }
 
 func (r Alerts) GetActiveFromRemote(ctx context.Context) ([]types2.Alert, error) {
@@ -40,10 +84,19 @@ func (r Alerts) GetActiveFromRemote(ctx context.Context) ([]types2.Alert, error)
 	)
 
 	for _, source := range sources {
-		if list, err = source.fn(ctx); err != nil {
+		started := time.Now()
+		fetchCtx, span := tracing.Start(ctx, "source fetch", attribute.String("source", source.source))
+		list, err = source.fn(fetchCtx)
+		span.SetAttributes(attribute.Int("alerts.count", len(list)))
+		tracing.End(span, err)
+		metrics.SourceFetchDuration.WithLabelValues(source.source).Observe(time.Since(started).Seconds())
+
+		if err != nil {
+			metrics.SourceFetchErrors.WithLabelValues(source.source).Inc()
 			r.log.Errorw("active alerts", "source", source.source, "err", err)
 		} else {
 			r.log.Infow("active alerts", "source", source.source)
+			r.health.SourceSucceeded(source.source)
 
 			break
 		}
@@ -75,6 +128,17 @@ func (r Alerts) GetActive(ctx context.Context) (types2.Alerts, error) {
 	return list, nil
 }
 
+// Freshness tells when the stored alerts were last refreshed and whether they are stale by now.
+// Never refreshed data counts as stale.
+func (r Alerts) Freshness(ctx context.Context) (time.Time, bool, error) {
+	refreshedAt, err := r.alerts.RefreshedAt(ctx)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("refreshed at: %w", err)
+	}
+
+	return refreshedAt, refreshedAt.IsZero() || time.Since(refreshedAt) > r.staleAfter, nil
+}
+
 type Ukrzen2Response struct {
 	Alerts Ukrzen2Alert `json:"alerts"`
 }
@@ -112,9 +176,11 @@ func (r Alerts) ukrzen(ctx context.Context) (types2.Alerts, error) {
 		err  error
 	)
 
-	apiKey := os.Getenv("UKRZEN_API_KEY")
+	r.keys.mu.RLock()
+	apiKey := r.keys.ukrzen
+	r.keys.mu.RUnlock()
 
-	if err = mkReqUnmarshal(ctx, "https://api.alerts.in.ua/v2/alerts/active.json?token="+apiKey, &resp); err != nil {
+	if err = r.getJSON(ctx, "https://api.alerts.in.ua/v2/alerts/active.json?token="+apiKey, &resp); err != nil {
 		return nil, fmt.Errorf("mk req: %w", err)
 	}
 
@@ -145,7 +211,7 @@ func (r Alerts) alarmmap(ctx context.Context) (types2.Alerts, error) {
 		err  error
 	)
 
-	if err = mkReqUnmarshal(ctx, "https://alarmmap.online/assets/alerts.json", &resp); err != nil {
+	if err = r.getJSON(ctx, "https://alarmmap.online/assets/alerts.json", &resp); err != nil {
 		return nil, fmt.Errorf("mk req: %w", err)
 	}
 
@@ -169,24 +235,12 @@ func (r Alerts) alarmmap(ctx context.Context) (types2.Alerts, error) {
 	return list, nil
 }
 
-func mkReqUnmarshal(ctx context.Context, url string, dst interface{}) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-
+func (r Alerts) getJSON(ctx context.Context, url string, dst interface{}) error {
+	bts, err := r.sources.Get(ctx, url)
 	if err != nil {
-		return fmt.Errorf("new request with context: %w", err)
+		return fmt.Errorf("get: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("do: %w", err)
-	}
-
-	bts, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("io read all: %w", err)
-	}
-	_ = resp.Body.Close()
-
 	if err := json.Unmarshal(bts, dst); err != nil {
 		return fmt.Errorf("json unmarshal: %w", err)
 	}
@@ -216,7 +270,7 @@ func (r Alerts) vadimklimenko(ctx context.Context) (types2.Alerts, error) {
 		err  error
 	)
 
-	if err = mkReqUnmarshal(ctx, "https://emapa.fra1.cdn.digitaloceanspaces.com/statuses.json", &resp); err != nil {
+	if err = r.getJSON(ctx, "https://emapa.fra1.cdn.digitaloceanspaces.com/statuses.json", &resp); err != nil {
 		return nil, fmt.Errorf("mk req: %w", err)
 	}
 
@@ -224,8 +278,8 @@ func (r Alerts) vadimklimenko(ctx context.Context) (types2.Alerts, error) {
 
 	for state, data := range resp.States {
 		area := strings.SplitN(state, " ", 2)[0]
-		if state == "м. Київ" {
-			area = "м. Київ"
+		if strings.HasPrefix(state, "м. ") || state == "Автономна Республіка Крим" {
+			area = state
 		}
 
 		if data.Enabled {
@@ -247,24 +301,9 @@ func (r Alerts) vadimklimenko(ctx context.Context) (types2.Alerts, error) {
 }
 
 func (r Alerts) GetMapSVGBytes(ctx context.Context) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://war.ukrzen.in.ua/alerts/map.svg", nil)
-
+	bts, err := r.sources.Get(ctx, "https://war.ukrzen.in.ua/alerts/map.svg")
 	if err != nil {
-		return nil, fmt.Errorf("new request with context: %w", err)
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("do: %w", err)
-	}
-
-	bts, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("io read all: %w", err)
-	}
-
-	if err := resp.Body.Close(); err != nil {
-		return nil, fmt.Errorf("resp body close: %w", err)
+		return nil, fmt.Errorf("get: %w", err)
 	}
 
 	return bts, nil
@@ -0,0 +1,61 @@
+package services
+
+import (
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const AuditPageSize = 20
+
+// AuditStore keeps the audit log; repositories.Audit implements it.
+type AuditStore interface {
+	Record(ctx context.Context, event types2.AuditEvent) error
+	Recent(ctx context.Context, limit, offset int) (types2.AuditEvents, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+type Audit struct {
+	log   *zap.SugaredLogger
+	audit AuditStore
+}
+
+func NewAudit(log *zap.SugaredLogger, audit AuditStore) Audit {
+	return Audit{log: log, audit: audit}
+}
+
+// Record writes down the action made by the actor from the context. It is best effort: a failure to record
+// should not undo the action, so it only gets logged.
+func (r Audit) Record(ctx context.Context, chatID int64, action, args, result string) {
+	event := types2.AuditEvent{
+		ActorID: types.ActorFrom(ctx),
+		ChatID:  chatID,
+		Action:  action,
+		Args:    args,
+		Result:  result,
+	}
+
+	if err := r.audit.Record(ctx, event); err != nil {
+		r.log.Errorw("record audit event", "event", event, "err", err)
+	}
+}
+
+// Page returns audit events newest first, together with the total number of pages.
+func (r Audit) Page(ctx context.Context, page int) (types2.AuditEvents, int, error) {
+	count, err := r.audit.Count(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count: %w", err)
+	}
+
+	pages := int((count + AuditPageSize - 1) / AuditPageSize)
+
+	list, err := r.audit.Recent(ctx, AuditPageSize, (page-1)*AuditPageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("recent: %w", err)
+	}
+
+	return list, pages, nil
+}
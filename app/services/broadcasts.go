@@ -0,0 +1,439 @@
+package services
+
+import (
+	"closealerts/app/clients"
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// broadcastBatch chats are sent to concurrently; the cursor moves after the whole batch. On shutdown the batch
+	// in flight is finished, so only a crash may deliver the broadcast twice, to at most that many chats.
+	broadcastBatch          = 10
+	broadcastReportInterval = 3 * time.Second
+)
+
+// BroadcastStore keeps the broadcasts and their progress; repositories.Broadcasts implements it.
+type BroadcastStore interface {
+	Create(ctx context.Context, broadcast types2.Broadcast) (types2.Broadcast, error)
+	Get(ctx context.Context, id int64) (types2.Broadcast, bool, error)
+	Save(ctx context.Context, broadcast types2.Broadcast) error
+	Transition(ctx context.Context, id int64, from, to string, messageID int) (bool, error)
+	Running(ctx context.Context) (types2.Broadcasts, error)
+}
+
+// ChatDirectory lists the chats and tells their languages; Chats implements it.
+type ChatDirectory interface {
+	All(ctx context.Context) (types2.Chats, error)
+	Language(ctx context.Context, id int64) (string, error)
+}
+
+// TrackingChats tells the chats tracking the areas, or any of them for none; Notification implements it.
+type TrackingChats interface {
+	ChatIDs(ctx context.Context, areas []string) ([]int64, error)
+}
+
+type Broadcasts struct {
+	log          *zap.SugaredLogger
+	telegram     Sender
+	broadcast    BroadcastStore
+	chat         ChatDirectory
+	notification TrackingChats
+	audit        AuditRecorder
+	runs         *broadcastRuns
+}
+
+// broadcastRuns tracks the broadcasts being sent, which run under the context of the broadcasts job.
+type broadcastRuns struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+func NewBroadcasts(
+	log *zap.SugaredLogger,
+	telegram Sender,
+	broadcast BroadcastStore,
+	chat ChatDirectory,
+	notification TrackingChats,
+	audit AuditRecorder,
+) Broadcasts {
+	return Broadcasts{
+		log:          log,
+		telegram:     telegram,
+		broadcast:    broadcast,
+		chat:         chat,
+		notification: notification,
+		audit:        audit,
+		runs:         &broadcastRuns{ctx: context.Background()},
+	}
+}
+
+// ParseBroadcastTarget turns admin input into a target: "all", "active" or a comma-separated list of areas.
+func ParseBroadcastTarget(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+
+	switch strings.ToLower(input) {
+	case types2.BroadcastTargetAll, "всі", "усі":
+		return types2.BroadcastTargetAll, true
+	case types2.BroadcastTargetActive, "активні":
+		return types2.BroadcastTargetActive, true
+	}
+
+	areas := splitAreas(input)
+	if len(areas) == 0 {
+		return "", false
+	}
+
+	return types2.BroadcastTargetAreas + areas.Join(","), true
+}
+
+func DescribeBroadcastTarget(p i18n.Printer, target string) string {
+	switch {
+	case target == types2.BroadcastTargetAll:
+		return p.T("broadcast.target_all")
+	case target == types2.BroadcastTargetActive:
+		return p.T("broadcast.target_active")
+	case strings.HasPrefix(target, types2.BroadcastTargetAreas):
+		areas := strings.Split(strings.TrimPrefix(target, types2.BroadcastTargetAreas), ",")
+
+		return p.T("broadcast.target_areas", p.Areas(areas).Join(", "))
+	default:
+		return target
+	}
+}
+
+// Draft saves the broadcast waiting for confirmation, counting its recipients.
+func (r Broadcasts) Draft(ctx context.Context, chatID int64, text, target string) (types2.Broadcast, error) {
+	recipients, err := r.recipients(ctx, target, math.MinInt64)
+	if err != nil {
+		return types2.Broadcast{}, fmt.Errorf("recipients: %w", err)
+	}
+
+	broadcast, err := r.broadcast.Create(ctx, types2.Broadcast{
+		CreatedBy: types.ActorFrom(ctx),
+		ChatID:    chatID,
+		Text:      text,
+		Target:    target,
+		Status:    types2.BroadcastDraft,
+		Total:     len(recipients),
+		Cursor:    math.MinInt64,
+	})
+	if err != nil {
+		return types2.Broadcast{}, fmt.Errorf("create: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (r Broadcasts) Get(ctx context.Context, id int64) (types2.Broadcast, bool, error) {
+	broadcast, ok, err := r.broadcast.Get(ctx, id)
+	if err != nil {
+		return types2.Broadcast{}, false, fmt.Errorf("get: %w", err)
+	}
+
+	return broadcast, ok, nil
+}
+
+// Confirm starts sending the draft in the background, reporting progress by editing the given message.
+// It returns false when the broadcast is not a draft anymore.
+func (r Broadcasts) Confirm(ctx context.Context, id int64, messageID int) (bool, error) {
+	ok, err := r.broadcast.Transition(ctx, id, types2.BroadcastDraft, types2.BroadcastRunning, messageID)
+	if err != nil {
+		return false, fmt.Errorf("transition: %w", err)
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	broadcast, _, err := r.broadcast.Get(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("get: %w", err)
+	}
+
+	r.audit.Record(ctx, broadcast.ChatID, "broadcast", broadcast.Text, "confirmed for "+broadcast.Target)
+
+	// the update context ends with the webhook request, the broadcast must not
+	r.start(types.ActorFrom(ctx), broadcast)
+
+	return true, nil
+}
+
+// Launch sends the broadcast without confirmation, reporting progress in a new message to the chat.
+func (r Broadcasts) Launch(ctx context.Context, chatID int64, text, target string) error {
+	broadcast, err := r.Draft(ctx, chatID, text, target)
+	if err != nil {
+		return fmt.Errorf("draft: %w", err)
+	}
+
+	p := r.printer(ctx, chatID)
+
+	status, err := r.telegram.Send(ctx, tgbotapi.NewMessage(chatID, BroadcastStatusText(p, broadcast, "broadcast.sending")))
+	if err != nil {
+		r.log.Errorw("send broadcast status", "id", broadcast.ID, "err", err)
+	}
+
+	if _, err := r.Confirm(ctx, broadcast.ID, status.MessageID); err != nil {
+		return fmt.Errorf("confirm: %w", err)
+	}
+
+	return nil
+}
+
+func (r Broadcasts) Cancel(ctx context.Context, id int64, messageID int) (bool, error) {
+	ok, err := r.broadcast.Transition(ctx, id, types2.BroadcastDraft, types2.BroadcastCancelled, messageID)
+	if err != nil {
+		return false, fmt.Errorf("transition: %w", err)
+	}
+
+	return ok, nil
+}
+
+// Resume continues broadcasts interrupted by a restart. The context is the one broadcasts run under from now on:
+// when it ends they stop between batches, to continue from their cursor on the next start.
+func (r Broadcasts) Resume(ctx context.Context) error {
+	r.runs.mu.Lock()
+	r.runs.ctx = ctx
+	r.runs.mu.Unlock()
+
+	list, err := r.broadcast.Running(ctx)
+	if err != nil {
+		return fmt.Errorf("running: %w", err)
+	}
+
+	for _, broadcast := range list {
+		r.log.Infow("resume broadcast", "id", broadcast.ID, "cursor", broadcast.Cursor)
+
+		r.start(broadcast.CreatedBy, broadcast)
+	}
+
+	return nil
+}
+
+// Wait blocks until the broadcasts being sent stop; the ones confirmed after it is called are left to Resume.
+func (r Broadcasts) Wait() {
+	r.runs.mu.Lock()
+	r.runs.stopping = true
+	r.runs.mu.Unlock()
+
+	r.runs.wg.Wait()
+}
+
+func (r Broadcasts) start(actor int64, broadcast types2.Broadcast) {
+	r.runs.mu.Lock()
+	defer r.runs.mu.Unlock()
+
+	if r.runs.stopping {
+		r.log.Infow("broadcast left to resume", "id", broadcast.ID)
+
+		return
+	}
+
+	r.runs.wg.Add(1)
+
+	go func(ctx context.Context) {
+		defer r.runs.wg.Done()
+
+		r.Run(types.WithActor(ctx, actor), broadcast)
+	}(r.runs.ctx)
+}
+
+// Run sends the broadcast to the recipients left after its cursor.
+func (r Broadcasts) Run(ctx context.Context, broadcast types2.Broadcast) {
+	recipients, err := r.recipients(ctx, broadcast.Target, broadcast.Cursor)
+	if err != nil {
+		r.log.Errorw("broadcast recipients", "id", broadcast.ID, "err", err)
+
+		return
+	}
+
+	// new chats may have appeared since the draft
+	broadcast.Total = broadcast.Delivered + broadcast.Blocked + broadcast.Failed + len(recipients)
+	p := r.printer(ctx, broadcast.ChatID)
+
+	// the status message is Run's alone: it replaces the preview and its buttons, then follows the progress
+	r.report(ctx, p, broadcast, "broadcast.sending")
+	reported := time.Now()
+
+	for start := 0; start < len(recipients); start += broadcastBatch {
+		if ctx.Err() != nil {
+			return
+		}
+
+		end := start + broadcastBatch
+		if end > len(recipients) {
+			end = len(recipients)
+		}
+
+		// a batch started is finished even on shutdown, for the cursor to tell who got the broadcast
+		delivered, blocked, failed := r.sendBatch(detached{ctx}, broadcast.Text, recipients[start:end])
+
+		broadcast.Delivered += delivered
+		broadcast.Blocked += blocked
+		broadcast.Failed += failed
+		broadcast.Cursor = recipients[end-1]
+
+		if err := r.broadcast.Save(detached{ctx}, broadcast); err != nil {
+			r.log.Errorw("save broadcast progress", "id", broadcast.ID, "err", err)
+		}
+
+		if time.Since(reported) >= broadcastReportInterval {
+			reported = time.Now()
+			r.report(ctx, p, broadcast, "broadcast.sending")
+		}
+	}
+
+	broadcast.Status = types2.BroadcastDone
+	if err := r.broadcast.Save(detached{ctx}, broadcast); err != nil {
+		r.log.Errorw("save finished broadcast", "id", broadcast.ID, "err", err)
+	}
+
+	r.report(ctx, p, broadcast, "broadcast.finished")
+
+	r.audit.Record(ctx, broadcast.ChatID, "broadcast", broadcast.Text, fmt.Sprintf(
+		"delivered %d, blocked %d, failed %d", broadcast.Delivered, broadcast.Blocked, broadcast.Failed,
+	))
+}
+
+// detached keeps the values of the context, like the actor and the span, but not its cancellation.
+type detached struct{ context.Context }
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+func (r Broadcasts) sendBatch(ctx context.Context, text string, chatIDs []int64) (delivered, blocked, failed int) {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, chatID := range chatIDs {
+		wg.Add(1)
+
+		go func(chatID int64) {
+			defer wg.Done()
+
+			_, err := r.telegram.Send(ctx, tgbotapi.NewMessage(chatID, text))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				delivered++
+			case clients.IsBlocked(err):
+				blocked++
+			default:
+				failed++
+
+				r.log.Errorw("broadcast send", "chat_id", chatID, "err", err)
+			}
+		}(chatID)
+	}
+
+	wg.Wait()
+
+	return delivered, blocked, failed
+}
+
+func (r Broadcasts) report(ctx context.Context, p i18n.Printer, broadcast types2.Broadcast, title string) {
+	if broadcast.MessageID == 0 {
+		return
+	}
+
+	text := BroadcastStatusText(p, broadcast, title)
+
+	// Launch sends the status Run starts with
+	_, err := r.telegram.Send(ctx, tgbotapi.NewEditMessageText(broadcast.ChatID, broadcast.MessageID, text))
+	if err != nil && !clients.IsNotModified(err) {
+		r.log.Errorw("report broadcast progress", "id", broadcast.ID, "err", err)
+	}
+}
+
+// printer speaks the language of the chat the broadcast reports its progress to.
+func (r Broadcasts) printer(ctx context.Context, chatID int64) i18n.Printer {
+	lang, err := r.chat.Language(ctx, chatID)
+	if err != nil {
+		r.log.Errorw("chat language", "chat_id", chatID, "err", err)
+	}
+
+	return i18n.New(lang)
+}
+
+// BroadcastStatusText reports the progress under the title, an i18n key.
+func BroadcastStatusText(p i18n.Printer, broadcast types2.Broadcast, title string) string {
+	return p.T(
+		"broadcast.status",
+		p.T(title),
+		broadcast.Delivered+broadcast.Blocked+broadcast.Failed,
+		broadcast.Total,
+		broadcast.Delivered,
+		broadcast.Blocked,
+		broadcast.Failed,
+	)
+}
+
+// recipients returns chat IDs of the target greater than the cursor, in ascending order.
+func (r Broadcasts) recipients(ctx context.Context, target string, cursor int64) ([]int64, error) {
+	var ids []int64
+
+	switch {
+	case target == types2.BroadcastTargetAll:
+		list, err := r.chat.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("all: %w", err)
+		}
+
+		for _, chat := range list {
+			ids = append(ids, chat.ID)
+		}
+
+	case target == types2.BroadcastTargetActive:
+		list, err := r.notification.ChatIDs(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("chat ids: %w", err)
+		}
+
+		ids = list
+
+	case strings.HasPrefix(target, types2.BroadcastTargetAreas):
+		areas := strings.Split(strings.TrimPrefix(target, types2.BroadcastTargetAreas), ",")
+
+		list, err := r.notification.ChatIDs(ctx, areas)
+		if err != nil {
+			return nil, fmt.Errorf("chat ids: %w", err)
+		}
+
+		ids = list
+
+	default:
+		return nil, fmt.Errorf("unknown target %s", strconv.Quote(target))
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	out := ids[:0]
+
+	for _, id := range ids {
+		if id > cursor {
+			out = append(out, id)
+		}
+	}
+
+	return out, nil
+}
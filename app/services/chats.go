@@ -1,7 +1,7 @@
 package services
 
 import (
-	"closealerts/app/repositories"
+	"closealerts/app/i18n"
 	types2 "closealerts/app/repositories/types"
 	"context"
 	"fmt"
@@ -9,16 +9,29 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// ChatStore keeps the chats and their settings; repositories.Chats implements it.
+type ChatStore interface {
+	CreateOrSelect(ctx context.Context, chat types2.Chat) (types2.Chat, error)
+	SetTester(ctx context.Context, id int64, tester bool) error
+	SetLanguage(ctx context.Context, id int64, lang string) error
+	Languages(ctx context.Context, ids []int64) (map[int64]string, error)
+	All(ctx context.Context) (types2.Chats, error)
+}
+
 type Chats struct {
-	chat repositories.Chats
+	chat ChatStore
 }
 
-func NewChats(chats repositories.Chats) Chats {
+func NewChats(chats ChatStore) Chats {
 	return Chats{chat: chats}
 }
 
-func (r Chats) FirstOrCreate(ctx context.Context, tgChat *tgbotapi.Chat) (types2.Chat, error) {
-	c := types2.Chat{ID: tgChat.ID, Username: tgChat.UserName}
+// FirstOrCreate registers the chat; a new chat gets the language, if the bot speaks it, of the user who brought it.
+func (r Chats) FirstOrCreate(ctx context.Context, tgChat *tgbotapi.Chat, languageCode string) (types2.Chat, error) {
+	c := types2.Chat{ID: tgChat.ID, Username: tgChat.UserName, Title: tgChat.Title, Type: tgChat.Type}
+	if lang, ok := i18n.Match(languageCode); ok {
+		c.Language = lang
+	}
 
 	chat, err := r.chat.CreateOrSelect(ctx, c)
 	if err != nil {
@@ -28,35 +41,37 @@ func (r Chats) FirstOrCreate(ctx context.Context, tgChat *tgbotapi.Chat) (types2
 	return chat, nil
 }
 
-func (r Chats) ClearCommand(ctx context.Context, id int64) error {
-	if err := r.chat.ClearCommand(ctx, id); err != nil {
-		return fmt.Errorf("clear command: %w", err)
+func (r Chats) All(ctx context.Context) (types2.Chats, error) {
+	list, err := r.chat.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("all: %w", err)
 	}
 
-	return nil
+	return list, nil
 }
 
-func (r Chats) SetCommand(ctx context.Context, chatID int64, command string) error {
-	if err := r.chat.SetCommand(ctx, chatID, command); err != nil {
-		return fmt.Errorf("set command: %w", err)
+func (r Chats) SetTester(ctx context.Context, id int64, tester bool) error {
+	if err := r.chat.SetTester(ctx, id, tester); err != nil {
+		return fmt.Errorf("set tester: %w", err)
 	}
 
 	return nil
 }
 
-func (r Chats) Grant(ctx context.Context, chatID int64, priv string) error {
-	if err := r.chat.Grant(ctx, chatID, priv); err != nil {
-		return fmt.Errorf("grant: %w", err)
+func (r Chats) SetLanguage(ctx context.Context, id int64, lang string) error {
+	if err := r.chat.SetLanguage(ctx, id, lang); err != nil {
+		return fmt.Errorf("set language: %w", err)
 	}
 
 	return nil
 }
 
-func (r Chats) All(ctx context.Context) (types2.Chats, error) {
-	list, err := r.chat.All(ctx)
+// Language returns the language of the chat, empty if it has none.
+func (r Chats) Language(ctx context.Context, id int64) (string, error) {
+	langs, err := r.chat.Languages(ctx, []int64{id})
 	if err != nil {
-		return nil, fmt.Errorf("all: %w", err)
+		return "", fmt.Errorf("languages: %w", err)
 	}
 
-	return list, nil
+	return langs[id], nil
 }
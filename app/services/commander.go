@@ -1,338 +1,1581 @@
 package services
 
 import (
-	"closealerts/app/clients"
+	"closealerts/app/i18n"
 	types2 "closealerts/app/repositories/types"
 	"closealerts/app/types"
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/singleflight"
 )
 
+// Subscriber changes the areas chats track; Notification implements it.
+type Subscriber interface {
+	Track(ctx context.Context, chatID int64, area string) error
+	TrackNear(ctx context.Context, chatID int64, near string, areas []string) ([]string, error)
+	StopNear(ctx context.Context, chatID int64, near string) error
+	Tracking(ctx context.Context, id int64) (types2.Notifications, error)
+	Stop(ctx context.Context, id int64, area string) error
+}
+
+// ChatSettings changes the settings of chats; Chats implements it.
+type ChatSettings interface {
+	SetTester(ctx context.Context, id int64, tester bool) error
+	SetLanguage(ctx context.Context, id int64, lang string) error
+}
+
+// AlertReader tells the alerts going on and how fresh they are; Alerts implements it.
+type AlertReader interface {
+	GetActive(ctx context.Context) (types2.Alerts, error)
+	Freshness(ctx context.Context) (time.Time, bool, error)
+}
+
+// Simulator starts and ends simulated alerts; Fakes implements it.
+type Simulator interface {
+	FakeAlert(ctx context.Context, areas []string, duration time.Duration) error
+	FakeAllClear(ctx context.Context, areas []string) types.Stringies
+	LoadScenario(name string) (FakeScenario, error)
+	Play(scenario FakeScenario) error
+}
+
+// MapRenderer renders the alert maps and keeps the Telegram files of the ones sent; Maps implements it.
+type MapRenderer interface {
+	Exists(ctx context.Context, alerts types2.Alerts) (types2.Map, bool, error)
+	Get(ctx context.Context, alerts types2.Alerts) (bool, types2.Map, []byte, error)
+	Save(ctx context.Context, alerts types2.Alerts, fileID string) (types2.Map, error)
+}
+
+// AreaGraph tells the neighbors of areas; Neighbors implements it.
+type AreaGraph interface {
+	Known(area string) bool
+	Within(area string, depth int) types.Stringies
+}
+
+// ConversationRunner takes chats through the steps of conversation flows; Conversations implements it.
+type ConversationRunner interface {
+	Start(ctx context.Context, chatID int64, flow ConversationFlow, data ConversationData) (tgbotapi.Chattable, error)
+	Continue(
+		ctx context.Context, chatID int64, flows map[string]ConversationFlow, input string,
+	) (tgbotapi.Chattable, error)
+	Cancel(ctx context.Context, chatID int64) (bool, error)
+}
+
+// PermissionManager grants and revokes permissions; Permissions implements it.
+type PermissionManager interface {
+	Bootstrap(userID int64) bool
+	BootstrapIDs() []int64
+	All(ctx context.Context) (types2.Permissions, error)
+	Grant(ctx context.Context, userID int64, permission string, grantedBy int64) error
+	Revoke(ctx context.Context, userID int64, permission string) (bool, error)
+}
+
+// AuditRecorder writes what was done to the audit log; Audit implements it.
+type AuditRecorder interface {
+	Record(ctx context.Context, chatID int64, action, args, result string)
+}
+
+// AuditLog writes the audit log and shows it; Audit implements it.
+type AuditLog interface {
+	AuditRecorder
+	Page(ctx context.Context, page int) (types2.AuditEvents, int, error)
+}
+
+// BroadcastDrafts drafts broadcasts and has them confirmed or cancelled; Broadcasts implements it.
+type BroadcastDrafts interface {
+	Draft(ctx context.Context, chatID int64, text, target string) (types2.Broadcast, error)
+	Get(ctx context.Context, id int64) (types2.Broadcast, bool, error)
+	Confirm(ctx context.Context, id int64, messageID int) (bool, error)
+	Cancel(ctx context.Context, id int64, messageID int) (bool, error)
+}
+
+// BroadcastScheduler schedules broadcasts and cancels them; ScheduledBroadcasts implements it.
+type BroadcastScheduler interface {
+	ParseWhen(input string) (string, time.Time, error)
+	Schedule(ctx context.Context, chatID int64, when, target, text string) (types2.ScheduledBroadcast, error)
+	Active(ctx context.Context) (types2.ScheduledBroadcasts, error)
+	Cancel(ctx context.Context, chatID, id int64) (bool, error)
+}
+
+// TemplateEditor changes the notification templates of chats; Templates implements it.
+type TemplateEditor interface {
+	Get(ctx context.Context, chatID int64) (types2.ChatTemplates, error)
+	Save(ctx context.Context, p i18n.Printer, chatTemplate types2.ChatTemplate) error
+	Reset(ctx context.Context, chatID int64, kind string) (bool, error)
+}
+
+// StatusSwitch turns the status messages of chats on and off; StatusMessages implements it.
+type StatusSwitch interface {
+	Enable(ctx context.Context, chatID int64) error
+	Disable(ctx context.Context, chatID int64) (bool, error)
+}
+
+// DigestSettings changes the digests chats get; Digests implements it.
+type DigestSettings interface {
+	Get(ctx context.Context, chatID int64) (types2.Digest, bool, error)
+	Set(ctx context.Context, digest types2.Digest) (types2.Digest, error)
+	Stop(ctx context.Context, chatID int64) (bool, error)
+}
+
 type Commander struct {
-	notification Notification
-	chat         Chats
-	alert        Alerts
-	fake         Fakes
-	telegram     clients.Telegram
-	mapz         Maps
-	sf           *singleflight.Group
-	log          *zap.SugaredLogger
+	notification  Subscriber
+	chat          ChatSettings
+	alert         AlertReader
+	fake          Simulator
+	telegram      Sender
+	mapz          MapRenderer
+	geo           Locator
+	neighbors     AreaGraph
+	conversations ConversationRunner
+	permissions   PermissionManager
+	audit         AuditLog
+	broadcasts    BroadcastDrafts
+	scheduled     BroadcastScheduler
+	templates     TemplateEditor
+	statuses      StatusSwitch
+	digests       DigestSettings
+	flows         map[string]ConversationFlow
+	sf            *singleflight.Group
+	log           *zap.SugaredLogger
+	logLevel      zap.AtomicLevel
+}
+
+func NewCommander(
+	log *zap.SugaredLogger,
+	tg Sender,
+	chat ChatSettings,
+	notification Subscriber,
+	alert AlertReader,
+	fake Simulator,
+	mapz MapRenderer,
+	geo Locator,
+	neighbors AreaGraph,
+	conversations ConversationRunner,
+	permissions PermissionManager,
+	audit AuditLog,
+	broadcasts BroadcastDrafts,
+	scheduled BroadcastScheduler,
+	templates TemplateEditor,
+	statuses StatusSwitch,
+	digests DigestSettings,
+	logLevel zap.AtomicLevel,
+) Commander {
+	r := Commander{
+		log:           log,
+		telegram:      tg,
+		chat:          chat,
+		notification:  notification,
+		alert:         alert,
+		fake:          fake,
+		mapz:          mapz,
+		geo:           geo,
+		neighbors:     neighbors,
+		conversations: conversations,
+		permissions:   permissions,
+		audit:         audit,
+		broadcasts:    broadcasts,
+		scheduled:     scheduled,
+		templates:     templates,
+		statuses:      statuses,
+		digests:       digests,
+		logLevel:      logLevel,
+		sf:            &singleflight.Group{},
+	}
+
+	r.flows = r.conversationFlows()
+
+	return r
+}
+
+const (
+	flowTrack     = "track"
+	flowStop      = "stop"
+	flowBroadcast = "admin_broadcast"
+	flowSchedule  = "admin_schedule"
+)
+
+// conversationFlows are named after the commands starting them.
+func (r Commander) conversationFlows() map[string]ConversationFlow {
+	flows := []ConversationFlow{
+		{
+			Name: flowTrack,
+			Steps: []ConversationStep{
+				{Name: "area", Prompt: "track.prompt", Validate: notEmpty},
+			},
+			Finish: func(ctx context.Context, chatID int64, data ConversationData) (tgbotapi.Chattable, error) {
+				return r.track(ctx, chatID, data["area"])
+			},
+		},
+		{
+			Name: flowStop,
+			Steps: []ConversationStep{
+				{Name: "area", Prompt: "stop.prompt", Validate: r.validateTracked},
+			},
+			Finish: func(ctx context.Context, chatID int64, data ConversationData) (tgbotapi.Chattable, error) {
+				return r.stop(ctx, chatID, data["area"])
+			},
+		},
+		{
+			Name: flowBroadcast,
+			Steps: []ConversationStep{
+				{
+					Name:     "target",
+					Prompt:   "broadcast.target_prompt",
+					Validate: validateBroadcastTarget,
+				},
+				{Name: "text", Prompt: "broadcast.text_prompt", Validate: notEmpty},
+			},
+			Finish: func(ctx context.Context, chatID int64, data ConversationData) (tgbotapi.Chattable, error) {
+				return r.broadcastPreview(ctx, chatID, data["text"], data["target"])
+			},
+		},
+		{
+			Name: flowSchedule,
+			Steps: []ConversationStep{
+				{
+					Name:     "when",
+					Prompt:   "schedule.when_prompt",
+					Validate: r.validateScheduleWhen,
+				},
+				{
+					Name:     "target",
+					Prompt:   "broadcast.target_prompt",
+					Validate: validateBroadcastTarget,
+				},
+				{Name: "text", Prompt: "broadcast.text_prompt", Validate: notEmpty},
+			},
+			Finish: func(ctx context.Context, chatID int64, data ConversationData) (tgbotapi.Chattable, error) {
+				return r.schedule(ctx, chatID, data["when"], data["target"], data["text"])
+			},
+		},
+	}
+
+	out := make(map[string]ConversationFlow, len(flows))
+	for _, flow := range flows {
+		out[flow.Name] = flow
+	}
+
+	return out
+}
+
+func notEmpty(ctx context.Context, _ int64, input string, _ ConversationData) (string, string, error) {
+	if input = strings.TrimSpace(input); len(input) == 0 {
+		return "", i18n.From(ctx).T("conversation.empty"), nil
+	}
+
+	return input, "", nil
+}
+
+func (r Commander) validateTracked(
+	ctx context.Context, chatID int64, input string, _ ConversationData,
+) (string, string, error) {
+	tracking, err := r.notification.Tracking(ctx, chatID)
+	if err != nil {
+		return "", "", fmt.Errorf("tracking: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	if len(tracking) == 0 {
+		return "", p.T("tracking.none"), nil
+	}
+
+	area := i18n.AreaID(input)
+	if !tracking.Tracking(area) {
+		return "", p.T("stop.not_tracking", strings.TrimSpace(input), p.Areas(tracking.Areas()).Sort().Join(", ")), nil
+	}
+
+	return area, "", nil
+}
+
+func (r Commander) Track(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+	if len(args) > 0 {
+		return r.track(ctx, msg.Chat.ID, args)
+	}
+
+	chattable, err := r.conversations.Start(ctx, msg.Chat.ID, r.flows[flowTrack], nil)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	return chattable, nil
+}
+
+func (r Commander) track(ctx context.Context, chatID int64, name string) (tgbotapi.Chattable, error) {
+	p, area := i18n.From(ctx), i18n.AreaID(name)
+
+	if err := r.notification.Track(ctx, chatID, area); err != nil {
+		if errors.Is(err, types.ErrLinkExists) {
+			return tgbotapi.NewMessage(chatID, p.T("track.exists", p.Area(area))), nil
+		}
+
+		return nil, fmt.Errorf("track: %w", err)
+	}
+
+	r.audit.Record(ctx, chatID, "track", area, "tracked")
+
+	return tgbotapi.NewMessage(chatID, p.T("track.done", p.Area(area))), nil
+}
+
+func (r Commander) Tracking(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
+	list, err := r.notification.Tracking(ctx, msg.Chat.ID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	if len(list) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("tracking.none")), nil
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.N("tracking.count", len(list), len(list), p.Areas(list.Areas()).Join(", "))), nil
+}
+
+func (r Commander) Stop(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+	if len(args) > 0 {
+		return r.stop(ctx, msg.Chat.ID, args)
+	}
+
+	chattable, err := r.conversations.Start(ctx, msg.Chat.ID, r.flows[flowStop], nil)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	return chattable, nil
+}
+
+func (r Commander) stop(ctx context.Context, chatID int64, name string) (tgbotapi.Chattable, error) {
+	p, area := i18n.From(ctx), i18n.AreaID(name)
+
+	if err := r.notification.Stop(ctx, chatID, area); err != nil {
+		return nil, fmt.Errorf("stop: %w", err)
+	}
+
+	r.audit.Record(ctx, chatID, "stop", area, "stopped")
+
+	return tgbotapi.NewMessage(chatID, p.T("stop.done", p.Area(area))), nil
+}
+
+// Continue takes a plain message as the answer to the conversation the chat is in.
+func (r Commander) Continue(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
+	chattable, err := r.conversations.Continue(ctx, msg.Chat.ID, r.flows, msg.Text)
+	if err != nil {
+		return nil, fmt.Errorf("continue: %w", err)
+	}
+
+	return chattable, nil
+}
+
+func (r Commander) Cancel(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
+	ok, err := r.conversations.Cancel(ctx, msg.Chat.ID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("cancel: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	if !ok {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("cancel.nothing")), nil
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("cancel.done")), nil
+}
+
+func (r Commander) Alerts(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
+	alerts, err := r.alert.GetActive(ctx)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("get active: %w", err)
+	}
+
+	asOf, err := r.asOf(ctx)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("as of: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	if len(alerts) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("alerts.none")+"\n\n"+asOf), nil
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.Areas(alerts.Areas()).Join(", ")+"\n\n"+asOf), nil
+}
+
+// asOf tells users how old the alert data is, warning them when the sources have not answered for a while.
+func (r Commander) asOf(ctx context.Context) (string, error) {
+	refreshedAt, stale, err := r.alert.Freshness(ctx)
+	if err != nil {
+		return "", fmt.Errorf("freshness: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	if refreshedAt.IsZero() {
+		return p.T("as_of.no_data"), nil
+	}
+
+	local := refreshedAt.In(ScheduleLocation)
+
+	layout := "15:04"
+	if local.Format("2006-01-02") != time.Now().In(ScheduleLocation).Format("2006-01-02") {
+		layout = "02.01 15:04"
+	}
+
+	if stale {
+		return p.T("as_of.stale", local.Format(layout)), nil
+	}
+
+	return p.T("as_of", local.Format(layout)), nil
+}
+
+func (r Commander) Start(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
+	return tgbotapi.NewMessage(msg.Chat.ID, i18n.From(ctx).T("start")), nil
+}
+
+func (r Commander) Areas(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
+	tracking, err := r.notification.Tracking(ctx, msg.Chat.ID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	var areasTracking types.Stringies
+
+	for _, notification := range tracking {
+		if oblasts.Contains(notification.Area) {
+			areasTracking = append(areasTracking, notification.Area)
+		}
+	}
+
+	p := i18n.From(ctx)
+
+	text := p.T("areas.pick")
+	if len(areasTracking) > 0 {
+		text += "\n\n" + p.T("areas.tracking", p.Areas(areasTracking).Sort().Join(", "))
+	}
+
+	outMsg := tgbotapi.NewMessage(msg.Chat.ID, text)
+	outMsg.ReplyMarkup = areasKeyboard(p, areasTracking)
+
+	return outMsg, nil
+}
+
+// oblasts offered by /areas, in the order of the keyboard.
+var oblasts = types.Stringies{
+	"Волинська", "Вінницька", "Дніпропетровська",
+	"Донецька", "Житомирська", "Закарпатська",
+	"Запорізька", "Івано-Франківська", "Київська",
+	"Кіровоградська", "Луганська", "Львівська",
+	"Миколаївська", "Одеська", "Полтавська",
+	"Рівненська", "Сумська", "Тернопільська",
+	"Харківська", "Херсонська", "Хмельницька",
+	"Черкаська", "Чернівецька", "Чернігівська",
+}
+
+func areasKeyboard(p i18n.Printer, tracking types.Stringies) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(oblasts)/3)
+
+	for i := 0; i < len(oblasts); i += 3 {
+		row := make([]tgbotapi.InlineKeyboardButton, 0, 3)
+
+		for _, area := range oblasts[i : i+3] {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(areaButton(p, tracking, area), "toggle_area:"+area))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// areaButton names the area, checked if it is tracked.
+func areaButton(p i18n.Printer, tracking types.Stringies, area string) string {
+	if tracking.Contains(area) {
+		return "✅" + p.Area(area)
+	}
+
+	return p.Area(area)
+}
+
+func (r Commander) ToggleArea(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.EditMessageTextConfig, error) {
+	trackingAreas, err := r.toggle(ctx, cq.Message.Chat.ID, payload)
+	if err != nil {
+		return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("toggle: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	text := p.T("areas.tracking", p.Areas(trackingAreas).Sort().Join(", "))
+	if len(trackingAreas) == 0 {
+		text = p.T("areas.none")
+	}
+
+	return tgbotapi.
+			NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, text, areasKeyboard(p, trackingAreas)),
+		nil
+}
+
+func (r Commander) toggle(ctx context.Context, chatID int64, payload string) (types.Stringies, error) {
+	tracking, err := r.notification.Tracking(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("tracking: %w", err)
+	}
+
+	trackingAreas := tracking.Areas()
+
+	if tracking.Tracking(payload) {
+		if err = r.notification.Stop(ctx, chatID, payload); err != nil {
+			return nil, fmt.Errorf("stop: %w", err)
+		}
+
+		r.audit.Record(ctx, chatID, "toggle_area", payload, "stopped")
+
+		return trackingAreas.Delete(payload), nil
+	}
+
+	if err = r.notification.Track(ctx, chatID, payload); err != nil {
+		return nil, fmt.Errorf("track: %w", err)
+	}
+
+	r.audit.Record(ctx, chatID, "toggle_area", payload, "tracked")
+
+	return append(trackingAreas, payload), nil
+}
+
+const nearbyRadiusKm = 50
+
+func (r Commander) Location(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
+	location := msg.Location
+	if msg.Venue != nil {
+		location = &msg.Venue.Location
+	}
+
+	p := i18n.From(ctx)
+
+	if location == nil {
+		outMsg := tgbotapi.NewMessage(msg.Chat.ID, p.T("location.ask"))
+		outMsg.ReplyMarkup = tgbotapi.NewOneTimeReplyKeyboard(
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButtonLocation(p.T("location.button"))),
+		)
+
+		return outMsg, nil
+	}
+
+	located := r.geo.Locate(location.Latitude, location.Longitude)
+	around := r.geo.Around(location.Latitude, location.Longitude, GeoLevelOblast, nearbyRadiusKm)
+
+	// the bundled borders are simplified, so a point right at the border may fall in between areas
+	if len(located) == 0 && len(around) > 0 {
+		located, around = around[:1], around[1:]
+	}
+
+	if len(located) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("location.not_found")), nil
+	}
+
+	tracking, err := r.notification.Tracking(ctx, msg.Chat.ID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	text := p.T("location.found", p.Areas(located.Names()).Join(" / "))
+	if len(around) > 0 {
+		text += "\n" + p.T("location.around", p.Areas(around.Names()).Join(", "))
+	}
+
+	text += "\n\n" + p.T("location.pick")
+
+	outMsg := tgbotapi.NewMessage(msg.Chat.ID, text)
+	outMsg.ReplyMarkup = locationKeyboard(p, append(located.Names(), around.Names()...), tracking.Areas())
+
+	return outMsg, nil
+}
+
+func locationKeyboard(p i18n.Printer, areas, tracking types.Stringies) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(areas)/2+2)
+
+	for i := 0; i < len(areas); i += 2 {
+		end := i + 2
+		if end > len(areas) {
+			end = len(areas)
+		}
+
+		row := make([]tgbotapi.InlineKeyboardButton, 0, 2)
+
+		for _, area := range areas[i:end] {
+			// raion and hromada names may not fit into 64 bytes of callback data
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(areaButton(p, tracking, area), "toggle_loc:"+GeoKey(area)))
+		}
+
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("location.track_all"), "track_loc:"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// locationAreas restores the list of offered areas from the keyboard the callback came from.
+func (r Commander) locationAreas(markup *tgbotapi.InlineKeyboardMarkup) types.Stringies {
+	if markup == nil {
+		return nil
+	}
+
+	var areas types.Stringies
+
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == nil || !strings.HasPrefix(*button.CallbackData, "toggle_loc:") {
+				continue
+			}
+
+			// areas gone from the borders since the keyboard was sent are not offered again
+			if area, ok := r.geo.ByKey(strings.TrimPrefix(*button.CallbackData, "toggle_loc:")); ok {
+				areas = append(areas, area)
+			}
+		}
+	}
+
+	return areas
+}
+
+func (r Commander) ToggleLocationArea(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.EditMessageReplyMarkupConfig, error) {
+	area, ok := r.geo.ByKey(payload)
+	if !ok {
+		return tgbotapi.EditMessageReplyMarkupConfig{}, fmt.Errorf("%s: %w", payload, types.ErrUnknownArea)
+	}
+
+	trackingAreas, err := r.toggle(ctx, cq.Message.Chat.ID, area)
+	if err != nil {
+		return tgbotapi.EditMessageReplyMarkupConfig{}, fmt.Errorf("toggle: %w", err)
+	}
+
+	return tgbotapi.NewEditMessageReplyMarkup(
+		cq.Message.Chat.ID,
+		cq.Message.MessageID,
+		locationKeyboard(i18n.From(ctx), r.locationAreas(cq.Message.ReplyMarkup), trackingAreas),
+	), nil
+}
+
+func (r Commander) TrackLocationAreas(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, _ string,
+) (tgbotapi.EditMessageReplyMarkupConfig, error) {
+	tracking, err := r.notification.Tracking(ctx, cq.Message.Chat.ID)
+	if err != nil {
+		return tgbotapi.EditMessageReplyMarkupConfig{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	areas := r.locationAreas(cq.Message.ReplyMarkup)
+	trackingAreas := tracking.Areas()
+
+	var added types.Stringies
+
+	for _, area := range areas {
+		if trackingAreas.Contains(area) {
+			continue
+		}
+
+		if err := r.notification.Track(ctx, cq.Message.Chat.ID, area); err != nil {
+			return tgbotapi.EditMessageReplyMarkupConfig{}, fmt.Errorf("track: %w", err)
+		}
+
+		trackingAreas = append(trackingAreas, area)
+		added = append(added, area)
+	}
+
+	r.audit.Record(ctx, cq.Message.Chat.ID, "track_loc", areas.Join(","), "tracked "+added.Join(","))
+
+	return tgbotapi.NewEditMessageReplyMarkup(
+		cq.Message.Chat.ID,
+		cq.Message.MessageID,
+		locationKeyboard(i18n.From(ctx), areas, trackingAreas),
+	), nil
+}
+
+const (
+	defaultNearDepth = 1
+	maxNearDepth     = 3
+)
+
+func (r Commander) Near(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+	args = strings.TrimSpace(args)
+	if len(args) == 0 {
+		return r.nearGroups(ctx, msg.Chat.ID)
+	}
+
+	name, depth := args, defaultNearDepth
+
+	if idx := strings.LastIndex(args, " "); idx > 0 {
+		if parsed, err := strconv.Atoi(args[idx+1:]); err == nil {
+			name, depth = strings.TrimSpace(args[:idx]), parsed
+		}
+	}
+
+	p, area := i18n.From(ctx), i18n.AreaID(name)
+
+	if depth < 1 || depth > maxNearDepth {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("near.depth", maxNearDepth)), nil
+	}
+
+	if !r.neighbors.Known(area) {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("near.unknown", name)), nil
+	}
+
+	areas := append(types.Stringies{area}, r.neighbors.Within(area, depth)...)
+
+	added, err := r.notification.TrackNear(ctx, msg.Chat.ID, area, areas)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("track near: %w", err)
+	}
+
+	r.audit.Record(ctx, msg.Chat.ID, "near", args, "tracked "+types.Stringies(added).Join(","))
+
+	if len(added) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("near.exists", p.Areas(areas).Join(", "))), nil
+	}
+
+	skipped := areas
+	for _, a := range added {
+		skipped = skipped.Delete(a)
+	}
+
+	text := p.T("near.done", p.Area(area), p.Areas(areas[1:]).Join(", "))
+	if len(skipped) > 0 {
+		text += "\n\n" + p.T("near.skipped", p.Areas(skipped).Join(", "))
+	}
+
+	outMsg := tgbotapi.NewMessage(msg.Chat.ID, text)
+	outMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(p.T("near.remove", p.Area(area)), "near_stop:"+area)),
+	)
+
+	return outMsg, nil
+}
+
+func (r Commander) nearGroups(ctx context.Context, chatID int64) (tgbotapi.Chattable, error) {
+	tracking, err := r.notification.Tracking(ctx, chatID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	groups := tracking.NearGroups().Sort()
+	if len(groups) == 0 {
+		return tgbotapi.NewMessage(chatID, p.T("near.usage")), nil
+	}
+
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(groups))
+	for _, group := range groups {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.T("near.remove", p.Area(group)), "near_stop:"+group),
+		))
+	}
+
+	outMsg := tgbotapi.NewMessage(chatID, p.T("near.groups", p.Areas(groups).Join(", ")))
+	outMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	return outMsg, nil
+}
+
+func (r Commander) StopNear(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.EditMessageTextConfig, error) {
+	if err := r.notification.StopNear(ctx, cq.Message.Chat.ID, payload); err != nil {
+		return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("stop near: %w", err)
+	}
+
+	r.audit.Record(ctx, cq.Message.Chat.ID, "near_stop", payload, "stopped")
+
+	p := i18n.From(ctx)
+
+	return tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, p.T("near.stopped", p.Area(payload))), nil
+}
+
+func (r Commander) Grant(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	userID, permission, ok := parsePermissionArgs(args)
+	if !ok {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("grant.usage", types.Permissions.Join(", "))), nil
+	}
+
+	if err := r.permissions.Grant(ctx, userID, permission, msg.From.ID); err != nil {
+		r.audit.Record(ctx, msg.Chat.ID, "grant", args, "error: "+err.Error())
+
+		return tgbotapi.MessageConfig{}, fmt.Errorf("grant: %w", err)
+	}
+
+	r.audit.Record(ctx, msg.Chat.ID, "grant", args, "granted")
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("grant.done", permission, userID)), nil
+}
+
+func (r Commander) Revoke(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	userID, permission, ok := parsePermissionArgs(args)
+	if !ok {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("revoke.usage", types.Permissions.Join(", "))), nil
+	}
+
+	if r.permissions.Bootstrap(userID) {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("revoke.bootstrap")), nil
+	}
+
+	revoked, err := r.permissions.Revoke(ctx, userID, permission)
+	if err != nil {
+		r.audit.Record(ctx, msg.Chat.ID, "revoke", args, "error: "+err.Error())
+
+		return tgbotapi.MessageConfig{}, fmt.Errorf("revoke: %w", err)
+	}
+
+	if !revoked {
+		r.audit.Record(ctx, msg.Chat.ID, "revoke", args, "not granted")
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("revoke.not_granted", userID, permission)), nil
+	}
+
+	r.audit.Record(ctx, msg.Chat.ID, "revoke", args, "revoked")
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("revoke.done", permission, userID)), nil
+}
+
+func parsePermissionArgs(args string) (int64, string, bool) {
+	fields := strings.Fields(args)
+	if len(fields) != 2 || !types.Permissions.Contains(fields[1]) {
+		return 0, "", false
+	}
+
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return userID, fields[1], true
+}
+
+func (r Commander) Admins(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
+	list, err := r.permissions.All(ctx)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("all: %w", err)
+	}
+
+	p := i18n.From(ctx)
+
+	var lines types.Stringies
+
+	for _, id := range r.permissions.BootstrapIDs() {
+		lines = append(lines, p.T("admins.config", id, types.PermAdmin))
+	}
+
+	for userID, permissions := range list.GroupByUserID() {
+		lines = append(lines, strconv.FormatInt(userID, 10)+": "+types.Stringies(permissions).Join(", "))
+	}
+
+	if len(lines) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("admins.none")), nil
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, lines.Sort().Join("\n")), nil
+}
+
+// auditArgsLength and auditResultLength keep a page of AuditPageSize events within a message.
+const (
+	auditArgsLength   = 64
+	auditResultLength = 32
+)
+
+func (r Commander) AdminAudit(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+	p, page := i18n.From(ctx), 1
+
+	if args = strings.TrimSpace(args); len(args) > 0 {
+		parsed, err := strconv.Atoi(args)
+		if err != nil || parsed < 1 {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("audit.usage")), nil
+		}
+
+		page = parsed
+	}
+
+	list, pages, err := r.audit.Page(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("page: %w", err)
+	}
+
+	if len(list) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("audit.empty", page)), nil
+	}
+
+	lines := make(types.Stringies, 0, len(list)+1)
+	lines = append(lines, p.T("audit.page", page, pages))
+
+	// arguments may be whole broadcast texts, and a page of them would not fit in a message
+	for _, event := range list {
+		lines = append(lines, fmt.Sprintf(
+			"%s actor=%d chat=%d %s %q → %s",
+			event.CreatedAt.Format("2006-01-02 15:04:05"), event.ActorID, event.ChatID, event.Action,
+			shorten(event.Args, auditArgsLength), shorten(event.Result, auditResultLength),
+		))
+	}
+
+	if page < pages {
+		lines = append(lines, p.T("audit.older", page+1))
+	}
+
+	outMsg := tgbotapi.NewMessage(msg.Chat.ID, shorten(lines.Join("\n"), maxMessageLength))
+
+	return outMsg, nil
 }
 
-func NewCommander(
-	log *zap.SugaredLogger,
-	tg clients.Telegram,
-	chat Chats,
-	notification Notification,
-	alert Alerts,
-	fake Fakes,
-	mapz Maps,
-) Commander {
-	return Commander{
-		log:          log,
-		telegram:     tg,
-		chat:         chat,
-		notification: notification,
-		alert:        alert,
-		fake:         fake,
-		mapz:         mapz,
-		sf:           &singleflight.Group{},
+// AdminLogLevel shows the log level, or changes it until the next restart or config reload.
+func (r Commander) AdminLogLevel(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	args = strings.TrimSpace(args)
+	if len(args) == 0 {
+		return tgbotapi.NewMessage(
+			msg.Chat.ID,
+			p.T("log_level.current", r.logLevel.String())+"\n"+p.T("log_level.usage"),
+		), nil
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(args)); err != nil {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("log_level.usage")), nil
 	}
+
+	previous := r.logLevel.Level()
+	r.logLevel.SetLevel(level)
+
+	r.log.Warnw("log level changed", "from", previous, "to", level, "by", types.ActorFrom(ctx))
+	r.audit.Record(ctx, msg.Chat.ID, "log_level", args, "changed from "+previous.String())
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("log_level.current", level.String())), nil
 }
 
-func (r Commander) Track(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
-	if len(args) > 0 {
-		if err := r.notification.Track(ctx, msg.Chat.ID, args); err != nil {
-			if errors.Is(err, types.ErrLinkExists) {
-				return tgbotapi.NewMessage(msg.Chat.ID, "вже пильную за "+args), nil
-			}
+// AdminFakeAlertIn takes comma-separated areas, optionally preceded by the duration like "15m".
+func (r Commander) AdminFakeAlertIn(
+	ctx context.Context, msg *tgbotapi.Message, args string,
+) (tgbotapi.MessageConfig, error) {
+	duration := DefaultFakeAlertDuration
 
-			return tgbotapi.MessageConfig{}, fmt.Errorf("track: %w", err)
+	if fields := strings.Fields(args); len(fields) > 0 {
+		if parsed, err := time.ParseDuration(fields[0]); err == nil {
+			duration = parsed
+			args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), fields[0]))
 		}
+	}
 
-		return tgbotapi.NewMessage(msg.Chat.ID, "буду пильнувати за "+args), nil
+	p, areas := i18n.From(ctx), splitAreas(args)
+	if len(areas) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.alert_usage")), nil
 	}
 
-	if err := r.chat.SetCommand(ctx, msg.Chat.ID, "track"); err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("set command: %w", err)
+	if err := r.fake.FakeAlert(ctx, areas, duration); err != nil {
+		r.audit.Record(ctx, msg.Chat.ID, "fake_alert", args, "error: "+err.Error())
+
+		return tgbotapi.MessageConfig{}, fmt.Errorf("alert: %w", err)
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, "вкажи територію, за якою пильнувати"), nil
+	r.audit.Record(ctx, msg.Chat.ID, "fake_alert", args, "sent for "+duration.String())
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.alerting", duration.String(), p.Areas(areas).Join(", "))), nil
 }
 
-func (r Commander) Tracking(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
-	list, err := r.notification.Tracking(ctx, msg.Chat.ID)
+// AdminFakeAllClear ends the given simulated alerts, or all of them.
+func (r Commander) AdminFakeAllClear(
+	ctx context.Context, msg *tgbotapi.Message, args string,
+) (tgbotapi.MessageConfig, error) {
+	ended := r.fake.FakeAllClear(ctx, splitAreas(args))
+
+	r.audit.Record(ctx, msg.Chat.ID, "fake_all_clear", args, "ended "+ended.Join(","))
+
+	p := i18n.From(ctx)
+
+	if len(ended) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.nothing_to_end")), nil
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.ended", p.Areas(ended).Join(", "))), nil
+}
+
+func (r Commander) AdminFakeScenario(
+	ctx context.Context, msg *tgbotapi.Message, args string,
+) (tgbotapi.MessageConfig, error) {
+	p, name := i18n.From(ctx), strings.TrimSpace(args)
+	if len(name) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.scenario_usage")), nil
+	}
+
+	scenario, err := r.fake.LoadScenario(name)
 	if err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+		r.audit.Record(ctx, msg.Chat.ID, "fake_scenario", name, "error: "+err.Error())
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.scenario_error", err.Error())), nil
 	}
 
-	if len(list) == 0 {
-		return tgbotapi.NewMessage(msg.Chat.ID, "ще нічого не трекаєш"), nil
+	// the scenario outlives the update, it plays under the alerts job and stops with it
+	if err := r.fake.Play(scenario); err != nil {
+		r.audit.Record(ctx, msg.Chat.ID, "fake_scenario", name, "error: "+err.Error())
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("fake.scenario_error", err.Error())), nil
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, strings.Join(list.Areas(), ", ")), nil
+	r.audit.Record(ctx, msg.Chat.ID, "fake_scenario", name, fmt.Sprintf("playing %d events", len(scenario.Events)))
+
+	events := len(scenario.Events)
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.N("fake.playing", events, scenario.Name, events)), nil
 }
 
-func (r Commander) Stop(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
-	if len(args) > 0 {
-		if err := r.notification.Stop(ctx, msg.Chat.ID, args); err != nil {
-			return tgbotapi.MessageConfig{}, fmt.Errorf("stop: %w", err)
-		}
+// Tester lets the chat opt in to simulated alerts and out of them.
+func (r Commander) Tester(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
 
-		return tgbotapi.NewMessage(msg.Chat.ID, "відписуюсь від "+args), nil
+	var tester bool
+
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "on":
+		tester = true
+	case "off":
+	default:
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("tester.usage")), nil
+	}
+
+	if err := r.chat.SetTester(ctx, msg.Chat.ID, tester); err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("set tester: %w", err)
 	}
 
-	if err := r.chat.SetCommand(ctx, msg.Chat.ID, "stop"); err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("set command: %w", err)
+	r.audit.Record(ctx, msg.Chat.ID, "tester", args, strconv.FormatBool(tester))
+
+	if tester {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("tester.on")), nil
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, "вкажи територію від якої відписатись"), nil
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("tester.off")), nil
 }
 
-func (r Commander) Alerts(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
-	alerts, err := r.alert.GetActive(ctx)
-	if err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("get active: %w", err)
+// Lang shows the language of the chat with buttons to switch it, or switches it to the one given.
+func (r Commander) Lang(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	args = strings.TrimSpace(args)
+	if len(args) == 0 {
+		outMsg := tgbotapi.NewMessage(msg.Chat.ID, p.T("lang.current", i18n.Name(p.Lang())))
+		outMsg.ReplyMarkup = langKeyboard()
+
+		return outMsg, nil
 	}
 
-	if len(alerts) == 0 {
-		return tgbotapi.NewMessage(msg.Chat.ID, "все тихо"), nil
+	lang, ok := i18n.Match(args)
+	if !ok {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("lang.unknown", strings.Join(i18n.Languages(), ", "))), nil
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, strings.Join(alerts.Areas(), ", ")), nil
+	if err := r.setLanguage(ctx, msg.Chat.ID, lang); err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+
+	return tgbotapi.NewMessage(msg.Chat.ID, i18n.New(lang).T("lang.set")), nil
 }
 
-func (r Commander) Start(_ context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.MessageConfig, error) {
-	return tgbotapi.NewMessage(
-		msg.Chat.ID,
-		`Пильнуй сповіщення в сусідніх областях.
+func langKeyboard() tgbotapi.InlineKeyboardMarkup {
+	row := make([]tgbotapi.InlineKeyboardButton, 0, len(i18n.Languages()))
+	for _, lang := range i18n.Languages() {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.Name(lang), "set_lang:"+lang))
+	}
 
-Приклад, як створити сповіщення:
-/areas
-І далі наклацати області, які цікавлять.
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
 
-Дані беруться з карт:
-- https://alerts.in.ua/
-- https://vadimklimenko.com/map/
-- https://alarmmap.online/
-`,
-	), nil
+func (r Commander) SetLanguage(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.EditMessageTextConfig, error) {
+	lang, ok := i18n.Match(payload)
+	if !ok {
+		return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("unknown language %s", payload)
+	}
+
+	if err := r.setLanguage(ctx, cq.Message.Chat.ID, lang); err != nil {
+		return tgbotapi.EditMessageTextConfig{}, err
+	}
+
+	return tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, i18n.New(lang).T("lang.set")), nil
 }
 
-func (r Commander) Areas(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
-	areas := map[string]struct{}{
-		"Волинська":         {},
-		"Вінницька":         {},
-		"Дніпропетровська":  {},
-		"Донецька":          {},
-		"Житомирська":       {},
-		"Закарпатська":      {},
-		"Запорізька":        {},
-		"Івано-Франківська": {},
-		"Київська":          {},
-		"Кіровоградська":    {},
-		"Луганська":         {},
-		"Львівська":         {},
-		"Миколаївська":      {},
-		"Одеська":           {},
-		"Полтавська":        {},
-		"Рівненська":        {},
-		"Сумська":           {},
-		"Тернопільська":     {},
-		"Харківська":        {},
-		"Херсонська":        {},
-		"Хмельницька":       {},
-		"Черкаська":         {},
-		"Чернівецька":       {},
-		"Чернігівська":      {},
+func (r Commander) setLanguage(ctx context.Context, chatID int64, lang string) error {
+	if err := r.chat.SetLanguage(ctx, chatID, lang); err != nil {
+		return fmt.Errorf("set language: %w", err)
 	}
 
-	tracking, err := r.notification.Tracking(ctx, msg.Chat.ID)
+	r.audit.Record(ctx, chatID, "lang", lang, "set")
+
+	return nil
+}
+
+// Status turns the pinned status message of the chat on and off; while it is on, the chat gets no notifications.
+func (r Commander) Status(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "on":
+		if err := r.statuses.Enable(ctx, msg.Chat.ID); err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("enable status: %w", err)
+		}
+
+		r.audit.Record(ctx, msg.Chat.ID, "status", args, "on")
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("status.on")), nil
+	case "off":
+		disabled, err := r.statuses.Disable(ctx, msg.Chat.ID)
+		if err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("disable status: %w", err)
+		}
+
+		if !disabled {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("status.not_on")), nil
+		}
+
+		r.audit.Record(ctx, msg.Chat.ID, "status", args, "off")
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("status.off")), nil
+	default:
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("status.usage")), nil
+	}
+}
+
+// Digest shows when the chat gets the digest of the alerts in its areas, schedules it or stops it.
+func (r Commander) Digest(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	args = strings.TrimSpace(args)
+
+	switch {
+	case len(args) == 0:
+		digest, ok, err := r.digests.Get(ctx, msg.Chat.ID)
+		if err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("get digest: %w", err)
+		}
+
+		if !ok {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("digest.usage")), nil
+		}
+
+		text := p.T("digest.current", DescribeDigest(p, digest), digest.NextAt.In(ScheduleLocation).Format("02.01 15:04"))
+
+		return tgbotapi.NewMessage(msg.Chat.ID, text+"\n\n"+p.T("digest.usage")), nil
+	case strings.EqualFold(args, "off"):
+		stopped, err := r.digests.Stop(ctx, msg.Chat.ID)
+		if err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("stop digest: %w", err)
+		}
+
+		if !stopped {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("digest.not_on")), nil
+		}
+
+		r.audit.Record(ctx, msg.Chat.ID, "digest", args, "off")
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("digest.off")), nil
+	}
+
+	digest, ok := ParseDigest(msg.Chat.ID, args)
+	if !ok {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("digest.usage")), nil
+	}
+
+	digest, err := r.digests.Set(ctx, digest)
 	if err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("tracking: %w", err)
+		return tgbotapi.MessageConfig{}, fmt.Errorf("set digest: %w", err)
 	}
 
-	var areasTracking types.Stringies
+	r.audit.Record(ctx, msg.Chat.ID, "digest", args, "set")
 
-	for _, notification := range tracking {
-		if _, ok := areas[notification.Area]; ok {
-			areasTracking = append(areasTracking, notification.Area)
+	text := p.T("digest.set", DescribeDigest(p, digest), digest.NextAt.In(ScheduleLocation).Format("02.01 15:04"))
+
+	return tgbotapi.NewMessage(msg.Chat.ID, text), nil
+}
+
+// Template shows the notification templates of the chat, or replaces or resets the one of a kind:
+// /template alert [format] <template>, /template alert reset.
+func (r Commander) Template(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	kind, rest := cutWord(args)
+	if len(kind) == 0 {
+		return r.showTemplates(ctx, msg.Chat.ID)
+	}
+
+	if !TemplateKinds().Contains(kind) {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.unknown_kind", TemplateKinds().Join(", "))), nil
+	}
+
+	if strings.EqualFold(rest, "reset") {
+		reset, err := r.templates.Reset(ctx, msg.Chat.ID, kind)
+		if err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("reset template: %w", err)
+		}
+
+		if !reset {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.not_set", kind)), nil
+		}
+
+		r.audit.Record(ctx, msg.Chat.ID, "template", kind, "reset")
+
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.reset", kind)), nil
+	}
+
+	format, text := templateFormat(rest)
+	if len(text) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.usage")), nil
+	}
+
+	chatTemplate := types2.ChatTemplate{ChatID: msg.Chat.ID, Kind: kind, Format: format, Text: text}
+	if err := r.templates.Save(ctx, p, chatTemplate); err != nil {
+		var invalid TemplateError
+		if errors.As(err, &invalid) {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.invalid", invalid.Text(p))), nil
 		}
+
+		return tgbotapi.MessageConfig{}, fmt.Errorf("save template: %w", err)
 	}
 
-	text := "можеш обрати на які області підписатись"
-	if len(areasTracking) > 0 {
-		text += "\n\nПідписки: " + areasTracking.Sort().Join(", ")
+	r.audit.Record(ctx, msg.Chat.ID, "template", kind, format)
+
+	return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.saved", kind, kind)), nil
+}
+
+func (r Commander) showTemplates(ctx context.Context, chatID int64) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	list, err := r.templates.Get(ctx, chatID)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("templates: %w", err)
+	}
+
+	lines := make([]string, 0, len(TemplateKinds())+1)
+
+	for _, kind := range TemplateKinds() {
+		if chatTemplate, ok := list.Of(chatID, kind); ok {
+			lines = append(lines, p.T("template.current", kind, chatTemplate.Format, chatTemplate.Text))
+		} else {
+			lines = append(lines, p.T("template.default", kind))
+		}
+	}
+
+	lines = append(lines, p.T("template.usage"))
+
+	return tgbotapi.NewMessage(chatID, strings.Join(lines, "\n\n")), nil
+}
+
+// TemplatePreview renders the template of a kind, alert if not given, with sample data:
+// the draft following the kind if there is one, the saved template or the default text otherwise.
+func (r Commander) TemplatePreview(
+	ctx context.Context, msg *tgbotapi.Message, args string,
+) (tgbotapi.MessageConfig, error) {
+	p := i18n.From(ctx)
+
+	kind, rest := cutWord(args)
+	if len(kind) == 0 {
+		kind = TemplateAlert
+	}
+
+	if !TemplateKinds().Contains(kind) {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.unknown_kind", TemplateKinds().Join(", "))), nil
+	}
+
+	data := SampleTemplateData(p, kind)
+	chatTemplate := types2.ChatTemplate{ChatID: msg.Chat.ID, Kind: kind}
+
+	if len(rest) > 0 {
+		chatTemplate.Format, chatTemplate.Text = templateFormat(rest)
+	} else {
+		list, err := r.templates.Get(ctx, msg.Chat.ID)
+		if err != nil {
+			return tgbotapi.MessageConfig{}, fmt.Errorf("templates: %w", err)
+		}
+
+		saved, ok := list.Of(msg.Chat.ID, kind)
+		if !ok {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("notify."+kind, data.Areas)), nil
+		}
+
+		chatTemplate = saved
+	}
+
+	if err := ValidateTemplate(p, chatTemplate); err != nil {
+		var invalid TemplateError
+		if errors.As(err, &invalid) {
+			return tgbotapi.NewMessage(msg.Chat.ID, p.T("template.invalid", invalid.Text(p))), nil
+		}
+
+		return tgbotapi.MessageConfig{}, fmt.Errorf("validate template: %w", err)
+	}
+
+	text, parseMode, err := RenderTemplate(chatTemplate, data)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("render template: %w", err)
 	}
 
 	outMsg := tgbotapi.NewMessage(msg.Chat.ID, text)
-	outMsg.ReplyMarkup = areasKeyboard(areasTracking)
+	outMsg.ParseMode = parseMode
 
 	return outMsg, nil
 }
 
-func areasKeyboard(tracking types.Stringies) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Волинська", "✅"), "toggle_area:Волинська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Вінницька", "✅"), "toggle_area:Вінницька"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Дніпропетровська", "✅"), "toggle_area:Дніпропетровська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Донецька", "✅"), "toggle_area:Донецька"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Житомирська", "✅"), "toggle_area:Житомирська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Закарпатська", "✅"), "toggle_area:Закарпатська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Запорізька", "✅"), "toggle_area:Запорізька"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Івано-Франківська", "✅"), "toggle_area:Івано-Франківська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Київська", "✅"), "toggle_area:Київська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Кіровоградська", "✅"), "toggle_area:Кіровоградська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Луганська", "✅"), "toggle_area:Луганська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Львівська", "✅"), "toggle_area:Львівська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Миколаївська", "✅"), "toggle_area:Миколаївська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Одеська", "✅"), "toggle_area:Одеська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Полтавська", "✅"), "toggle_area:Полтавська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Рівненська", "✅"), "toggle_area:Рівненська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Сумська", "✅"), "toggle_area:Сумська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Тернопільська", "✅"), "toggle_area:Тернопільська"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Харківська", "✅"), "toggle_area:Харківська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Херсонська", "✅"), "toggle_area:Херсонська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Хмельницька", "✅"), "toggle_area:Хмельницька"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Черкаська", "✅"), "toggle_area:Черкаська"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Чернівецька", "✅"), "toggle_area:Чернівецька"),
-			tgbotapi.NewInlineKeyboardButtonData(tracking.PrependIfContains("Чернігівська", "✅"), "toggle_area:Чернігівська"),
-		),
-	)
+// templateFormat splits the format off the template, taking text if the template does not start with one.
+func templateFormat(input string) (string, string) {
+	word, rest := cutWord(input)
+	if format := strings.ToLower(word); TemplateFormats().Contains(format) {
+		return format, rest
+	}
+
+	return FormatText, input
 }
 
-func (r Commander) ToggleArea(
+// cutWord splits the first word off the input, keeping the line breaks of the rest.
+func cutWord(input string) (string, string) {
+	input = strings.TrimSpace(input)
+
+	idx := strings.IndexFunc(input, unicode.IsSpace)
+	if idx < 0 {
+		return input, ""
+	}
+
+	return input[:idx], strings.TrimSpace(input[idx:])
+}
+
+// shorten cuts the text to at most max runes, marking the cut with an ellipsis.
+func shorten(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	return string([]rune(text)[:max-1]) + "…"
+}
+
+func splitAreas(input string) types.Stringies {
+	var areas types.Stringies
+
+	for _, area := range strings.Split(input, ",") {
+		if area = strings.TrimSpace(area); len(area) > 0 {
+			areas = append(areas, area)
+		}
+	}
+
+	return areas
+}
+
+// Broadcast asks whom to send to and what, taking the text from the arguments if there are any.
+func (r Commander) Broadcast(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
+	var data ConversationData
+	if args = strings.TrimSpace(args); len(args) > 0 {
+		data = ConversationData{"text": args}
+	}
+
+	chattable, err := r.conversations.Start(ctx, msg.Chat.ID, r.flows[flowBroadcast], data)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	return chattable, nil
+}
+
+func validateBroadcastTarget(ctx context.Context, _ int64, input string, _ ConversationData) (string, string, error) {
+	target, ok := ParseBroadcastTarget(input)
+	if !ok {
+		return "", i18n.From(ctx).T("broadcast.target_retry"), nil
+	}
+
+	return target, "", nil
+}
+
+// broadcastPreview saves the draft and shows it the way chats will see it, waiting for confirmation.
+func (r Commander) broadcastPreview(ctx context.Context, chatID int64, text, target string) (tgbotapi.Chattable, error) {
+	broadcast, err := r.broadcasts.Draft(ctx, chatID, text, target)
+	if err != nil {
+		return nil, fmt.Errorf("draft: %w", err)
+	}
+
+	p, id := i18n.From(ctx), strconv.FormatInt(broadcast.ID, 10)
+
+	outMsg := tgbotapi.NewMessage(chatID, p.T(
+		"broadcast.preview",
+		DescribeBroadcastTarget(p, target), p.N("broadcast.recipients", broadcast.Total, broadcast.Total), text,
+	))
+	outMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(p.T("broadcast.send"), "bc_confirm:"+id),
+		tgbotapi.NewInlineKeyboardButtonData(p.T("broadcast.cancel"), "bc_cancel:"+id),
+	))
+
+	return outMsg, nil
+}
+
+func (r Commander) ConfirmBroadcast(
 	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
-) (tgbotapi.EditMessageTextConfig, error) {
-	tracking, err := r.notification.Tracking(ctx, cq.Message.Chat.ID)
+) (tgbotapi.Chattable, error) {
+	id, err := strconv.ParseInt(payload, 10, 64)
 	if err != nil {
-		return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("tracking: %w", err)
+		return nil, fmt.Errorf("parse id %s: %w", payload, err)
 	}
 
-	trackingAreas := tracking.Areas()
+	_, ok, err := r.broadcasts.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
 
-	if tracking.Tracking(payload) {
-		if err = r.notification.Stop(ctx, cq.Message.Chat.ID, payload); err != nil {
-			return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("stop: %w", err)
-		}
+	p := i18n.From(ctx)
 
-		trackingAreas = trackingAreas.Delete(payload)
-	} else {
-		if err = r.notification.Track(ctx, cq.Message.Chat.ID, payload); err != nil {
-			return tgbotapi.EditMessageTextConfig{}, fmt.Errorf("track: %w", err)
-		}
+	if !ok {
+		return tgbotapi.NewCallbackWithAlert(cq.ID, p.T("broadcast.not_found")), nil
+	}
 
-		trackingAreas = append(trackingAreas, payload)
+	if ok, err = r.broadcasts.Confirm(ctx, id, cq.Message.MessageID); err != nil {
+		return nil, fmt.Errorf("confirm: %w", err)
 	}
 
-	text := "Підписки: " + trackingAreas.Sort().Join(", ")
-	if len(trackingAreas) == 0 {
-		text = "Нема підписок"
+	if !ok {
+		return tgbotapi.NewCallbackWithAlert(cq.ID, p.T("broadcast.not_draft")), nil
 	}
 
-	return tgbotapi.
-			NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, text, areasKeyboard(trackingAreas)),
-		nil
+	// the broadcast edits the preview into its status itself; an edit from here could land after it has finished
+	return tgbotapi.NewCallback(cq.ID, p.T("broadcast.sending")), nil
 }
 
-func (r Commander) Auth(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
-	split := strings.SplitN(args, ":", 2)
-	if len(split) != 2 {
-		return tgbotapi.NewMessage(msg.Chat.ID, "nopes"), nil
+func (r Commander) CancelBroadcast(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.Chattable, error) {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse id %s: %w", payload, err)
 	}
 
-	priv, passwd := split[0], split[1]
-	if passwd != os.Getenv("SOME_ADMIN_PASSWD") {
-		return tgbotapi.NewMessage(msg.Chat.ID, "nopes"), nil
+	ok, err := r.broadcasts.Cancel(ctx, id, cq.Message.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel: %w", err)
 	}
 
-	if err := r.chat.Grant(ctx, msg.Chat.ID, priv); err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("grant: %w", err)
+	p := i18n.From(ctx)
+
+	if !ok {
+		return tgbotapi.NewCallbackWithAlert(cq.ID, p.T("broadcast.not_draft")), nil
 	}
 
-	return tgbotapi.NewDeleteMessage(msg.Chat.ID, msg.MessageID), nil
+	return tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, p.T("broadcast.cancelled")), nil
 }
 
-func (r Commander) AdminFakeAlertIn(
-	ctx context.Context, msg *tgbotapi.Message, args string,
-) (tgbotapi.MessageConfig, error) {
-	if len(args) == 0 {
-		return tgbotapi.NewMessage(msg.Chat.ID, "specify area name"), nil
+func (r Commander) Schedule(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
+	chattable, err := r.conversations.Start(ctx, msg.Chat.ID, r.flows[flowSchedule], nil)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
 	}
 
-	if err := r.fake.FakeAlert(ctx, args); err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("alert: %w", err)
+	return chattable, nil
+}
+
+func (r Commander) validateScheduleWhen(
+	ctx context.Context, _ int64, input string, _ ConversationData,
+) (string, string, error) {
+	if _, _, err := r.scheduled.ParseWhen(input); err != nil {
+		return "", i18n.From(ctx).T("schedule.when_retry", err.Error()), nil
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, "sent"), nil
+	return strings.TrimSpace(input), "", nil
 }
 
-func (r Commander) Broadcast(ctx context.Context, msg *tgbotapi.Message, args string) (tgbotapi.Chattable, error) {
-	if len(args) > 0 {
-		list, err := r.chat.All(ctx)
-		if err != nil {
-			return tgbotapi.MessageConfig{}, fmt.Errorf("all: %w", err)
-		}
+func (r Commander) schedule(ctx context.Context, chatID int64, when, target, text string) (tgbotapi.Chattable, error) {
+	scheduled, err := r.scheduled.Schedule(ctx, chatID, when, target, text)
+	if err != nil {
+		return nil, fmt.Errorf("schedule: %w", err)
+	}
+
+	return tgbotapi.NewMessage(chatID, i18n.From(ctx).T(
+		"schedule.done",
+		scheduled.ID, scheduled.NextRunAt.In(ScheduleLocation).Format(ScheduleTimeLayout),
+	)), nil
+}
 
-		//if len(list) == 1 {
-		//	return tgbotapi.NewMessage(msg.Chat.ID, "Only one chat -- that is you"), nil
-		//}
+// scheduleTextLength is how much of a broadcast text /schedules shows, for the list to fit in a message.
+const scheduleTextLength = 100
 
-		r.telegram.MaybeSendText(ctx, msg.Chat.ID, "Знайшов "+strconv.Itoa(len(list))+" чатів — броадкастю...")
+func (r Commander) Schedules(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
+	list, err := r.scheduled.Active(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("active: %w", err)
+	}
 
-		wg := &sync.WaitGroup{}
-		sf := make(chan struct{}, 10)
+	p := i18n.From(ctx)
 
-		for _, chat := range list {
-			sf <- struct{}{}
-			wg.Add(1)
+	if len(list) == 0 {
+		return tgbotapi.NewMessage(msg.Chat.ID, p.T("schedule.none")), nil
+	}
 
-			go func(chat types2.Chat) {
-				defer func() {
-					<-sf
-					wg.Done()
-				}()
+	lines := make(types.Stringies, 0, len(list))
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(list))
 
-				r.telegram.MaybeSendText(ctx, chat.ID, args)
-			}(chat)
+	for _, scheduled := range list {
+		when := p.T("schedule.once")
+		if len(scheduled.Cron) > 0 {
+			when = p.T("schedule.cron", scheduled.Cron)
 		}
 
-		wg.Wait()
+		lines = append(lines, p.T(
+			"schedule.item",
+			scheduled.ID,
+			when,
+			scheduled.NextRunAt.In(ScheduleLocation).Format(ScheduleTimeLayout),
+			DescribeBroadcastTarget(p, scheduled.Target),
+			shorten(scheduled.Text, scheduleTextLength),
+		))
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			p.T("schedule.cancel", scheduled.ID), "sched_cancel:"+strconv.FormatInt(scheduled.ID, 10),
+		)))
+	}
+
+	outMsg := tgbotapi.NewMessage(msg.Chat.ID, shorten(lines.Join("\n\n"), maxMessageLength))
+	outMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+
+	return outMsg, nil
+}
+
+func (r Commander) CancelScheduled(
+	ctx context.Context, cq *tgbotapi.CallbackQuery, payload string,
+) (tgbotapi.Chattable, error) {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse id %s: %w", payload, err)
+	}
 
-		return tgbotapi.NewMessage(msg.Chat.ID, "заброадкастив"), nil
+	ok, err := r.scheduled.Cancel(ctx, cq.Message.Chat.ID, id)
+	if err != nil {
+		return nil, fmt.Errorf("cancel: %w", err)
 	}
 
-	if err := r.chat.SetCommand(ctx, msg.Chat.ID, "admin_broadcast"); err != nil {
-		return tgbotapi.MessageConfig{}, fmt.Errorf("set command: %w", err)
+	p := i18n.From(ctx)
+
+	if !ok {
+		return tgbotapi.NewCallbackWithAlert(cq.ID, p.T("schedule.already_cancelled")), nil
 	}
 
-	return tgbotapi.NewMessage(msg.Chat.ID, "що будемо броадкастити?"), nil
+	return tgbotapi.NewCallback(cq.ID, p.T("schedule.cancelled", id)), nil
 }
 
 func (r Commander) Map(ctx context.Context, msg *tgbotapi.Message, _ string) (tgbotapi.Chattable, error) {
@@ -341,13 +1584,18 @@ func (r Commander) Map(ctx context.Context, msg *tgbotapi.Message, _ string) (tg
 		return tgbotapi.MessageConfig{}, fmt.Errorf("get active alerts: %w", err)
 	}
 
+	caption, err := r.asOf(ctx)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, fmt.Errorf("as of: %w", err)
+	}
+
 	mapz, ok, err := r.mapz.Exists(ctx, alerts)
 	if err != nil {
 		return tgbotapi.MessageConfig{}, fmt.Errorf("mapz exists: %w", err)
 	}
 
 	if ok {
-		return tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileID(mapz.FileID)), nil
+		return photo(msg.Chat.ID, tgbotapi.FileID(mapz.FileID), caption), nil
 	}
 
 	var (
@@ -358,7 +1606,7 @@ func (r Commander) Map(ctx context.Context, msg *tgbotapi.Message, _ string) (tg
 	)
 
 	go func() {
-		val, err, shared = r.sf.Do(alerts.Areas().Sort().Join(","), r.getMapLong(ctx, msg.Chat.ID, alerts))
+		val, err, shared = r.sf.Do(alerts.Areas().Sort().Join(","), r.getMapLong(ctx, msg.Chat.ID, alerts, caption))
 		close(done)
 		ticker.Stop()
 	}()
@@ -383,21 +1631,23 @@ loop:
 	r.log.Debugw("got map from singleflight", "shared", shared, "chat_id", msg.Chat.ID)
 
 	if mapz, ok = val.(types2.Map); ok {
-		return tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileID(mapz.FileID)), nil
+		return photo(msg.Chat.ID, tgbotapi.FileID(mapz.FileID), caption), nil
 	}
 
 	if cf, ok := val.(chatFile); ok {
 		r.log.Debugw("got chatfile", "chatfile", cf)
 
 		if msg.Chat.ID != cf.ChatID {
-			return tgbotapi.NewPhoto(msg.Chat.ID, tgbotapi.FileID(cf.FileID)), nil
+			return photo(msg.Chat.ID, tgbotapi.FileID(cf.FileID), caption), nil
 		}
 	}
 
 	return tgbotapi.MessageConfig{}, nil
 }
 
-func (r Commander) getMapLong(ctx context.Context, chatID int64, alerts types2.Alerts) func() (interface{}, error) {
+func (r Commander) getMapLong(
+	ctx context.Context, chatID int64, alerts types2.Alerts, caption string,
+) func() (interface{}, error) {
 	return func() (interface{}, error) {
 		r.log.Debugw("singleflight get map", "chat_id", chatID, "areas", alerts.Areas())
 
@@ -414,7 +1664,7 @@ func (r Commander) getMapLong(ctx context.Context, chatID int64, alerts types2.A
 
 		fileData := tgbotapi.FileBytes{Name: "map.png", Bytes: bts}
 
-		photoMsg, err := r.telegram.Send(ctx, tgbotapi.NewPhoto(chatID, fileData))
+		photoMsg, err := r.telegram.Send(ctx, photo(chatID, fileData, caption))
 		if err != nil {
 			return nil, fmt.Errorf("telegram send: %w", err)
 		}
@@ -435,6 +1685,13 @@ func (r Commander) getMapLong(ctx context.Context, chatID int64, alerts types2.A
 	}
 }
 
+func photo(chatID int64, file tgbotapi.RequestFileData, caption string) tgbotapi.PhotoConfig {
+	cfg := tgbotapi.NewPhoto(chatID, file)
+	cfg.Caption = caption
+
+	return cfg
+}
+
 type chatFile struct {
 	ChatID int64  `json:"chat_id"`
 	FileID string `json:"file_id"`
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const defaultConversationTimeout = 10 * time.Minute
+
+type ConversationData map[string]string
+
+type ConversationStep struct {
+	Name string
+	// Prompt is the i18n key of the question asked.
+	Prompt string
+	// Validate returns the value to keep under the step name, or a non-empty retry text to ask again with,
+	// written in the language of the context.
+	Validate func(ctx context.Context, chatID int64, input string, data ConversationData) (value, retry string, err error)
+}
+
+type ConversationFlow struct {
+	Name    string
+	Steps   []ConversationStep
+	Timeout time.Duration
+	Finish  func(ctx context.Context, chatID int64, data ConversationData) (tgbotapi.Chattable, error)
+}
+
+// ConversationStore keeps the conversations chats are in; repositories.Conversations implements it.
+type ConversationStore interface {
+	Get(ctx context.Context, chatID int64) (types2.Conversation, bool, error)
+	Save(ctx context.Context, conv types2.Conversation) error
+	Delete(ctx context.Context, chatID int64) error
+}
+
+type Conversations struct {
+	conversation ConversationStore
+	now          func() time.Time
+}
+
+func NewConversations(conversation ConversationStore) Conversations {
+	return Conversations{conversation: conversation, now: time.Now}
+}
+
+// Pending returns the flow name of the conversation the chat is in, even if it has already expired.
+func (r Conversations) Pending(ctx context.Context, chatID int64) (string, bool, error) {
+	conv, ok, err := r.conversation.Get(ctx, chatID)
+	if err != nil {
+		return "", false, fmt.Errorf("get: %w", err)
+	}
+
+	return conv.Flow, ok, nil
+}
+
+// Start begins the flow, skipping the steps data already has values for.
+func (r Conversations) Start(
+	ctx context.Context, chatID int64, flow ConversationFlow, data ConversationData,
+) (tgbotapi.Chattable, error) {
+	if data == nil {
+		data = ConversationData{}
+	}
+
+	return r.next(ctx, chatID, flow, data)
+}
+
+// Continue feeds the answer to the current step of the conversation.
+func (r Conversations) Continue(
+	ctx context.Context, chatID int64, flows map[string]ConversationFlow, input string,
+) (tgbotapi.Chattable, error) {
+	conv, ok, err := r.conversation.Get(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	if !ok {
+		return tgbotapi.NewMessage(chatID, i18n.From(ctx).T("conversation.none")), nil
+	}
+
+	flow, ok := flows[conv.Flow]
+	if !ok || r.now().After(conv.ExpiresAt) {
+		if err := r.conversation.Delete(ctx, chatID); err != nil {
+			return nil, fmt.Errorf("delete: %w", err)
+		}
+
+		return tgbotapi.NewMessage(chatID, i18n.From(ctx).T("conversation.expired")), nil
+	}
+
+	var data ConversationData
+	if err := json.Unmarshal([]byte(conv.Data), &data); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	step, ok := flow.step(conv.Step)
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown step %s", flow.Name, conv.Step)
+	}
+
+	value, retry := input, ""
+
+	if step.Validate != nil {
+		if value, retry, err = step.Validate(ctx, chatID, input, data); err != nil {
+			return nil, fmt.Errorf("validate %s/%s: %w", flow.Name, step.Name, err)
+		}
+	}
+
+	if len(retry) > 0 {
+		return tgbotapi.NewMessage(chatID, i18n.From(ctx).T("conversation.retry", retry)), nil
+	}
+
+	data[step.Name] = value
+
+	return r.next(ctx, chatID, flow, data)
+}
+
+func (r Conversations) Cancel(ctx context.Context, chatID int64) (bool, error) {
+	_, ok, err := r.conversation.Get(ctx, chatID)
+	if err != nil {
+		return false, fmt.Errorf("get: %w", err)
+	}
+
+	if !ok {
+		return false, nil
+	}
+
+	if err := r.conversation.Delete(ctx, chatID); err != nil {
+		return false, fmt.Errorf("delete: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r Conversations) next(
+	ctx context.Context, chatID int64, flow ConversationFlow, data ConversationData,
+) (tgbotapi.Chattable, error) {
+	for _, step := range flow.Steps {
+		if _, ok := data[step.Name]; ok {
+			continue
+		}
+
+		bts, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("json marshal: %w", err)
+		}
+
+		timeout := flow.Timeout
+		if timeout == 0 {
+			timeout = defaultConversationTimeout
+		}
+
+		conv := types2.Conversation{
+			ChatID:    chatID,
+			Flow:      flow.Name,
+			Step:      step.Name,
+			Data:      string(bts),
+			ExpiresAt: r.now().Add(timeout),
+		}
+
+		if err := r.conversation.Save(ctx, conv); err != nil {
+			return nil, fmt.Errorf("save: %w", err)
+		}
+
+		return tgbotapi.NewMessage(chatID, i18n.From(ctx).T(step.Prompt)), nil
+	}
+
+	if err := r.conversation.Delete(ctx, chatID); err != nil {
+		return nil, fmt.Errorf("delete: %w", err)
+	}
+
+	chattable, err := flow.Finish(ctx, chatID, data)
+	if err != nil {
+		return nil, fmt.Errorf("finish %s: %w", flow.Name, err)
+	}
+
+	return chattable, nil
+}
+
+func (r ConversationFlow) step(name string) (ConversationStep, bool) {
+	for _, step := range r.Steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+
+	return ConversationStep{}, false
+}
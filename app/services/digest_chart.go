@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"time"
+)
+
+const (
+	chartWidth  = 960
+	chartHeight = 480
+	chartMargin = 40
+)
+
+var (
+	chartBackground = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	chartGrid       = color.RGBA{R: 235, G: 235, B: 235, A: 255}
+	chartAxis       = color.RGBA{R: 90, G: 90, B: 90, A: 255}
+	chartPrevious   = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	chartCurrent    = color.RGBA{R: 230, G: 25, B: 25, A: 255}
+)
+
+// DigestChart draws the time under alert by bucket as a PNG bar chart: red bars for the period, grey ones
+// behind them for the period before. The grid lines split the tallest bar in quarters.
+func DigestChart(current, previous []time.Duration) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(chartBackground), image.Point{}, draw.Src)
+
+	var tallest time.Duration
+
+	for _, list := range [][]time.Duration{current, previous} {
+		for _, d := range list {
+			if d > tallest {
+				tallest = d
+			}
+		}
+	}
+
+	plotHeight := chartHeight - 2*chartMargin
+	baseline := chartHeight - chartMargin
+
+	for quarter := 1; quarter <= 4; quarter++ {
+		y := baseline - plotHeight*quarter/4
+		fill(img, image.Rect(chartMargin, y, chartWidth-chartMargin, y+1), chartGrid)
+	}
+
+	if len(current) > 0 && tallest > 0 {
+		slot := (chartWidth - 2*chartMargin) / len(current)
+
+		height := func(d time.Duration) int {
+			return int(int64(plotHeight) * int64(d) / int64(tallest))
+		}
+
+		for i := range current {
+			left := chartMargin + i*slot
+
+			if i < len(previous) {
+				fill(img, image.Rect(left+slot/10, baseline-height(previous[i]), left+slot*9/10, baseline), chartPrevious)
+			}
+
+			fill(img, image.Rect(left+slot/4, baseline-height(current[i]), left+slot*3/4, baseline), chartCurrent)
+		}
+	}
+
+	fill(img, image.Rect(chartMargin, baseline, chartWidth-chartMargin, baseline+2), chartAxis)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func fill(img draw.Image, rect image.Rectangle, c color.Color) {
+	draw.Draw(img, rect, image.NewUniform(c), image.Point{}, draw.Src)
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"closealerts/app/clients"
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Digest periods.
+const (
+	DigestDay  = "day"
+	DigestWeek = "week"
+)
+
+// DigestTimeLayout is how chats enter the time digests come at, in the ScheduleLocation.
+const DigestTimeLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"нд": time.Sunday, "пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday,
+	"чт": time.Thursday, "пт": time.Friday, "сб": time.Saturday,
+}
+
+// DigestSummary is what happened in the areas of a chat over a period.
+type DigestSummary struct {
+	// Count is of the alerts started in the period.
+	Count int
+	// Total is the time any of the areas was under alert.
+	Total   time.Duration
+	Longest types2.AlertRecord
+	// LongestFor is how long the longest alert lasted, or has lasted by the end of the period.
+	LongestFor time.Duration
+	// Buckets split the Total by hours of a day or days of a week, for the chart.
+	Buckets []time.Duration
+}
+
+// DigestStore keeps the digests chats asked for; repositories.Digests implements it.
+type DigestStore interface {
+	Get(ctx context.Context, chatID int64) (types2.Digest, bool, error)
+	Save(ctx context.Context, digest types2.Digest) error
+	Delete(ctx context.Context, chatID int64) (bool, error)
+	// Due returns the digests whose time has come by now.
+	Due(ctx context.Context, now time.Time) (types2.Digests, error)
+}
+
+// AlertRecordStore keeps the history of the alerts; repositories.AlertRecords implements it.
+type AlertRecordStore interface {
+	Record(ctx context.Context, alerts types2.Alerts, at time.Time) error
+	// Between returns the alerts in the areas that went on at some point of [from, to).
+	Between(ctx context.Context, areas []string, from, to time.Time) (types2.AlertRecords, error)
+}
+
+// Digests sends chats the summaries of the alerts in their areas they asked for, out of the alert history
+// it keeps.
+type Digests struct {
+	log          *zap.SugaredLogger
+	digests      DigestStore
+	records      AlertRecordStore
+	notification TrackingStore
+	languages    LanguageStore
+	telegram     Sender
+	now          func() time.Time
+}
+
+func NewDigests(
+	log *zap.SugaredLogger,
+	digests DigestStore,
+	records AlertRecordStore,
+	notification TrackingStore,
+	languages LanguageStore,
+	telegram Sender,
+) Digests {
+	return Digests{
+		log:          log,
+		digests:      digests,
+		records:      records,
+		notification: notification,
+		languages:    languages,
+		telegram:     telegram,
+		now:          time.Now,
+	}
+}
+
+// Record keeps the history of the real alerts, which digests are made of.
+func (r Digests) Record(ctx context.Context, alerts types2.Alerts) error {
+	if err := r.records.Record(ctx, alerts.Real(), r.now()); err != nil {
+		return fmt.Errorf("record: %w", err)
+	}
+
+	return nil
+}
+
+// ParseDigest reads the settings like day 21:00, or week 09:00 mon chart; weekly digests come on Mondays
+// unless told otherwise.
+func ParseDigest(chatID int64, input string) (types2.Digest, bool) {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) < 2 || (fields[0] != DigestDay && fields[0] != DigestWeek) {
+		return types2.Digest{}, false
+	}
+
+	digest := types2.Digest{ChatID: chatID, Period: fields[0], Weekday: int(time.Monday)}
+
+	at, err := time.Parse(DigestTimeLayout, fields[1])
+	if err != nil {
+		return types2.Digest{}, false
+	}
+
+	digest.Hour, digest.Minute = at.Hour(), at.Minute()
+
+	for _, field := range fields[2:] {
+		weekday, isWeekday := weekdays[field]
+
+		switch {
+		case field == "chart":
+			digest.Chart = true
+		case isWeekday && digest.Period == DigestWeek:
+			digest.Weekday = int(weekday)
+		default:
+			return types2.Digest{}, false
+		}
+	}
+
+	return digest, true
+}
+
+// NextDigest is the first time after the given one the digest is due.
+func NextDigest(digest types2.Digest, after time.Time) time.Time {
+	after = after.In(ScheduleLocation)
+	day := after.Day()
+
+	for {
+		next := time.Date(after.Year(), after.Month(), day, digest.Hour, digest.Minute, 0, 0, ScheduleLocation)
+		if next.After(after) && (digest.Period == DigestDay || next.Weekday() == time.Weekday(digest.Weekday)) {
+			return next
+		}
+
+		day++
+	}
+}
+
+// DescribeDigest tells when the digest comes, like "daily at 21:00, with a chart".
+func DescribeDigest(p i18n.Printer, digest types2.Digest) string {
+	at := fmt.Sprintf("%02d:%02d", digest.Hour, digest.Minute)
+
+	text := p.T("digest.schedule.day", at)
+	if digest.Period == DigestWeek {
+		text = p.T("digest.schedule.week", p.T("weekday."+strconv.Itoa(digest.Weekday)), at)
+	}
+
+	if digest.Chart {
+		text += p.T("digest.with_chart")
+	}
+
+	return text
+}
+
+func (r Digests) Get(ctx context.Context, chatID int64) (types2.Digest, bool, error) {
+	digest, ok, err := r.digests.Get(ctx, chatID)
+	if err != nil {
+		return types2.Digest{}, false, fmt.Errorf("get: %w", err)
+	}
+
+	return digest, ok, nil
+}
+
+// Set schedules the digest from now on, returning it with the time it comes next.
+func (r Digests) Set(ctx context.Context, digest types2.Digest) (types2.Digest, error) {
+	digest.NextAt = NextDigest(digest, r.now())
+
+	if err := r.digests.Save(ctx, digest); err != nil {
+		return types2.Digest{}, fmt.Errorf("save: %w", err)
+	}
+
+	return digest, nil
+}
+
+// Stop tells whether the chat had digests to stop.
+func (r Digests) Stop(ctx context.Context, chatID int64) (bool, error) {
+	stopped, err := r.digests.Delete(ctx, chatID)
+	if err != nil {
+		return false, fmt.Errorf("delete: %w", err)
+	}
+
+	return stopped, nil
+}
+
+// RunDue sends the digests that are due and schedules the next ones. Digests missed while the bot was down
+// come once, summarizing the period up to the time they were due.
+func (r Digests) RunDue(ctx context.Context) error {
+	due, err := r.digests.Due(ctx, r.now())
+	if err != nil {
+		return fmt.Errorf("due: %w", err)
+	}
+
+	for _, digest := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		err := r.send(ctx, digest)
+
+		switch {
+		case clients.IsBlocked(err):
+			r.log.Infow("chat is gone, dropping its digest", "chat_id", digest.ChatID)
+
+			if _, err := r.digests.Delete(ctx, digest.ChatID); err != nil {
+				r.log.Errorw("delete digest", "chat_id", digest.ChatID, "err", err)
+			}
+
+			continue
+		case err != nil:
+			// a digest failing every time must not be retried every tick
+			r.log.Errorw("send digest", "chat_id", digest.ChatID, "err", err)
+		}
+
+		digest.NextAt = NextDigest(digest, r.now())
+		if err := r.digests.Save(ctx, digest); err != nil {
+			r.log.Errorw("schedule next digest", "chat_id", digest.ChatID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+func (r Digests) send(ctx context.Context, digest types2.Digest) error {
+	langs, err := r.languages.Languages(ctx, []int64{digest.ChatID})
+	if err != nil {
+		r.log.Errorw("chat languages", "err", err)
+	}
+
+	p := i18n.New(langs[digest.ChatID])
+
+	text, current, previous, err := r.Build(ctx, p, digest.ChatID, digest.Period, digest.NextAt)
+	if err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+
+	if !digest.Chart || current.Total+previous.Total == 0 {
+		if _, err := r.telegram.Send(ctx, tgbotapi.NewMessage(digest.ChatID, text)); err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+
+		return nil
+	}
+
+	chart, err := DigestChart(current.Buckets, previous.Buckets)
+	if err != nil {
+		return fmt.Errorf("chart: %w", err)
+	}
+
+	text += "\n\n" + p.T("digest.chart."+digest.Period)
+
+	if _, err := r.telegram.Send(ctx, photo(digest.ChatID, tgbotapi.FileBytes{Name: "digest.png", Bytes: chart}, text)); err != nil {
+		return fmt.Errorf("send photo: %w", err)
+	}
+
+	return nil
+}
+
+// Build writes the digest of the period ending at the given time, returning it along with the summaries
+// of the period and the one before it.
+func (r Digests) Build(
+	ctx context.Context, p i18n.Printer, chatID int64, period string, end time.Time,
+) (string, DigestSummary, DigestSummary, error) {
+	tracking, err := r.notification.Tracking(ctx, chatID)
+	if err != nil {
+		return "", DigestSummary{}, DigestSummary{}, fmt.Errorf("tracking: %w", err)
+	}
+
+	if len(tracking) == 0 {
+		return p.T("digest.no_areas"), DigestSummary{}, DigestSummary{}, nil
+	}
+
+	length, buckets := 24*time.Hour, 24
+	if period == DigestWeek {
+		length, buckets = 7*24*time.Hour, 7
+	}
+
+	start, prevStart := end.Add(-length), end.Add(-2*length)
+	areas := types2.Notifications(tracking).Areas()
+
+	records, err := r.records.Between(ctx, areas, prevStart, end)
+	if err != nil {
+		return "", DigestSummary{}, DigestSummary{}, fmt.Errorf("between: %w", err)
+	}
+
+	current := Summarize(records, start, end, buckets)
+	previous := Summarize(records, prevStart, start, buckets)
+	prev := p.T("digest.prev." + period)
+
+	lines := []string{
+		p.T("digest.title."+period, p.Areas(areas.Sort()).Join(", ")),
+		"",
+		p.T("digest.count", current.Count, compare(p, strconv.Itoa(abs(current.Count-previous.Count)), int64(current.Count-previous.Count), prev)),
+		p.T("digest.total", FormatDuration(p, current.Total),
+			compare(p, FormatDuration(p, absDuration(current.Total-previous.Total)), int64(current.Total-previous.Total), prev)),
+	}
+
+	if current.LongestFor > 0 {
+		lines = append(lines, p.T("digest.longest",
+			p.Area(current.Longest.Area),
+			FormatDuration(p, current.LongestFor),
+			current.Longest.StartedAt.In(ScheduleLocation).Format("02.01 15:04"),
+		))
+	}
+
+	return strings.Join(lines, "\n"), current, previous, nil
+}
+
+// compare tells how the value differs from the one of the previous period, by the written difference and its sign.
+func compare(p i18n.Printer, difference string, delta int64, prev string) string {
+	switch {
+	case delta > 0:
+		return p.T("digest.more", difference, prev)
+	case delta < 0:
+		return p.T("digest.less", difference, prev)
+	default:
+		return p.T("digest.same", prev)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
+
+// Summarize sums the alerts up over [from, to), splitting the time under alert into equal buckets.
+func Summarize(records types2.AlertRecords, from, to time.Time, buckets int) DigestSummary {
+	summary := DigestSummary{Buckets: make([]time.Duration, buckets)}
+
+	var spans [][2]time.Time
+
+	for _, record := range records {
+		end := to
+		if record.EndedAt != nil && record.EndedAt.Before(to) {
+			end = *record.EndedAt
+		}
+
+		if !record.StartedAt.Before(to) || !end.After(from) {
+			continue
+		}
+
+		if !record.StartedAt.Before(from) {
+			summary.Count++
+		}
+
+		if lasted := end.Sub(record.StartedAt); lasted > summary.LongestFor {
+			summary.Longest, summary.LongestFor = record, lasted
+		}
+
+		spans = append(spans, [2]time.Time{later(record.StartedAt, from), end})
+	}
+
+	// alerts in several areas at once count once
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0].Before(spans[j][0]) })
+
+	var merged [][2]time.Time
+
+	for _, span := range spans {
+		if last := len(merged) - 1; last >= 0 && !span[0].After(merged[last][1]) {
+			merged[last][1] = later(merged[last][1], span[1])
+
+			continue
+		}
+
+		merged = append(merged, span)
+	}
+
+	bucket := to.Sub(from) / time.Duration(buckets)
+
+	for _, span := range merged {
+		summary.Total += span[1].Sub(span[0])
+
+		for i := range summary.Buckets {
+			bucketFrom := from.Add(time.Duration(i) * bucket)
+			bucketTo := bucketFrom.Add(bucket)
+
+			if overlap := earlier(span[1], bucketTo).Sub(later(span[0], bucketFrom)); overlap > 0 {
+				summary.Buckets[i] += overlap
+			}
+		}
+	}
+
+	return summary
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
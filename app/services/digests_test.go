@@ -0,0 +1,343 @@
+package services_test
+
+import (
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/services"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   types2.Digest
+		wantOK bool
+	}{
+		{
+			name:   "daily",
+			input:  "day 21:00",
+			want:   types2.Digest{ChatID: 1, Period: services.DigestDay, Hour: 21, Weekday: int(time.Monday)},
+			wantOK: true,
+		},
+		{
+			name:   "weekly on Mondays by default",
+			input:  "week 09:30",
+			want:   types2.Digest{ChatID: 1, Period: services.DigestWeek, Hour: 9, Minute: 30, Weekday: int(time.Monday)},
+			wantOK: true,
+		},
+		{
+			name:   "weekly on a given day with a chart",
+			input:  "Week 09:00 FRI chart",
+			want:   types2.Digest{ChatID: 1, Period: services.DigestWeek, Hour: 9, Weekday: int(time.Friday), Chart: true},
+			wantOK: true,
+		},
+		{
+			name:   "weekday in Ukrainian",
+			input:  "week 18:00 chart нд",
+			want:   types2.Digest{ChatID: 1, Period: services.DigestWeek, Hour: 18, Weekday: int(time.Sunday), Chart: true},
+			wantOK: true,
+		},
+		{name: "weekday of a daily digest", input: "day 21:00 mon", wantOK: false},
+		{name: "no time", input: "day", wantOK: false},
+		{name: "time out of range", input: "day 24:00", wantOK: false},
+		{name: "time without minutes", input: "day 21", wantOK: false},
+		{name: "unknown period", input: "month 21:00", wantOK: false},
+		{name: "unknown option", input: "week 21:00 mon map", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := services.ParseDigest(1, tt.input)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDigest(%q) = %+v, %v, want %+v, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	daily := func(hour, minute int) types2.Digest {
+		return types2.Digest{Period: services.DigestDay, Hour: hour, Minute: minute}
+	}
+
+	weekly := func(weekday time.Weekday, hour int) types2.Digest {
+		return types2.Digest{Period: services.DigestWeek, Hour: hour, Weekday: int(weekday)}
+	}
+
+	tests := []struct {
+		name   string
+		digest types2.Digest
+		after  time.Time
+		want   time.Time
+	}{
+		{
+			name:   "later today",
+			digest: daily(21, 0),
+			after:  time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "right at the time is the next day",
+			digest: daily(21, 0),
+			after:  time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "the day is of the schedule location",
+			digest: daily(1, 0),
+			after:  time.Date(2026, 10, 19, 21, 30, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "over the end of the month",
+			digest: daily(9, 0),
+			after:  time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "weekly later this week",
+			digest: weekly(time.Friday, 9),
+			after:  time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 23, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "weekly on the day after the time",
+			digest: weekly(time.Monday, 9),
+			after:  time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 26, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "the day clocks go forward is 23 hours long",
+			digest: daily(21, 0),
+			after:  time.Date(2026, 3, 28, 19, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 3, 29, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "the day clocks go back is 25 hours long",
+			digest: daily(21, 0),
+			after:  time.Date(2026, 10, 24, 18, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 25, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "time skipped by the clocks going forward comes an hour later",
+			digest: daily(3, 30),
+			after:  time.Date(2026, 3, 28, 22, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "time repeated by the clocks going back comes once",
+			digest: daily(3, 30),
+			after:  time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 26, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "weekly over the clocks going back",
+			digest: weekly(time.Monday, 9),
+			after:  time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, 10, 26, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := services.NextDigest(tt.digest, tt.after); !got.Equal(tt.want) {
+				t.Errorf("NextDigest(%+v, %s) = %s, want %s", tt.digest, tt.after, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, services.ScheduleLocation)
+	}
+
+	record := func(area string, from time.Time, to *time.Time) types2.AlertRecord {
+		return types2.AlertRecord{Area: area, Type: "air", StartedAt: from, EndedAt: to}
+	}
+
+	ended := func(t time.Time) *time.Time { return &t }
+
+	// hourly buckets of the window, the unlisted ones are empty
+	hourly := func(busy map[int]time.Duration) []time.Duration {
+		buckets := make([]time.Duration, 24)
+		for i, d := range busy {
+			buckets[i] = d
+		}
+
+		return buckets
+	}
+
+	from, to := at(19, 0, 0), at(20, 0, 0)
+
+	tests := []struct {
+		name     string
+		records  types2.AlertRecords
+		from, to time.Time
+		buckets  int
+		want     services.DigestSummary
+	}{
+		{
+			name:    "nothing",
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want:    services.DigestSummary{Buckets: hourly(nil)},
+		},
+		{
+			name:    "one alert",
+			records: types2.AlertRecords{record("Київська", at(19, 10, 0), ended(at(19, 11, 0)))},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      1,
+				Total:      time.Hour,
+				Longest:    record("Київська", at(19, 10, 0), ended(at(19, 11, 0))),
+				LongestFor: time.Hour,
+				Buckets:    hourly(map[int]time.Duration{10: time.Hour}),
+			},
+		},
+		{
+			name: "overlapping alerts count once in the total",
+			records: types2.AlertRecords{
+				record("Київська", at(19, 10, 0), ended(at(19, 11, 0))),
+				record("м. Київ", at(19, 10, 30), ended(at(19, 12, 0))),
+			},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      2,
+				Total:      2 * time.Hour,
+				Longest:    record("м. Київ", at(19, 10, 30), ended(at(19, 12, 0))),
+				LongestFor: 90 * time.Minute,
+				Buckets:    hourly(map[int]time.Duration{10: time.Hour, 11: time.Hour}),
+			},
+		},
+		{
+			name: "alert within another",
+			records: types2.AlertRecords{
+				record("м. Київ", at(19, 11, 0), ended(at(19, 12, 0))),
+				record("Київська", at(19, 10, 0), ended(at(19, 14, 0))),
+			},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      2,
+				Total:      4 * time.Hour,
+				Longest:    record("Київська", at(19, 10, 0), ended(at(19, 14, 0))),
+				LongestFor: 4 * time.Hour,
+				Buckets:    hourly(map[int]time.Duration{10: time.Hour, 11: time.Hour, 12: time.Hour, 13: time.Hour}),
+			},
+		},
+		{
+			name: "adjacent alerts",
+			records: types2.AlertRecords{
+				record("м. Київ", at(19, 11, 0), ended(at(19, 12, 0))),
+				record("Київська", at(19, 10, 0), ended(at(19, 11, 0))),
+			},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      2,
+				Total:      2 * time.Hour,
+				Longest:    record("м. Київ", at(19, 11, 0), ended(at(19, 12, 0))),
+				LongestFor: time.Hour,
+				Buckets:    hourly(map[int]time.Duration{10: time.Hour, 11: time.Hour}),
+			},
+		},
+		{
+			name:    "alert started before the window is not counted",
+			records: types2.AlertRecords{record("Київська", at(18, 22, 0), ended(at(19, 1, 30)))},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Total:      90 * time.Minute,
+				Longest:    record("Київська", at(18, 22, 0), ended(at(19, 1, 30))),
+				LongestFor: 210 * time.Minute,
+				Buckets:    hourly(map[int]time.Duration{0: time.Hour, 1: 30 * time.Minute}),
+			},
+		},
+		{
+			name:    "alert ended after the window is cut at its end",
+			records: types2.AlertRecords{record("Київська", at(19, 23, 30), ended(at(20, 1, 0)))},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      1,
+				Total:      30 * time.Minute,
+				Longest:    record("Київська", at(19, 23, 30), ended(at(20, 1, 0))),
+				LongestFor: 30 * time.Minute,
+				Buckets:    hourly(map[int]time.Duration{23: 30 * time.Minute}),
+			},
+		},
+		{
+			name:    "ongoing alert lasts till the end of the window",
+			records: types2.AlertRecords{record("Київська", at(19, 23, 0), nil)},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want: services.DigestSummary{
+				Count:      1,
+				Total:      time.Hour,
+				Longest:    record("Київська", at(19, 23, 0), nil),
+				LongestFor: time.Hour,
+				Buckets:    hourly(map[int]time.Duration{23: time.Hour}),
+			},
+		},
+		{
+			name: "alerts out of the window",
+			records: types2.AlertRecords{
+				record("Київська", at(18, 22, 0), ended(at(19, 0, 0))),
+				record("м. Київ", at(20, 0, 0), nil),
+			},
+			from:    from,
+			to:      to,
+			buckets: 24,
+			want:    services.DigestSummary{Buckets: hourly(nil)},
+		},
+		{
+			name:    "the day clocks go back",
+			records: types2.AlertRecords{record("Київська", at(25, 2, 0), ended(at(25, 5, 0)))},
+			from:    at(25, 0, 0),
+			to:      at(26, 0, 0),
+			buckets: 1,
+			want: services.DigestSummary{
+				Count:      1,
+				Total:      4 * time.Hour,
+				Longest:    record("Київська", at(25, 2, 0), ended(at(25, 5, 0))),
+				LongestFor: 4 * time.Hour,
+				Buckets:    []time.Duration{4 * time.Hour},
+			},
+		},
+		{
+			name:    "the day clocks go forward",
+			records: types2.AlertRecords{record("Київська", time.Date(2026, 3, 29, 2, 0, 0, 0, services.ScheduleLocation), nil)},
+			from:    time.Date(2026, 3, 29, 0, 0, 0, 0, services.ScheduleLocation),
+			to:      time.Date(2026, 3, 30, 0, 0, 0, 0, services.ScheduleLocation),
+			buckets: 1,
+			want: services.DigestSummary{
+				Count:      1,
+				Total:      21 * time.Hour,
+				Longest:    record("Київська", time.Date(2026, 3, 29, 2, 0, 0, 0, services.ScheduleLocation), nil),
+				LongestFor: 21 * time.Hour,
+				Buckets:    []time.Duration{21 * time.Hour},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := services.Summarize(tt.records, tt.from, tt.to, tt.buckets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
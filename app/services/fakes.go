@@ -2,35 +2,230 @@ package services
 
 import (
 	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultFakeAlertDuration = 5 * time.Minute
+
+var (
+	ErrNoScenarios = errors.New("scenarios dir is not configured")
+	ErrStopping    = errors.New("shutting down")
 )
 
+// FakeScenario is a timeline of simulated alert starts and ends, offsets counted from the start of playing.
+type FakeScenario struct {
+	Name   string              `yaml:"name"`
+	Events []FakeScenarioEvent `yaml:"events"`
+}
+
+type FakeScenarioEvent struct {
+	At    time.Duration `yaml:"at"`
+	Start []string      `yaml:"start"`
+	End   []string      `yaml:"end"`
+	// For limits the started alerts; they last until ended explicitly otherwise.
+	For time.Duration `yaml:"for"`
+}
+
+// Fakes keeps simulated alerts, which the alerts job merges with the real ones flagged as simulated.
 type Fakes struct {
-	alerts chan types2.Alert
+	log          *zap.SugaredLogger
+	scenariosDir string
+	now          func() time.Time
+
+	mu *sync.Mutex
+	// alerts maps areas to the time the simulated alert ends at
+	alerts map[string]time.Time
+	plays  *scenarioPlays
+}
+
+// scenarioPlays tracks the scenarios being played, which run under the context of the alerts job.
+type scenarioPlays struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	stopping bool
+	wg       sync.WaitGroup
 }
 
-func NewFakes() Fakes {
+func NewFakes(log *zap.SugaredLogger, config types.Config) Fakes {
 	return Fakes{
-		alerts: make(chan types2.Alert, 1),
+		log:          log,
+		scenariosDir: config.FakeScenariosDir,
+		now:          time.Now,
+		mu:           &sync.Mutex{},
+		alerts:       map[string]time.Time{},
+		plays:        &scenarioPlays{ctx: context.Background()},
 	}
 }
 
-func (f Fakes) FakeAlert(_ context.Context, args string) error {
-	select {
-	case f.alerts <- types2.Alert{ID: args}:
-	default:
-		return errors.New("channel busy")
+// FakeAlert starts simulated alerts in the areas, extending the ones already going on.
+func (f Fakes) FakeAlert(_ context.Context, areas []string, duration time.Duration) error {
+	if len(areas) == 0 {
+		return errors.New("no areas")
+	}
+
+	if duration <= 0 {
+		duration = DefaultFakeAlertDuration
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	until := f.now().Add(duration)
+	for _, area := range areas {
+		f.alerts[area] = until
 	}
 
 	return nil
 }
 
-func (f Fakes) Alert(context.Context) (types2.Alert, bool) {
-	select {
-	case alert := <-f.alerts:
-		return alert, true
-	default:
-		return types2.Alert{}, false
+// FakeAllClear ends simulated alerts in the areas, or all of them if none are given. It returns the ended areas.
+func (f Fakes) FakeAllClear(_ context.Context, areas []string) types.Stringies {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ended types.Stringies
+
+	for area := range f.alerts {
+		if len(areas) == 0 || types.Stringies(areas).Contains(area) {
+			delete(f.alerts, area)
+			ended = append(ended, area)
+		}
 	}
+
+	return ended.Sort()
+}
+
+// Alerts returns simulated alerts going on, dropping the expired ones.
+func (f Fakes) Alerts(context.Context) types2.Alerts {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+
+	var out types2.Alerts
+
+	for area, until := range f.alerts {
+		if !now.Before(until) {
+			delete(f.alerts, area)
+
+			continue
+		}
+
+		out = append(out, types2.Alert{ID: area, Simulated: true})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out
+}
+
+// LoadScenario reads <name>.yaml, <name>.yml or <name>.json from the scenarios dir.
+func (f Fakes) LoadScenario(name string) (FakeScenario, error) {
+	if len(f.scenariosDir) == 0 {
+		return FakeScenario{}, ErrNoScenarios
+	}
+
+	if name != filepath.Base(name) {
+		return FakeScenario{}, fmt.Errorf("bad scenario name %s", name)
+	}
+
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		bts, err := os.ReadFile(filepath.Join(f.scenariosDir, name+ext))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return FakeScenario{}, fmt.Errorf("read: %w", err)
+		}
+
+		// JSON is valid YAML, one decoder does for both
+		var scenario FakeScenario
+		if err := yaml.Unmarshal(bts, &scenario); err != nil {
+			return FakeScenario{}, fmt.Errorf("unmarshal %s: %w", name+ext, err)
+		}
+
+		if len(scenario.Name) == 0 {
+			scenario.Name = name
+		}
+
+		sort.SliceStable(scenario.Events, func(i, j int) bool { return scenario.Events[i].At < scenario.Events[j].At })
+
+		return scenario, nil
+	}
+
+	return FakeScenario{}, fmt.Errorf("scenario %s not found", name)
+}
+
+// Start sets the context scenarios play under from now on: when it ends they stop before their next event.
+func (f Fakes) Start(ctx context.Context) {
+	f.plays.mu.Lock()
+	f.plays.ctx = ctx
+	f.plays.mu.Unlock()
+}
+
+// Wait blocks until the scenarios being played stop; the ones asked for after it is called are refused.
+func (f Fakes) Wait() {
+	f.plays.mu.Lock()
+	f.plays.stopping = true
+	f.plays.mu.Unlock()
+
+	f.plays.wg.Wait()
+}
+
+// Play runs the scenario in the background until it ends or the context given to Start is done.
+func (f Fakes) Play(scenario FakeScenario) error {
+	f.plays.mu.Lock()
+	defer f.plays.mu.Unlock()
+
+	if f.plays.stopping {
+		return ErrStopping
+	}
+
+	f.plays.wg.Add(1)
+
+	go func(ctx context.Context) {
+		defer f.plays.wg.Done()
+
+		started := f.now()
+
+		for _, event := range scenario.Events {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(started.Add(event.At))):
+			}
+
+			if len(event.Start) > 0 {
+				duration := event.For
+				if duration <= 0 {
+					// until ended by a later event
+					duration = 24 * time.Hour
+				}
+
+				if err := f.FakeAlert(ctx, event.Start, duration); err != nil {
+					f.log.Errorw("scenario fake alert", "scenario", scenario.Name, "err", err)
+				}
+			}
+
+			if len(event.End) > 0 {
+				f.FakeAllClear(ctx, event.End)
+			}
+
+			f.log.Infow("scenario event", "scenario", scenario.Name, "at", event.At, "start", event.Start, "end", event.End)
+		}
+	}(f.plays.ctx)
+
+	return nil
 }
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"closealerts/app/types"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+)
+
+//go:generate go run ../cmd/geo-raions -dir geo
+
+// areasGeoJSON has simplified oblast borders, with Kyiv and Sevastopol as areas of their own. Hromada borders
+// are too heavy to bundle; they come from the file named by the config.
+//
+//go:embed geo/areas.geojson
+var areasGeoJSON []byte
+
+// raionsGeoJSON has approximate raion borders, generated out of the oblast ones by cmd/geo-raions.
+//
+//go:embed geo/raions.geojson
+var raionsGeoJSON []byte
+
+const (
+	GeoLevelOblast  = "oblast"
+	GeoLevelRaion   = "raion"
+	GeoLevelHromada = "hromada"
+
+	earthRadiusKm = 6371.0
+)
+
+type GeoArea struct {
+	Name   string
+	Level  string
+	Parent string
+
+	polygons [][][][2]float64
+	// size is in square degrees, only to compare areas containing the same point
+	size float64
+}
+
+type GeoAreas []GeoArea
+
+func (r GeoAreas) Names() types.Stringies {
+	if len(r) == 0 {
+		return nil
+	}
+
+	names := make(types.Stringies, 0, len(r))
+	for _, area := range r {
+		names = append(names, area.Name)
+	}
+
+	return names
+}
+
+// Locator finds the areas around shared locations; Geo implements it.
+type Locator interface {
+	Locate(lat, lon float64) GeoAreas
+	Around(lat, lon float64, level string, radiusKm float64) GeoAreas
+	ByKey(key string) (string, bool)
+}
+
+type Geo struct {
+	areas GeoAreas
+	// keys map short keys of area names, which fit into callback data, to the names
+	keys   map[string]string
+	levels map[string]string
+}
+
+type geoJSONCollection struct {
+	Features []struct {
+		Properties struct {
+			Name   string `json:"name"`
+			Level  string `json:"level"`
+			Parent string `json:"parent"`
+		} `json:"properties"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// NewGeo loads the bundled oblast and raion borders and, when the config names a GeoJSON file, the areas
+// from it. Areas of the file replace the bundled ones of the same name and level; the rest, like hromadas,
+// are added.
+func NewGeo(config types.Config) (Geo, error) {
+	areas, err := parseGeoAreas(areasGeoJSON)
+	if err != nil {
+		return Geo{}, fmt.Errorf("bundled areas: %w", err)
+	}
+
+	raions, err := parseGeoAreas(raionsGeoJSON)
+	if err != nil {
+		return Geo{}, fmt.Errorf("bundled raions: %w", err)
+	}
+
+	areas = append(areas, raions...)
+
+	if len(config.GeoAreasFile) == 0 {
+		return newGeo(areas)
+	}
+
+	bts, err := os.ReadFile(config.GeoAreasFile)
+	if err != nil {
+		return Geo{}, fmt.Errorf("read file: %w", err)
+	}
+
+	extra, err := parseGeoAreas(bts)
+	if err != nil {
+		return Geo{}, fmt.Errorf("%s: %w", config.GeoAreasFile, err)
+	}
+
+	index := make(map[[2]string]int, len(areas))
+	for i, area := range areas {
+		index[[2]string{area.Name, area.Level}] = i
+	}
+
+	for _, area := range extra {
+		if i, ok := index[[2]string{area.Name, area.Level}]; ok {
+			areas[i] = area
+
+			continue
+		}
+
+		areas = append(areas, area)
+	}
+
+	return newGeo(areas)
+}
+
+func newGeo(areas GeoAreas) (Geo, error) {
+	keys := make(map[string]string, len(areas))
+	levels := make(map[string]string, len(areas))
+
+	for _, area := range areas {
+		if _, ok := levels[area.Name]; !ok {
+			levels[area.Name] = area.Level
+		}
+
+		key := GeoKey(area.Name)
+		if name, ok := keys[key]; ok && name != area.Name {
+			return Geo{}, fmt.Errorf("%s and %s have the same key %s", name, area.Name, key)
+		}
+
+		keys[key] = area.Name
+	}
+
+	return Geo{areas: areas, keys: keys, levels: levels}, nil
+}
+
+// GeoKey is a short key of the area name, for callback data, which Telegram limits to 64 bytes.
+func GeoKey(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// ByKey returns the name of the known area with the key.
+func (r Geo) ByKey(key string) (string, bool) {
+	name, ok := r.keys[key]
+
+	return name, ok
+}
+
+// Level tells the level of the known area, or nothing for areas without borders.
+func (r Geo) Level(name string) string {
+	return r.levels[name]
+}
+
+func parseGeoAreas(bts []byte) (GeoAreas, error) {
+	var collection geoJSONCollection
+	if err := json.Unmarshal(bts, &collection); err != nil {
+		return nil, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	areas := make(GeoAreas, 0, len(collection.Features))
+
+	for _, feature := range collection.Features {
+		area := GeoArea{
+			Name:   feature.Properties.Name,
+			Level:  feature.Properties.Level,
+			Parent: feature.Properties.Parent,
+		}
+
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("polygon %s: %w", area.Name, err)
+			}
+
+			area.polygons = [][][][2]float64{polygon}
+
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &area.polygons); err != nil {
+				return nil, fmt.Errorf("multipolygon %s: %w", area.Name, err)
+			}
+
+		default:
+			return nil, fmt.Errorf("%s: unsupported geometry %s", area.Name, feature.Geometry.Type)
+		}
+
+		if levelRank(area.Level) > levelRank(GeoLevelHromada) {
+			return nil, fmt.Errorf("%s: unknown level %s", area.Name, area.Level)
+		}
+
+		area.size = area.measure()
+
+		areas = append(areas, area)
+	}
+
+	return areas, nil
+}
+
+// Locate returns the areas containing the point, at most one per level, ordered from the widest one.
+// Of the areas of a level containing the point the smallest one is taken, as cities lie within the oblasts
+// around them.
+func (r Geo) Locate(lat, lon float64) GeoAreas {
+	found := map[string]GeoArea{}
+
+	for _, area := range r.areas {
+		if current, ok := found[area.Level]; ok && current.size <= area.size {
+			continue
+		}
+
+		if area.contains(lat, lon) {
+			found[area.Level] = area
+		}
+	}
+
+	out := make(GeoAreas, 0, len(found))
+	for _, area := range found {
+		out = append(out, area)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return levelRank(out[i].Level) < levelRank(out[j].Level) })
+
+	return out
+}
+
+// Around returns the areas of the given level closer than radiusKm to the point, except the ones
+// Locate gives for it. Closest areas go first.
+func (r Geo) Around(lat, lon float64, level string, radiusKm float64) GeoAreas {
+	type areaDistance struct {
+		area     GeoArea
+		distance float64
+	}
+
+	located := r.Locate(lat, lon).Names()
+
+	var found []areaDistance
+
+	for _, area := range r.areas {
+		if area.Level != level || located.Contains(area.Name) {
+			continue
+		}
+
+		distance := 0.0
+		if !area.contains(lat, lon) {
+			distance = area.distanceKm(lat, lon)
+		}
+
+		if distance <= radiusKm {
+			found = append(found, areaDistance{area: area, distance: distance})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+
+	out := make(GeoAreas, 0, len(found))
+	for _, item := range found {
+		out = append(out, item.area)
+	}
+
+	return out
+}
+
+func levelRank(level string) int {
+	switch level {
+	case GeoLevelOblast:
+		return 0
+	case GeoLevelRaion:
+		return 1
+	case GeoLevelHromada:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func (r GeoArea) contains(lat, lon float64) bool {
+	for _, polygon := range r.polygons {
+		if len(polygon) == 0 || !ringContains(polygon[0], lat, lon) {
+			continue
+		}
+
+		inHole := false
+
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, lat, lon) {
+				inHole = true
+
+				break
+			}
+		}
+
+		if !inHole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// measure sums the shoelace areas of the outer rings, less the holes.
+func (r GeoArea) measure() float64 {
+	var size float64
+
+	for _, polygon := range r.polygons {
+		for i, ring := range polygon {
+			ringSize := 0.0
+			for j := 1; j < len(ring); j++ {
+				ringSize += ring[j-1][0]*ring[j][1] - ring[j][0]*ring[j-1][1]
+			}
+
+			if i == 0 {
+				size += math.Abs(ringSize) / 2
+			} else {
+				size -= math.Abs(ringSize) / 2
+			}
+		}
+	}
+
+	return size
+}
+
+func (r GeoArea) distanceKm(lat, lon float64) float64 {
+	min := math.Inf(1)
+
+	for _, polygon := range r.polygons {
+		for _, ring := range polygon {
+			for i := 1; i < len(ring); i++ {
+				if d := segmentDistanceKm(lat, lon, ring[i-1], ring[i]); d < min {
+					min = d
+				}
+			}
+		}
+	}
+
+	return min
+}
+
+// ringContains does the ray casting; ring points are [lon, lat] as GeoJSON has them.
+func ringContains(ring [][2]float64, lat, lon float64) bool {
+	inside := false
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// segmentDistanceKm projects points onto a plane around the given point, which is precise enough
+// at the scale of a few hundred kilometers.
+func segmentDistanceKm(lat, lon float64, a, b [2]float64) float64 {
+	kx := earthRadiusKm * math.Pi / 180 * math.Cos(lat*math.Pi/180)
+	ky := earthRadiusKm * math.Pi / 180
+
+	ax, ay := (a[0]-lon)*kx, (a[1]-lat)*ky
+	bx, by := (b[0]-lon)*kx, (b[1]-lat)*ky
+	dx, dy := bx-ax, by-ay
+
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
@@ -0,0 +1,59 @@
+package services_test
+
+import (
+	"closealerts/app/services"
+	"closealerts/app/types"
+	"reflect"
+	"testing"
+)
+
+func TestGeoLocate(t *testing.T) {
+	geo, err := services.NewGeo(types.Config{})
+	if err != nil {
+		t.Fatalf("new geo: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     types.Stringies
+	}{
+		{name: "oblast and raion", lat: 49.84, lon: 24.03, want: types.Stringies{"Львівська", "Львівський"}},
+		{name: "Kyiv is not in the oblast around it", lat: 50.45, lon: 30.52, want: types.Stringies{"м. Київ"}},
+		{name: "Sevastopol is not in Crimea", lat: 44.6, lon: 33.52, want: types.Stringies{"м. Севастополь"}},
+		{name: "Crimea", lat: 44.95, lon: 34.1, want: types.Stringies{"Автономна Республіка Крим", "Сімферопольський"}},
+		{name: "out of the country", lat: 52.23, lon: 21.01, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := geo.Locate(tt.lat, tt.lon).Names(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Locate(%v, %v) = %q, want %q", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoByKey(t *testing.T) {
+	geo, err := services.NewGeo(types.Config{})
+	if err != nil {
+		t.Fatalf("new geo: %v", err)
+	}
+
+	for _, area := range []string{"Автономна Республіка Крим", "Білгород-Дністровський", "Кам'янець-Подільський"} {
+		key := services.GeoKey(area)
+
+		// callback data are limited to 64 bytes
+		if len("toggle_loc:"+key) > 64 {
+			t.Errorf("%s: key %s is too long", area, key)
+		}
+
+		if got, ok := geo.ByKey(key); !ok || got != area {
+			t.Errorf("ByKey(%s) = %q, %v, want %q", key, got, ok, area)
+		}
+	}
+
+	if got, ok := geo.ByKey("00000000"); ok {
+		t.Errorf("ByKey of an unknown key = %q", got)
+	}
+}
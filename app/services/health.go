@@ -0,0 +1,182 @@
+package services
+
+import (
+	"closealerts/app/clients"
+	"closealerts/app/types"
+	"context"
+	"sync"
+	"time"
+)
+
+// Webhook reports the state of the Telegram webhook; clients.Telegram implements it.
+type Webhook interface {
+	WebhookStatus(ctx context.Context) (clients.WebhookStatus, error)
+}
+
+// Health keeps track of alert ticks and sources for the readiness probe.
+type Health struct {
+	db         clients.DB
+	webhook    Webhook
+	staleAfter time.Duration
+	now        func() time.Time
+	state      *healthState
+}
+
+type healthState struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	lastTick  time.Time
+	tickErr   string
+	source    string
+	sources   map[string]time.Time
+}
+
+type HealthReport struct {
+	Ready   bool            `json:"ready"`
+	Tick    TickHealth      `json:"tick"`
+	Sources SourcesHealth   `json:"sources"`
+	DB      ComponentHealth `json:"db"`
+	Webhook WebhookHealth   `json:"webhook"`
+}
+
+type ComponentHealth struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type TickHealth struct {
+	OK          bool       `json:"ok"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Age         string     `json:"age"`
+	StaleAfter  string     `json:"stale_after"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+type SourcesHealth struct {
+	Active      string               `json:"active,omitempty"`
+	SinceActive string               `json:"since_active,omitempty"`
+	LastSuccess map[string]time.Time `json:"last_success"`
+}
+
+type WebhookHealth struct {
+	ComponentHealth
+	RegisteredAt       *time.Time `json:"registered_at,omitempty"`
+	URL                string     `json:"url"`
+	PendingUpdateCount int        `json:"pending_update_count"`
+	LastErrorDate      *time.Time `json:"last_error_date,omitempty"`
+	LastErrorMessage   string     `json:"last_error_message,omitempty"`
+}
+
+func NewHealth(db clients.DB, webhook Webhook, config types.Config) Health {
+	return Health{
+		db:         db,
+		webhook:    webhook,
+		staleAfter: config.StaleAfter,
+		now:        time.Now,
+		state:      &healthState{startedAt: time.Now(), sources: map[string]time.Time{}},
+	}
+}
+
+func (r Health) TickSucceeded() {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	r.state.lastTick = r.now()
+	r.state.tickErr = ""
+}
+
+func (r Health) TickFailed(err error) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	r.state.tickErr = err.Error()
+}
+
+// SourceSucceeded marks the source the alerts were last taken from.
+func (r Health) SourceSucceeded(source string) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+
+	r.state.source = source
+	r.state.sources[source] = r.now()
+}
+
+// Report checks the subsystems. The bot is ready while alert data is fresher than the stale threshold,
+// counted from the start before the first tick, and the database answers; webhook problems are only reported,
+// restarting the bot does not fix them.
+func (r Health) Report(ctx context.Context) HealthReport {
+	now := r.now()
+
+	r.state.mu.Lock()
+	since := r.state.startedAt
+	tick := TickHealth{StaleAfter: r.staleAfter.String(), LastError: r.state.tickErr}
+
+	if !r.state.lastTick.IsZero() {
+		lastTick := r.state.lastTick
+		tick.LastSuccess = &lastTick
+		since = lastTick
+	}
+
+	sources := SourcesHealth{Active: r.state.source, LastSuccess: map[string]time.Time{}}
+	for source, at := range r.state.sources {
+		sources.LastSuccess[source] = at
+	}
+
+	if at, ok := r.state.sources[r.state.source]; ok {
+		sources.SinceActive = now.Sub(at).Round(time.Second).String()
+	}
+	r.state.mu.Unlock()
+
+	tick.Age = now.Sub(since).Round(time.Second).String()
+	tick.OK = now.Sub(since) <= r.staleAfter
+
+	report := HealthReport{
+		Tick:    tick,
+		Sources: sources,
+		DB:      r.pingDB(ctx),
+		Webhook: r.webhookHealth(ctx),
+	}
+
+	report.Ready = report.Tick.OK && report.DB.OK
+
+	return report
+}
+
+func (r Health) pingDB(ctx context.Context) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := r.db.Ping(ctx); err != nil {
+		return ComponentHealth{Error: err.Error()}
+	}
+
+	return ComponentHealth{OK: true}
+}
+
+func (r Health) webhookHealth(ctx context.Context) WebhookHealth {
+	status, err := r.webhook.WebhookStatus(ctx)
+
+	var out WebhookHealth
+
+	if !status.RegisteredAt.IsZero() {
+		out.RegisteredAt = &status.RegisteredAt
+	}
+
+	if err != nil {
+		out.Error = err.Error()
+
+		return out
+	}
+
+	out.OK = len(status.URL) > 0
+	out.URL = status.URL
+	out.PendingUpdateCount = status.PendingUpdateCount
+	out.LastErrorMessage = status.LastErrorMessage
+
+	if status.LastErrorDate > 0 {
+		at := time.Unix(int64(status.LastErrorDate), 0)
+		out.LastErrorDate = &at
+	}
+
+	return out
+}
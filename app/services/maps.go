@@ -1,7 +1,7 @@
 package services
 
 import (
-	"closealerts/app/repositories"
+	"closealerts/app/metrics"
 	types2 "closealerts/app/repositories/types"
 	"context"
 	"crypto/md5"
@@ -10,20 +10,28 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// MapStore caches Telegram file IDs of rendered maps by the alerts they show; repositories.Maps implements it.
+// Get fails with gorm.ErrRecordNotFound for maps not rendered yet.
+type MapStore interface {
+	Get(ctx context.Context, alertsKey string) (types2.Map, error)
+	Save(ctx context.Context, key string, fileID string) (types2.Map, error)
+}
+
 type Maps struct {
-	mapz  repositories.Maps
+	mapz  MapStore
 	log   *zap.SugaredLogger
 	alert Alerts
 }
 
 func NewMaps(
 	log *zap.SugaredLogger,
-	mapz repositories.Maps,
+	mapz MapStore,
 ) Maps {
 	return Maps{
 		log:  log,
@@ -46,6 +54,8 @@ func (r Maps) Get(ctx context.Context, alerts types2.Alerts) (bool, types2.Map,
 
 	r.log.Infow("no map for given alerts set yet", "areas", areas)
 
+	started := time.Now()
+
 	hash := md5.New()
 	hash.Write([]byte(alertsKey))
 	out := hash.Sum(nil)
@@ -88,6 +98,8 @@ func (r Maps) Get(ctx context.Context, alerts types2.Alerts) (bool, types2.Map,
 		return false, mapz, nil, fmt.Errorf("remove %s: %w", filenamePNG, err)
 	}
 
+	metrics.MapRenderDuration.Observe(time.Since(started).Seconds())
+
 	return false, mapz, bts, nil
 }
 
@@ -110,12 +122,14 @@ func (r Maps) Exists(ctx context.Context, alerts types2.Alerts) (types2.Map, boo
 	mapz, err := r.mapz.Get(ctx, alertsKey)
 	if err == nil {
 		r.log.Debugw("map existence", "exists", true)
+		metrics.MapCache.WithLabelValues("hit").Inc()
 
 		return mapz, true, nil
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		r.log.Debugw("map existence", "exists", false)
+		metrics.MapCache.WithLabelValues("miss").Inc()
 
 		return mapz, false, nil
 	}
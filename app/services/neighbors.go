@@ -0,0 +1,115 @@
+package services
+
+import (
+	"closealerts/app/types"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// neighborsJSON links the oblasts, Kyiv and Sevastopol.
+//
+//go:embed geo/neighbors.json
+var neighborsJSON []byte
+
+// raionNeighborsJSON links the raions whose bundled borders touch, generated by cmd/geo-raions along with them.
+//
+//go:embed geo/raion_neighbors.json
+var raionNeighborsJSON []byte
+
+// Neighbors is an adjacency graph of areas as they are named by the alert sources: the bundled oblasts and
+// raions and, when the config names a file, the areas from it, like hromadas.
+type Neighbors struct {
+	graph map[string]types.Stringies
+}
+
+func NewNeighbors(config types.Config) (Neighbors, error) {
+	var adjacency map[string][]string
+	if err := json.Unmarshal(neighborsJSON, &adjacency); err != nil {
+		return Neighbors{}, fmt.Errorf("json unmarshal: %w", err)
+	}
+
+	var raions map[string][]string
+	if err := json.Unmarshal(raionNeighborsJSON, &raions); err != nil {
+		return Neighbors{}, fmt.Errorf("json unmarshal raions: %w", err)
+	}
+
+	for area, neighbors := range raions {
+		adjacency[area] = append(adjacency[area], neighbors...)
+	}
+
+	if len(config.GeoNeighborsFile) > 0 {
+		bts, err := os.ReadFile(config.GeoNeighborsFile)
+		if err != nil {
+			return Neighbors{}, fmt.Errorf("read file: %w", err)
+		}
+
+		var extra map[string][]string
+		if err := json.Unmarshal(bts, &extra); err != nil {
+			return Neighbors{}, fmt.Errorf("json unmarshal %s: %w", config.GeoNeighborsFile, err)
+		}
+
+		for area, neighbors := range extra {
+			adjacency[area] = append(adjacency[area], neighbors...)
+		}
+	}
+
+	graph := make(map[string]types.Stringies, len(adjacency))
+
+	link := func(a, b string) {
+		if !graph[a].Contains(b) {
+			graph[a] = append(graph[a], b)
+		}
+	}
+
+	// the file may list an edge on one side only
+	for area, neighbors := range adjacency {
+		if _, ok := graph[area]; !ok {
+			graph[area] = nil
+		}
+
+		for _, neighbor := range neighbors {
+			link(area, neighbor)
+			link(neighbor, area)
+		}
+	}
+
+	return Neighbors{graph: graph}, nil
+}
+
+func (r Neighbors) Known(area string) bool {
+	_, ok := r.graph[area]
+
+	return ok
+}
+
+// Within returns areas reachable from the given one in up to depth hops, without the area itself.
+// Closer areas go first, the ones at the same distance are sorted by name.
+func (r Neighbors) Within(area string, depth int) types.Stringies {
+	visited := map[string]struct{}{area: {}}
+	frontier := types.Stringies{area}
+
+	var out types.Stringies
+
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next types.Stringies
+
+		for _, current := range frontier {
+			for _, neighbor := range r.graph[current] {
+				if _, ok := visited[neighbor]; ok {
+					continue
+				}
+
+				visited[neighbor] = struct{}{}
+				next = append(next, neighbor)
+			}
+		}
+
+		next = next.Sort()
+		out = append(out, next...)
+		frontier = next
+	}
+
+	return out
+}
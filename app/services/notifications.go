@@ -2,43 +2,129 @@ package services
 
 import (
 	"closealerts/app/clients"
-	"closealerts/app/repositories"
+	"closealerts/app/i18n"
+	"closealerts/app/metrics"
 	types2 "closealerts/app/repositories/types"
+	"closealerts/app/tracing"
+	"closealerts/app/types"
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
+// SubscriptionStore keeps the areas chats track; repositories.Notification implements it.
+type SubscriptionStore interface {
+	Track(ctx context.Context, chatID int64, area string) error
+	TrackNear(ctx context.Context, chatID int64, near string, areas []string) ([]string, error)
+	StopNear(ctx context.Context, chatID int64, near string) error
+	Tracking(ctx context.Context, id int64) ([]types2.Notification, error)
+	ChatIDs(ctx context.Context, areas []string) ([]int64, error)
+	Stop(ctx context.Context, id int64, area string) error
+}
+
+// NotifyStore keeps whether chats were notified about the alerts in the areas they track;
+// repositories.Notification implements it.
+type NotifyStore interface {
+	// Eligible returns subscriptions to notify about the alerts, AlertEnded the ones to notify about all-clear.
+	Eligible(ctx context.Context, alerts []types2.Alert) (types2.Notifications, error)
+	Notified(ctx context.Context, eligible types2.Notification) error
+	Unmark(ctx context.Context, alerts []types2.Alert) error
+	AlertEnded(ctx context.Context, alerts []types2.Alert) (types2.Notifications, error)
+}
+
+// TrackingStore tells the areas a chat tracks, for the services showing them; repositories.Notification
+// implements it.
+type TrackingStore interface {
+	Tracking(ctx context.Context, id int64) ([]types2.Notification, error)
+}
+
+// LanguageStore tells the languages of chats; repositories.Chats implements it.
+type LanguageStore interface {
+	Languages(ctx context.Context, ids []int64) (map[int64]string, error)
+}
+
+// TemplateStore tells the notification templates of chats; repositories.Templates implements it.
+type TemplateStore interface {
+	Templates(ctx context.Context, ids []int64) (types2.ChatTemplates, error)
+}
+
+// StatusStore tells which chats follow a status message instead of getting notifications;
+// repositories.StatusMessages implements it.
+type StatusStore interface {
+	StatusMessages(ctx context.Context, ids []int64) (types2.StatusMessages, error)
+}
+
+// AreaLevels tells whether areas are oblasts, raions or hromadas, for the texts to name them right; Geo
+// implements it.
+type AreaLevels interface {
+	Level(name string) string
+}
+
 type Notification struct {
-	notification repositories.Notification
-	log          *zap.SugaredLogger
-	telegram     clients.Telegram
+	subscriptions SubscriptionStore
+	notification  NotifyStore
+	languages     LanguageStore
+	templates     TemplateStore
+	statuses      StatusStore
+	levels        AreaLevels
+	log           *zap.SugaredLogger
+	telegram      Sender
 }
 
 func NewNotification(
 	log *zap.SugaredLogger,
-	telegram clients.Telegram,
-	notification repositories.Notification,
+	telegram Sender,
+	subscriptions SubscriptionStore,
+	notification NotifyStore,
+	languages LanguageStore,
+	templates TemplateStore,
+	statuses StatusStore,
+	levels AreaLevels,
 ) Notification {
 	return Notification{
-		log:          log,
-		telegram:     telegram,
-		notification: notification,
+		log:           log,
+		telegram:      telegram,
+		subscriptions: subscriptions,
+		notification:  notification,
+		languages:     languages,
+		templates:     templates,
+		statuses:      statuses,
+		levels:        levels,
 	}
 }
 
 func (r Notification) Track(ctx context.Context, chatID int64, area string) error {
-	if err := r.notification.Track(ctx, chatID, area); err != nil {
+	if err := r.subscriptions.Track(ctx, chatID, area); err != nil {
 		return fmt.Errorf("track: %w", err)
 	}
 
 	return nil
 }
 
+func (r Notification) TrackNear(ctx context.Context, chatID int64, near string, areas []string) ([]string, error) {
+	added, err := r.subscriptions.TrackNear(ctx, chatID, near, areas)
+	if err != nil {
+		return nil, fmt.Errorf("track near: %w", err)
+	}
+
+	return added, nil
+}
+
+func (r Notification) StopNear(ctx context.Context, chatID int64, near string) error {
+	if err := r.subscriptions.StopNear(ctx, chatID, near); err != nil {
+		return fmt.Errorf("stop near: %w", err)
+	}
+
+	return nil
+}
+
 func (r Notification) Tracking(ctx context.Context, id int64) (types2.Notifications, error) {
-	list, err := r.notification.Tracking(ctx, id)
+	list, err := r.subscriptions.Tracking(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("tracking: %w", err)
 	}
@@ -46,8 +132,17 @@ func (r Notification) Tracking(ctx context.Context, id int64) (types2.Notificati
 	return list, nil
 }
 
+func (r Notification) ChatIDs(ctx context.Context, areas []string) ([]int64, error) {
+	ids, err := r.subscriptions.ChatIDs(ctx, areas)
+	if err != nil {
+		return nil, fmt.Errorf("chat ids: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (r Notification) Stop(ctx context.Context, id int64, area string) error {
-	if err := r.notification.Stop(ctx, id, area); err != nil {
+	if err := r.subscriptions.Stop(ctx, id, area); err != nil {
 		return fmt.Errorf("stop: %w", err)
 	}
 
@@ -60,14 +155,15 @@ func (r Notification) Notify(ctx context.Context, alerts []types2.Alert) error {
 		return fmt.Errorf("eligible: %w", err)
 	}
 
-	alertsWg := r.notifyAboutAlertsAsync(ctx, eligible)
-
 	endedFor, err := r.notification.AlertEnded(ctx, alerts)
 	if err != nil {
 		return fmt.Errorf("alert ended: %w", err)
 	}
 
-	endedAlertsWg := r.notifyAboutEndedAlertsAsync(ctx, endedFor)
+	settings := r.chatSettings(ctx, append(eligible, endedFor...))
+
+	alertsWg := r.notifyAboutAlertsAsync(ctx, settings, eligible, alerts)
+	endedAlertsWg := r.notifyAboutEndedAlertsAsync(ctx, settings, endedFor)
 
 	if err := r.notification.Unmark(ctx, alerts); err != nil {
 		return fmt.Errorf("unmark: %w", err)
@@ -79,10 +175,61 @@ func (r Notification) Notify(ctx context.Context, alerts []types2.Alert) error {
 	return nil
 }
 
-func (r Notification) notifyAboutAlertsAsync(ctx context.Context, eligible types2.Notifications) *sync.WaitGroup {
+// chatSettings are what the notifications of chats are written with.
+type chatSettings struct {
+	langs     map[int64]string
+	templates types2.ChatTemplates
+	// statuses are of the chats whose status messages show the alerts instead.
+	statuses types2.StatusMessages
+}
+
+// chatSettings returns the languages, the templates and the status messages of the notified chats; without them
+// chats get the default texts, which beats not notifying at all.
+func (r Notification) chatSettings(ctx context.Context, notifications types2.Notifications) chatSettings {
+	if len(notifications) == 0 {
+		return chatSettings{}
+	}
+
+	ids := make([]int64, 0, len(notifications))
+	for chatID := range notifications.GroupByChatID() {
+		ids = append(ids, chatID)
+	}
+
+	langs, err := r.languages.Languages(ctx, ids)
+	if err != nil {
+		r.log.Errorw("chat languages", "err", err)
+	}
+
+	templates, err := r.templates.Templates(ctx, ids)
+	if err != nil {
+		r.log.Errorw("chat templates", "err", err)
+	}
+
+	statuses, err := r.statuses.StatusMessages(ctx, ids)
+	if err != nil {
+		r.log.Errorw("chat status messages", "err", err)
+	}
+
+	return chatSettings{langs: langs, templates: templates, statuses: statuses}
+}
+
+func (r Notification) notifyAboutAlertsAsync(
+	ctx context.Context, settings chatSettings, eligible types2.Notifications, alerts types2.Alerts,
+) *sync.WaitGroup {
 	wg := &sync.WaitGroup{}
+	simulated := alerts.Simulated().Areas()
+
+	alertTypes := make(map[string]string, len(alerts))
+	for _, alert := range alerts {
+		alertTypes[alert.ID] = alert.Type
+	}
+
+	// the spawning goroutine counts itself, for Wait not to return before it has added the chats
+	wg.Add(1)
 
 	go func() {
+		defer wg.Done()
+
 		sf := make(chan struct{}, 10)
 
 		for chatID, notifications := range eligible.GroupByChatID() {
@@ -90,15 +237,25 @@ func (r Notification) notifyAboutAlertsAsync(ctx context.Context, eligible types
 			wg.Add(1)
 
 			go func(chatID int64, notifications types2.Notifications) {
+				ctx, span := tracing.Start(ctx, "notify about alerts",
+					attribute.Int64("chat.id", chatID),
+					attribute.StringSlice("areas", notifications.Areas()),
+				)
+
 				defer func() {
+					span.End()
 					<-sf
 					wg.Done()
 				}()
 
 				r.log.Debugw("notify about alerts", "chat_id", chatID, "areas", notifications.Areas())
-				r.telegram.MaybeSendText(ctx, chatID, notifications.Areas().Join(", ")+": тривога!")
+				// the status message shows the alerts by itself once the subscriptions are marked
+				if !settings.statuses.Has(chatID) {
+					r.sendAlerts(ctx, chatID, settings, notifications, simulated, alertTypes)
+				}
 
 				for _, notification := range notifications {
+					notification.NotifiedSimulated = simulated.Contains(notification.Area)
 					if err := r.notification.Notified(ctx, notification); err != nil {
 						r.log.Errorw("notified", "err", err)
 					}
@@ -110,26 +267,204 @@ func (r Notification) notifyAboutAlertsAsync(ctx context.Context, eligible types
 	return wg
 }
 
-func (r Notification) notifyAboutEndedAlertsAsync(ctx context.Context, endedFor types2.Notifications) *sync.WaitGroup {
+// sendAlerts notifies the chat about the alerts in the areas; testers must not mistake real alerts
+// for simulated ones, so these come separately.
+func (r Notification) sendAlerts(
+	ctx context.Context,
+	chatID int64,
+	settings chatSettings,
+	notifications types2.Notifications,
+	simulated types.Stringies,
+	alertTypes map[string]string,
+) {
+	p := i18n.New(settings.langs[chatID])
+
+	var realAlerts, fake types2.Notifications
+
+	for _, notification := range notifications {
+		if simulated.Contains(notification.Area) {
+			fake = append(fake, notification)
+		} else {
+			realAlerts = append(realAlerts, notification)
+		}
+	}
+
+	if len(realAlerts) > 0 {
+		fallback := p.T("notify.alert", r.labels(p, realAlerts).Join(", "))
+		data := r.alertData(p, realAlerts, alertTypes)
+		r.send(ctx, chatID, "alert", r.compose(chatID, settings, TemplateAlert, "", data, fallback), fallback)
+	}
+
+	if len(fake) > 0 {
+		fallback := p.T("notify.test", r.labels(p, fake).Join(", "))
+		data := r.alertData(p, fake, alertTypes)
+		r.send(ctx, chatID, "test_alert", r.compose(chatID, settings, TemplateAlert, "🧪 ", data, fallback), fallback)
+	}
+}
+
+func (r Notification) notifyAboutEndedAlertsAsync(
+	ctx context.Context, settings chatSettings, endedFor types2.Notifications,
+) *sync.WaitGroup {
 	wg := &sync.WaitGroup{}
 
+	wg.Add(1)
+
 	go func() {
+		defer wg.Done()
+
 		sf := make(chan struct{}, 10)
 		for chatID, notifications := range endedFor.GroupByChatID() {
 			sf <- struct{}{}
 			wg.Add(1)
 
 			go func(chatID int64, notifications types2.Notifications) {
+				ctx, span := tracing.Start(ctx, "notify about ended alerts",
+					attribute.Int64("chat.id", chatID),
+					attribute.StringSlice("areas", notifications.Areas()),
+				)
+
 				defer func() {
+					span.End()
 					<-sf
 					wg.Done()
 				}()
 
 				r.log.Debugw("notify about ended alerts", "chat_id", chatID, "areas", notifications.Areas())
-				r.telegram.MaybeSendText(ctx, chatID, "відбій: "+notifications.Areas().Join(", "))
+
+				if !settings.statuses.Has(chatID) {
+					r.sendAllClears(ctx, chatID, settings, notifications)
+				}
 			}(chatID, notifications)
 		}
 	}()
 
 	return wg
 }
+
+// sendAllClears notifies the chat the alerts in the areas have ended; like the alerts, the ended simulated ones
+// come separately, for testers not to take them for a real all-clear.
+func (r Notification) sendAllClears(
+	ctx context.Context, chatID int64, settings chatSettings, notifications types2.Notifications,
+) {
+	p := i18n.New(settings.langs[chatID])
+
+	var realAlerts, fake types2.Notifications
+
+	for _, notification := range notifications {
+		if notification.NotifiedSimulated {
+			fake = append(fake, notification)
+		} else {
+			realAlerts = append(realAlerts, notification)
+		}
+	}
+
+	if len(realAlerts) > 0 {
+		fallback := p.T("notify.all_clear", r.labels(p, realAlerts).Join(", "))
+		data := r.allClearData(p, realAlerts)
+		r.send(ctx, chatID, "all_clear", r.compose(chatID, settings, TemplateAllClear, "", data, fallback), fallback)
+	}
+
+	if len(fake) > 0 {
+		fallback := p.T("notify.test_all_clear", r.labels(p, fake).Join(", "))
+		data := r.allClearData(p, fake)
+		r.send(ctx, chatID, "test_all_clear", r.compose(chatID, settings, TemplateAllClear, "🧪 ", data, fallback), fallback)
+	}
+}
+
+// compose writes the notification with the chat's template of the kind, prefixed, or with the fallback text
+// if the chat has no template or it does not render.
+func (r Notification) compose(
+	chatID int64, settings chatSettings, kind, prefix string, data TemplateData, fallback string,
+) tgbotapi.MessageConfig {
+	chatTemplate, ok := settings.templates.Of(chatID, kind)
+	if !ok {
+		return tgbotapi.NewMessage(chatID, fallback)
+	}
+
+	text, parseMode, err := RenderTemplate(chatTemplate, data)
+	if err != nil {
+		r.log.Errorw("render template", "chat_id", chatID, "kind", kind, "err", err)
+
+		return tgbotapi.NewMessage(chatID, fallback)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, prefix+text)
+	msg.ParseMode = parseMode
+
+	return msg
+}
+
+// send delivers a notification of the kind, counting it as sent or failed. Templates are validated when saved,
+// but should Telegram still reject a templated one, the chat gets the fallback text rather than nothing.
+func (r Notification) send(ctx context.Context, chatID int64, kind string, msg tgbotapi.MessageConfig, fallback string) {
+	_, err := r.telegram.Send(ctx, msg)
+	if err != nil && msg.Text != fallback && !clients.IsBlocked(err) {
+		r.log.Warnw("send templated notification", "chat_id", chatID, "kind", kind, "err", err)
+
+		_, err = r.telegram.Send(ctx, tgbotapi.NewMessage(chatID, fallback))
+	}
+
+	if err != nil {
+		metrics.Notifications.WithLabelValues(kind, "failed").Inc()
+		r.log.Errorw("send notification", "chat_id", chatID, "kind", kind, "err", err)
+
+		return
+	}
+
+	metrics.Notifications.WithLabelValues(kind, "sent").Inc()
+}
+
+func (r Notification) alertData(p i18n.Printer, notifications types2.Notifications, alertTypes map[string]string) TemplateData {
+	areaTypes := make([]string, 0, len(notifications))
+	for _, notification := range notifications {
+		areaTypes = append(areaTypes, alertTypes[notification.Area])
+	}
+
+	return TemplateData{
+		Areas: r.labels(p, notifications).Join(", "),
+		Count: len(notifications),
+		Type:  AreaTypes(p, areaTypes),
+		Start: time.Now().In(ScheduleLocation).Format("15:04"),
+	}
+}
+
+// allClearData tells when the alert started by the first notification about it; chats notified before
+// the time was kept get neither the start nor the duration.
+func (r Notification) allClearData(p i18n.Printer, notifications types2.Notifications) TemplateData {
+	data := TemplateData{Areas: r.labels(p, notifications).Join(", "), Count: len(notifications)}
+
+	if since, ok := notifications.NotifiedSince(); ok {
+		data.Start, data.Duration = since.In(ScheduleLocation).Format("15:04"), FormatDuration(p, time.Since(since))
+	}
+
+	return data
+}
+
+// labels names the areas for the notification text, pointing out the ones tracked as neighbors by their level;
+// areas without borders, named by the neighbors file only, are just said to be near.
+func (r Notification) labels(p i18n.Printer, notifications types2.Notifications) types.Stringies {
+	out := make(types.Stringies, 0, len(notifications))
+
+	for _, notification := range notifications {
+		if len(notification.Near) == 0 || notification.Near == notification.Area {
+			out = append(out, p.Area(notification.Area))
+
+			continue
+		}
+
+		key := "notify.near"
+
+		switch r.levels.Level(notification.Area) {
+		case GeoLevelOblast:
+			key = "notify.near_oblast"
+		case GeoLevelRaion:
+			key = "notify.near_raion"
+		case GeoLevelHromada:
+			key = "notify.near_hromada"
+		}
+
+		out = append(out, p.T(key, p.Area(notification.Area), p.Area(notification.Near)))
+	}
+
+	return out
+}
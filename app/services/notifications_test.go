@@ -0,0 +1,198 @@
+package services_test
+
+import (
+	"closealerts/app/fakes"
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/services"
+	"closealerts/app/types"
+	"context"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestNotificationNotify feeds the alerts of consecutive ticks to Notify and checks what every chat gets
+// after each of them.
+func TestNotificationNotify(t *testing.T) {
+	p := i18n.New(i18n.UK)
+	levels := fakes.AreaLevels{"Житомирська": services.GeoLevelOblast, "Бучанський": services.GeoLevelRaion}
+
+	alertsIn := func(areas ...string) []types2.Alert {
+		out := make([]types2.Alert, 0, len(areas))
+		for _, area := range areas {
+			out = append(out, types2.Alert{ID: area, Type: "o"})
+		}
+
+		return out
+	}
+
+	simulatedIn := func(areas ...string) []types2.Alert {
+		out := alertsIn(areas...)
+		for i := range out {
+			out[i].Simulated = true
+		}
+
+		return out
+	}
+
+	type tick struct {
+		alerts []types2.Alert
+		// want are the texts sent on the tick by chat; chats missing must get nothing.
+		want map[int64]types.Stringies
+	}
+
+	tests := []struct {
+		name     string
+		track    map[int64][]string
+		near     map[int64][2]string
+		testers  map[int64]bool
+		statuses types2.StatusMessages
+		ticks    []tick
+	}{
+		{
+			name:  "alert once, then all-clear",
+			track: map[int64][]string{1: {"Київська"}},
+			ticks: []tick{
+				{alerts: alertsIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.alert", "Київська")}}},
+				{alerts: alertsIn("Київська")},
+				{alerts: nil, want: map[int64]types.Stringies{1: {p.T("notify.all_clear", "Київська")}}},
+				{alerts: nil},
+			},
+		},
+		{
+			name:  "only the tracked areas",
+			track: map[int64][]string{1: {"Київська"}, 2: {"Львівська"}},
+			ticks: []tick{
+				{alerts: alertsIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.alert", "Київська")}}},
+				{alerts: alertsIn("Київська", "Львівська"), want: map[int64]types.Stringies{
+					2: {p.T("notify.alert", "Львівська")},
+				}},
+				{alerts: alertsIn("Львівська"), want: map[int64]types.Stringies{1: {p.T("notify.all_clear", "Київська")}}},
+			},
+		},
+		{
+			name:  "areas starting together come in one message",
+			track: map[int64][]string{1: {"Київська", "Львівська"}},
+			ticks: []tick{
+				{alerts: alertsIn("Київська", "Львівська"), want: map[int64]types.Stringies{
+					1: {p.T("notify.alert", "Київська, Львівська")},
+				}},
+			},
+		},
+		{
+			name: "areas tracked near another one",
+			near: map[int64][2]string{1: {"Житомирська", "Київська"}},
+			ticks: []tick{
+				{alerts: alertsIn("Житомирська"), want: map[int64]types.Stringies{
+					1: {p.T("notify.alert", p.T("notify.near_oblast", "Житомирська", "Київська"))},
+				}},
+				{alerts: nil, want: map[int64]types.Stringies{
+					1: {p.T("notify.all_clear", p.T("notify.near_oblast", "Житомирська", "Київська"))},
+				}},
+			},
+		},
+		{
+			name: "neighbors are named by their level",
+			near: map[int64][2]string{1: {"Бучанський", "Вишгородський"}, 2: {"Ірпінська", "Бучанська"}},
+			ticks: []tick{
+				{alerts: alertsIn("Бучанський", "Ірпінська"), want: map[int64]types.Stringies{
+					1: {p.T("notify.alert", p.T("notify.near_raion", "Бучанський", "Вишгородський"))},
+					// areas known from the neighbors file only have no level
+					2: {p.T("notify.alert", p.T("notify.near", "Ірпінська", "Бучанська"))},
+				}},
+			},
+		},
+		{
+			name:    "simulated alerts reach testers only",
+			track:   map[int64][]string{1: {"Київська"}, 2: {"Київська"}},
+			testers: map[int64]bool{1: true},
+			ticks: []tick{
+				{alerts: simulatedIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.test", "Київська")}}},
+				{alerts: nil, want: map[int64]types.Stringies{1: {p.T("notify.test_all_clear", "Київська")}}},
+			},
+		},
+		{
+			name:    "a real alert replaces a simulated one",
+			track:   map[int64][]string{1: {"Київська"}},
+			testers: map[int64]bool{1: true},
+			ticks: []tick{
+				{alerts: simulatedIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.test", "Київська")}}},
+				{alerts: alertsIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.alert", "Київська")}}},
+				{alerts: alertsIn("Київська")},
+				{alerts: nil, want: map[int64]types.Stringies{1: {p.T("notify.all_clear", "Київська")}}},
+			},
+		},
+		{
+			name:     "chats with a status message are not notified",
+			track:    map[int64][]string{1: {"Київська"}, 2: {"Київська"}},
+			statuses: types2.StatusMessages{{ChatID: 2}},
+			ticks: []tick{
+				{alerts: alertsIn("Київська"), want: map[int64]types.Stringies{1: {p.T("notify.alert", "Київська")}}},
+				{alerts: nil, want: map[int64]types.Stringies{1: {p.T("notify.all_clear", "Київська")}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := &fakes.SubscriptionStore{Testers: tt.testers}
+			sender := &fakes.Sender{}
+
+			chatIDs := map[int64]struct{}{}
+
+			for chatID, areas := range tt.track {
+				chatIDs[chatID] = struct{}{}
+
+				for _, area := range areas {
+					if err := store.Track(ctx, chatID, area); err != nil {
+						t.Fatalf("track: %v", err)
+					}
+				}
+			}
+
+			for chatID, near := range tt.near {
+				chatIDs[chatID] = struct{}{}
+
+				if _, err := store.TrackNear(ctx, chatID, near[1], near[:1]); err != nil {
+					t.Fatalf("track near: %v", err)
+				}
+			}
+
+			notification := services.NewNotification(
+				zap.NewNop().Sugar(),
+				sender,
+				store,
+				store,
+				fakes.ChatLanguages(nil),
+				fakes.ChatTemplates(nil),
+				fakes.StatusMessages(tt.statuses),
+				levels,
+			)
+
+			seen := map[int64]int{}
+
+			for i, tick := range tt.ticks {
+				if err := notification.Notify(ctx, tick.alerts); err != nil {
+					t.Fatalf("tick %d: notify: %v", i, err)
+				}
+
+				for chatID := range chatIDs {
+					texts := sender.Texts(chatID)
+					got := texts[seen[chatID]:]
+					seen[chatID] = len(texts)
+
+					if len(got) == 0 && len(tick.want[chatID]) == 0 {
+						continue
+					}
+
+					if !reflect.DeepEqual(got, tick.want[chatID]) {
+						t.Errorf("tick %d: chat %d got %q, want %q", i, chatID, got, tick.want[chatID])
+					}
+				}
+			}
+		})
+	}
+}
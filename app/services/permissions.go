@@ -0,0 +1,124 @@
+package services
+
+import (
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+)
+
+// PermissionStore keeps the permissions granted to users; repositories.Permissions implements it.
+type PermissionStore interface {
+	Grant(ctx context.Context, permission types2.Permission) error
+	Revoke(ctx context.Context, userID int64, permission string) (bool, error)
+	ByUserID(ctx context.Context, userID int64) (types2.Permissions, error)
+	All(ctx context.Context) (types2.Permissions, error)
+}
+
+type Permissions struct {
+	permission PermissionStore
+	bootstrap  map[int64]struct{}
+}
+
+func NewPermissions(config types.Config, permission PermissionStore) Permissions {
+	bootstrap := make(map[int64]struct{}, len(config.AdminUserIDs))
+	for _, id := range config.AdminUserIDs {
+		bootstrap[id] = struct{}{}
+	}
+
+	return Permissions{permission: permission, bootstrap: bootstrap}
+}
+
+// Bootstrap tells if the user is an admin by the config, rather than by a grant.
+func (r Permissions) Bootstrap(userID int64) bool {
+	_, ok := r.bootstrap[userID]
+
+	return ok
+}
+
+func (r Permissions) BootstrapIDs() []int64 {
+	ids := make([]int64, 0, len(r.bootstrap))
+	for id := range r.bootstrap {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Of lists the permissions the user has; admins have all of them.
+func (r Permissions) Of(ctx context.Context, userID int64) (types.Stringies, error) {
+	if r.Bootstrap(userID) {
+		return types.Permissions, nil
+	}
+
+	list, err := r.permission.ByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("by user id: %w", err)
+	}
+
+	var out types.Stringies
+
+	for _, permission := range list {
+		if permission.Permission == types.PermAdmin {
+			return types.Permissions, nil
+		}
+
+		out = append(out, permission.Permission)
+	}
+
+	return out, nil
+}
+
+func (r Permissions) Has(ctx context.Context, userID int64, permission string) (bool, error) {
+	list, err := r.Of(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("of: %w", err)
+	}
+
+	return list.Contains(permission), nil
+}
+
+// Admins lists the users with all permissions: the bootstrap ones and those granted admin.
+func (r Permissions) Admins(ctx context.Context) ([]int64, error) {
+	list, err := r.permission.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("all: %w", err)
+	}
+
+	ids := r.BootstrapIDs()
+
+	for _, permission := range list {
+		if permission.Permission == types.PermAdmin && !r.Bootstrap(permission.UserID) {
+			ids = append(ids, permission.UserID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r Permissions) Grant(ctx context.Context, userID int64, permission string, grantedBy int64) error {
+	err := r.permission.Grant(ctx, types2.Permission{UserID: userID, Permission: permission, GrantedBy: grantedBy})
+	if err != nil {
+		return fmt.Errorf("grant: %w", err)
+	}
+
+	return nil
+}
+
+func (r Permissions) Revoke(ctx context.Context, userID int64, permission string) (bool, error) {
+	ok, err := r.permission.Revoke(ctx, userID, permission)
+	if err != nil {
+		return false, fmt.Errorf("revoke: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (r Permissions) All(ctx context.Context) (types2.Permissions, error) {
+	list, err := r.permission.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("all: %w", err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // the scheduling timezone must not depend on the host
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// ScheduleTimeLayout is how admins enter one-off broadcast times, in the ScheduleLocation.
+const ScheduleTimeLayout = "2006-01-02 15:04"
+
+// ScheduleLocation is Kyiv time. The zone was renamed to Europe/Kyiv in tzdata 2022b, and neither older hosts
+// nor the tzdata of older Go versions know the new name.
+var ScheduleLocation = mustLoadLocation("Europe/Kyiv", "Europe/Kiev")
+
+// mustLoadLocation loads the first of the names known.
+func mustLoadLocation(names ...string) *time.Location {
+	var err error
+
+	for _, name := range names {
+		var loc *time.Location
+		if loc, err = time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+
+	panic(err)
+}
+
+// ScheduledBroadcastStore keeps the broadcasts scheduled by admins; repositories.ScheduledBroadcasts implements it.
+type ScheduledBroadcastStore interface {
+	Create(ctx context.Context, scheduled types2.ScheduledBroadcast) (types2.ScheduledBroadcast, error)
+	Save(ctx context.Context, scheduled types2.ScheduledBroadcast) error
+	Active(ctx context.Context) (types2.ScheduledBroadcasts, error)
+	// Due returns the active broadcasts whose time has come by now.
+	Due(ctx context.Context, now time.Time) (types2.ScheduledBroadcasts, error)
+	Deactivate(ctx context.Context, id int64) (bool, error)
+}
+
+// BroadcastLauncher sends broadcasts without confirmation; Broadcasts implements it.
+type BroadcastLauncher interface {
+	Launch(ctx context.Context, chatID int64, text, target string) error
+}
+
+type ScheduledBroadcasts struct {
+	log       *zap.SugaredLogger
+	scheduled ScheduledBroadcastStore
+	broadcast BroadcastLauncher
+	audit     AuditRecorder
+	now       func() time.Time
+}
+
+func NewScheduledBroadcasts(
+	log *zap.SugaredLogger,
+	scheduled ScheduledBroadcastStore,
+	broadcast BroadcastLauncher,
+	audit AuditRecorder,
+) ScheduledBroadcasts {
+	return ScheduledBroadcasts{
+		log:       log,
+		scheduled: scheduled,
+		broadcast: broadcast,
+		audit:     audit,
+		now:       time.Now,
+	}
+}
+
+// ParseWhen accepts either a time in the ScheduleTimeLayout or a five-field cron expression,
+// returning the cron expression (empty for one-off broadcasts) and the first run.
+func (r ScheduledBroadcasts) ParseWhen(input string) (string, time.Time, error) {
+	input = strings.TrimSpace(input)
+
+	if at, err := time.ParseInLocation(ScheduleTimeLayout, input, ScheduleLocation); err == nil {
+		if !at.After(r.now()) {
+			return "", time.Time{}, fmt.Errorf("%s is in the past", input)
+		}
+
+		return "", at, nil
+	}
+
+	schedule, err := cron.ParseStandard(input)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parse cron: %w", err)
+	}
+
+	return input, schedule.Next(r.now().In(ScheduleLocation)), nil
+}
+
+func (r ScheduledBroadcasts) Schedule(
+	ctx context.Context, chatID int64, when, target, text string,
+) (types2.ScheduledBroadcast, error) {
+	expr, next, err := r.ParseWhen(when)
+	if err != nil {
+		return types2.ScheduledBroadcast{}, fmt.Errorf("parse when: %w", err)
+	}
+
+	scheduled, err := r.scheduled.Create(ctx, types2.ScheduledBroadcast{
+		CreatedBy: types.ActorFrom(ctx),
+		ChatID:    chatID,
+		Text:      text,
+		Target:    target,
+		Cron:      expr,
+		NextRunAt: next,
+		Active:    true,
+	})
+	if err != nil {
+		return types2.ScheduledBroadcast{}, fmt.Errorf("create: %w", err)
+	}
+
+	r.audit.Record(ctx, chatID, "schedule_broadcast", when+" "+target, fmt.Sprintf("scheduled #%d", scheduled.ID))
+
+	return scheduled, nil
+}
+
+func (r ScheduledBroadcasts) Active(ctx context.Context) (types2.ScheduledBroadcasts, error) {
+	list, err := r.scheduled.Active(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("active: %w", err)
+	}
+
+	return list, nil
+}
+
+func (r ScheduledBroadcasts) Cancel(ctx context.Context, chatID, id int64) (bool, error) {
+	ok, err := r.scheduled.Deactivate(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("deactivate: %w", err)
+	}
+
+	if ok {
+		r.audit.Record(ctx, chatID, "cancel_scheduled_broadcast", fmt.Sprintf("#%d", id), "cancelled")
+	}
+
+	return ok, nil
+}
+
+// RunDue launches the broadcasts whose time has come through the regular delivery path and moves
+// recurring ones to their next run. Runs missed while the bot was down are made once, not caught up.
+func (r ScheduledBroadcasts) RunDue(ctx context.Context) error {
+	now := r.now()
+
+	list, err := r.scheduled.Due(ctx, now)
+	if err != nil {
+		return fmt.Errorf("due: %w", err)
+	}
+
+	for _, scheduled := range list {
+		if len(scheduled.Cron) == 0 {
+			scheduled.Active = false
+		} else {
+			schedule, err := cron.ParseStandard(scheduled.Cron)
+			if err != nil {
+				r.log.Errorw("parse scheduled cron", "id", scheduled.ID, "cron", scheduled.Cron, "err", err)
+
+				scheduled.Active = false
+			} else {
+				scheduled.NextRunAt = schedule.Next(now.In(ScheduleLocation))
+			}
+		}
+
+		// save first, so a failing broadcast does not get launched on every tick
+		if err := r.scheduled.Save(ctx, scheduled); err != nil {
+			return fmt.Errorf("save %d: %w", scheduled.ID, err)
+		}
+
+		actx := types.WithActor(ctx, scheduled.CreatedBy)
+		if err := r.broadcast.Launch(actx, scheduled.ChatID, scheduled.Text, scheduled.Target); err != nil {
+			r.log.Errorw("launch scheduled broadcast", "id", scheduled.ID, "err", err)
+		}
+	}
+
+	return nil
+}
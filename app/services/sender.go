@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sender is the part of clients.Telegram services talk to chats through.
+type Sender interface {
+	Send(ctx context.Context, chattable tgbotapi.Chattable) (tgbotapi.Message, error)
+	MaybeSend(ctx context.Context, chattable tgbotapi.Chattable)
+	MaybeSendText(ctx context.Context, chatID int64, text string)
+}
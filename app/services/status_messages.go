@@ -0,0 +1,269 @@
+package services
+
+import (
+	"closealerts/app/clients"
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/ratelimit"
+	"go.uber.org/zap"
+)
+
+// StatusMessageStore keeps the status messages of chats; repositories.StatusMessages implements it.
+type StatusMessageStore interface {
+	All(ctx context.Context) (types2.StatusMessages, error)
+	StatusMessages(ctx context.Context, ids []int64) (types2.StatusMessages, error)
+	Save(ctx context.Context, msg types2.StatusMessage) error
+	Delete(ctx context.Context, chatID int64) error
+}
+
+// StatusMessages keeps a pinned message in the chats that want one, showing the state of the areas they track.
+// It is edited when the state changes, instead of the chat being notified about every change.
+type StatusMessages struct {
+	log          *zap.SugaredLogger
+	messages     StatusMessageStore
+	notification TrackingStore
+	languages    LanguageStore
+	telegram     Sender
+	// edits keeps the edits of many chats from eating up the Bot API rate limit notifications need.
+	edits   ratelimit.Limiter
+	refresh *sync.Mutex
+	// checks are when the messages were last edited whether changed or not, which is how deleted ones are found;
+	// guarded by refresh.
+	checks     *time.Time
+	checkEvery time.Duration
+	now        func() time.Time
+}
+
+func NewStatusMessages(
+	log *zap.SugaredLogger,
+	config types.Config,
+	messages StatusMessageStore,
+	notification TrackingStore,
+	languages LanguageStore,
+	telegram Sender,
+) StatusMessages {
+	return StatusMessages{
+		log:          log,
+		messages:     messages,
+		notification: notification,
+		languages:    languages,
+		telegram:     telegram,
+		edits:        ratelimit.New(config.StatusEditRateLimit, ratelimit.Per(time.Second)),
+		refresh:      &sync.Mutex{},
+		checks:       &time.Time{},
+		checkEvery:   config.StatusCheckInterval,
+		now:          time.Now,
+	}
+}
+
+// Enable sends and pins the status message to the chat, replacing the one it had.
+func (r StatusMessages) Enable(ctx context.Context, chatID int64) error {
+	if _, err := r.Disable(ctx, chatID); err != nil {
+		return fmt.Errorf("disable: %w", err)
+	}
+
+	text, err := r.text(ctx, chatID, r.printer(ctx, chatID))
+	if err != nil {
+		return fmt.Errorf("text: %w", err)
+	}
+
+	if err := r.create(ctx, chatID, text); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	return nil
+}
+
+// Disable unpins the status message of the chat and stops editing it, telling whether the chat had one.
+func (r StatusMessages) Disable(ctx context.Context, chatID int64) (bool, error) {
+	list, err := r.messages.StatusMessages(ctx, []int64{chatID})
+	if err != nil {
+		return false, fmt.Errorf("status messages: %w", err)
+	}
+
+	if len(list) == 0 {
+		return false, nil
+	}
+
+	r.telegram.MaybeSend(ctx, tgbotapi.UnpinChatMessageConfig{ChatID: chatID, MessageID: list[0].MessageID})
+
+	if err := r.messages.Delete(ctx, chatID); err != nil {
+		return false, fmt.Errorf("delete: %w", err)
+	}
+
+	return true, nil
+}
+
+// Refresh edits the status messages whose text has changed, recreating the deleted ones. A message deleted while
+// nothing changes is only found when every message gets edited, once in the check interval. A refresh still
+// going on when the next one is due is left to finish, and the next one is skipped.
+func (r StatusMessages) Refresh(ctx context.Context) {
+	if !r.refresh.TryLock() {
+		r.log.Debugw("status messages still refreshing")
+
+		return
+	}
+	defer r.refresh.Unlock()
+
+	list, err := r.messages.All(ctx)
+	if err != nil {
+		r.log.Errorw("status messages", "err", err)
+
+		return
+	}
+
+	if len(list) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(list))
+	for _, msg := range list {
+		ids = append(ids, msg.ChatID)
+	}
+
+	langs, err := r.languages.Languages(ctx, ids)
+	if err != nil {
+		r.log.Errorw("chat languages", "err", err)
+	}
+
+	check := r.now().Sub(*r.checks) >= r.checkEvery
+	if check {
+		*r.checks = r.now()
+	}
+
+	for _, msg := range list {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := r.refreshOne(ctx, msg, i18n.New(langs[msg.ChatID]), check); err != nil {
+			r.log.Errorw("refresh status message", "chat_id", msg.ChatID, "err", err)
+		}
+	}
+}
+
+// refreshOne edits the message if its text has changed, or anyway if it is to be checked.
+func (r StatusMessages) refreshOne(ctx context.Context, msg types2.StatusMessage, p i18n.Printer, check bool) error {
+	text, err := r.text(ctx, msg.ChatID, p)
+	if err != nil {
+		return fmt.Errorf("text: %w", err)
+	}
+
+	if text == msg.Text && !check {
+		return nil
+	}
+
+	r.edits.Take()
+
+	_, err = r.telegram.Send(ctx, tgbotapi.NewEditMessageText(msg.ChatID, msg.MessageID, text))
+
+	switch {
+	case err == nil, clients.IsNotModified(err):
+		msg.Text, msg.UpdatedAt = text, r.now()
+		if err := r.messages.Save(ctx, msg); err != nil {
+			return fmt.Errorf("save: %w", err)
+		}
+	case clients.IsMessageGone(err):
+		r.log.Infow("status message deleted, recreating", "chat_id", msg.ChatID, "message_id", msg.MessageID)
+
+		if err := r.create(ctx, msg.ChatID, text); err != nil {
+			return fmt.Errorf("recreate: %w", err)
+		}
+	case clients.IsBlocked(err):
+		r.log.Infow("chat is gone, dropping its status message", "chat_id", msg.ChatID)
+
+		if err := r.messages.Delete(ctx, msg.ChatID); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+	default:
+		return fmt.Errorf("edit: %w", err)
+	}
+
+	return nil
+}
+
+func (r StatusMessages) create(ctx context.Context, chatID int64, text string) error {
+	sent, err := r.telegram.Send(ctx, tgbotapi.NewMessage(chatID, text))
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	// without the right to pin in a group the message still gets edited, people just have to find it
+	r.telegram.MaybeSend(ctx, tgbotapi.PinChatMessageConfig{
+		ChatID: chatID, MessageID: sent.MessageID, DisableNotification: true,
+	})
+
+	msg := types2.StatusMessage{ChatID: chatID, MessageID: sent.MessageID, Text: text, UpdatedAt: r.now()}
+	if err := r.messages.Save(ctx, msg); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	return nil
+}
+
+func (r StatusMessages) printer(ctx context.Context, chatID int64) i18n.Printer {
+	langs, err := r.languages.Languages(ctx, []int64{chatID})
+	if err != nil {
+		r.log.Errorw("chat languages", "err", err)
+	}
+
+	return i18n.New(langs[chatID])
+}
+
+// text lists the areas the chat tracks, the ones under alert first, with the time their state changed.
+func (r StatusMessages) text(ctx context.Context, chatID int64, p i18n.Printer) (string, error) {
+	list, err := r.notification.Tracking(ctx, chatID)
+	if err != nil {
+		return "", fmt.Errorf("tracking: %w", err)
+	}
+
+	if len(list) == 0 {
+		return p.T("status.title") + "\n\n" + p.T("status.empty"), nil
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Notified != list[j].Notified {
+			return list[i].Notified
+		}
+
+		return p.Area(list[i].Area) < p.Area(list[j].Area)
+	})
+
+	lines := make([]string, 0, len(list))
+
+	for _, notification := range list {
+		area := p.Area(notification.Area)
+
+		switch {
+		case notification.Notified && notification.NotifiedAt != nil:
+			lines = append(lines, p.T("status.alert", area, r.since(*notification.NotifiedAt)))
+		case notification.Notified:
+			lines = append(lines, p.T("status.alert_unknown", area))
+		case notification.ClearedAt != nil:
+			lines = append(lines, p.T("status.calm", area, r.since(*notification.ClearedAt)))
+		default:
+			lines = append(lines, p.T("status.calm_unknown", area))
+		}
+	}
+
+	return p.T("status.title") + "\n\n" + strings.Join(lines, "\n"), nil
+}
+
+// since writes the time as HH:MM, adding the date when it was not today.
+func (r StatusMessages) since(at time.Time) string {
+	at, now := at.In(ScheduleLocation), r.now().In(ScheduleLocation)
+	if at.YearDay() == now.YearDay() && at.Year() == now.Year() {
+		return at.Format("15:04")
+	}
+
+	return at.Format("02.01 15:04")
+}
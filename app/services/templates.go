@@ -0,0 +1,423 @@
+package services
+
+import (
+	"closealerts/app/i18n"
+	types2 "closealerts/app/repositories/types"
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Notification kinds a chat can replace the text of.
+const (
+	TemplateAlert    = "alert"
+	TemplateAllClear = "all_clear"
+)
+
+// Template formats: how the values are escaped and which parse mode the text is sent with.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+func TemplateKinds() types.Stringies {
+	return types.Stringies{TemplateAlert, TemplateAllClear}
+}
+
+func TemplateFormats() types.Stringies {
+	return types.Stringies{FormatText, FormatMarkdown, FormatHTML}
+}
+
+// maxMessageLength is the most runes Telegram takes in a message.
+const maxMessageLength = 4096
+
+// TemplateData is what templates can show, as {{.Areas}}, {{.Count}}, {{.Type}}, {{.Start}} and {{.Duration}}.
+// Type is only known for alerts, Duration only for all-clear.
+type TemplateData struct {
+	Areas    string
+	Count    int
+	Type     string
+	Start    string
+	Duration string
+}
+
+// TemplateError explains why a template was rejected, as a key of the i18n catalog with its args.
+type TemplateError struct {
+	Key  string
+	Args []interface{}
+}
+
+func (r TemplateError) Error() string {
+	return fmt.Sprintf("%s %v", r.Key, r.Args)
+}
+
+// Text is the explanation in the language of the printer.
+func (r TemplateError) Text(p i18n.Printer) string {
+	return p.T(r.Key, r.Args...)
+}
+
+// templateField is a placeholder like {{.Areas}}; templates are plain text with placeholders, not text/template,
+// so whoever writes one can't make rendering loop or grow without bound.
+var templateField = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+
+// TemplatePlaceholders are what templates can show, as written in them.
+func TemplatePlaceholders() types.Stringies {
+	return types.Stringies{"{{.Areas}}", "{{.Count}}", "{{.Type}}", "{{.Start}}", "{{.Duration}}"}
+}
+
+// RenderTemplate fills the placeholders of the template with the data escaped for its format,
+// returning the text and the parse mode to send it with.
+func RenderTemplate(chatTemplate types2.ChatTemplate, data TemplateData) (string, string, error) {
+	escape, parseMode := func(s string) string { return s }, ""
+
+	switch chatTemplate.Format {
+	case FormatMarkdown:
+		escape, parseMode = func(s string) string { return tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, s) }, tgbotapi.ModeMarkdownV2
+	case FormatHTML:
+		escape, parseMode = html.EscapeString, tgbotapi.ModeHTML
+	}
+
+	values := map[string]string{
+		"Areas":    escape(data.Areas),
+		"Count":    strconv.Itoa(data.Count),
+		"Type":     escape(data.Type),
+		"Start":    escape(data.Start),
+		"Duration": escape(data.Duration),
+	}
+
+	var unknown string
+
+	text := templateField.ReplaceAllStringFunc(chatTemplate.Text, func(placeholder string) string {
+		name := templateField.FindStringSubmatch(placeholder)[1]
+
+		value, ok := values[name]
+		if !ok && len(unknown) == 0 {
+			unknown = name
+		}
+
+		return value
+	})
+
+	if len(unknown) > 0 {
+		return "", "", TemplateError{Key: "template.unknown_field", Args: []interface{}{unknown, TemplatePlaceholders().Join(", ")}}
+	}
+
+	// what is left of braces is not a placeholder
+	if strings.Contains(templateField.ReplaceAllString(chatTemplate.Text, ""), "{{") {
+		return "", "", TemplateError{Key: "template.syntax", Args: []interface{}{TemplatePlaceholders().Join(", ")}}
+	}
+
+	return text, parseMode, nil
+}
+
+// ValidateTemplate renders the template with the sample data and checks Telegram would take the result.
+func ValidateTemplate(p i18n.Printer, chatTemplate types2.ChatTemplate) error {
+	if !TemplateKinds().Contains(chatTemplate.Kind) {
+		return TemplateError{Key: "template.unknown_kind", Args: []interface{}{TemplateKinds().Join(", ")}}
+	}
+
+	if !TemplateFormats().Contains(chatTemplate.Format) {
+		return TemplateError{Key: "template.unknown_format", Args: []interface{}{TemplateFormats().Join(", ")}}
+	}
+
+	text, _, err := RenderTemplate(chatTemplate, SampleTemplateData(p, chatTemplate.Kind))
+	if err != nil {
+		return err
+	}
+
+	if len(strings.TrimSpace(text)) == 0 {
+		return TemplateError{Key: "template.empty"}
+	}
+
+	if n := utf8.RuneCountInString(text); n > maxMessageLength {
+		return TemplateError{Key: "template.too_long", Args: []interface{}{n, maxMessageLength}}
+	}
+
+	switch chatTemplate.Format {
+	case FormatMarkdown:
+		return validateMarkdown(text)
+	case FormatHTML:
+		return validateHTML(text)
+	}
+
+	return nil
+}
+
+// SampleTemplateData is what previews and validation render templates with.
+func SampleTemplateData(p i18n.Printer, kind string) TemplateData {
+	now := time.Now().In(ScheduleLocation)
+	areas := p.Areas([]string{"Київська", "м. Київ"})
+
+	data := TemplateData{Areas: areas.Join(", "), Count: len(areas)}
+
+	if kind == TemplateAllClear {
+		duration := 85 * time.Minute
+		data.Start, data.Duration = now.Add(-duration).Format("15:04"), FormatDuration(p, duration)
+
+		return data
+	}
+
+	data.Start, data.Type = now.Format("15:04"), AreaTypes(p, []string{"o"})
+
+	return data
+}
+
+// FormatDuration writes the duration in hours and minutes, like 1h 25m.
+func FormatDuration(p i18n.Printer, d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return p.T("duration.minutes", minutes)
+	}
+
+	return p.T("duration.hours_minutes", minutes/60, minutes%60)
+}
+
+// AreaTypes names the distinct alert types, the sources' o, r, c and h, in the language.
+func AreaTypes(p i18n.Printer, alertTypes []string) string {
+	var names types.Stringies
+
+	for _, alertType := range alertTypes {
+		if len(alertType) == 0 {
+			continue
+		}
+
+		name := p.T("area_type." + alertType)
+		if name == "area_type."+alertType {
+			name = alertType
+		}
+
+		if !names.Contains(name) {
+			names = append(names, name)
+		}
+	}
+
+	return names.Join(", ")
+}
+
+// markdownReserved are the characters MarkdownV2 wants escaped outside of entities.
+const markdownReserved = "_*[]()~`>#+-=|{}.!"
+
+// validateMarkdown checks the text parses as MarkdownV2: reserved characters are escaped
+// and the entities are closed. Telegram rejects the whole message otherwise.
+func validateMarkdown(text string) error {
+	runes := []rune(text)
+	open := map[string]bool{}
+	inLink := false
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		switch {
+		case c == '\\':
+			i++
+		case c == '`':
+			fence := "`"
+			if i+2 < len(runes) && runes[i+1] == '`' && runes[i+2] == '`' {
+				fence = "```"
+			}
+
+			end := indexUnescaped(runes, i+len(fence), fence)
+			if end < 0 {
+				return TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{fence}}
+			}
+
+			i = end + len(fence) - 1
+		case c == '_' && i+1 < len(runes) && runes[i+1] == '_':
+			open["__"] = !open["__"]
+			i++
+		case c == '|' && i+1 < len(runes) && runes[i+1] == '|':
+			open["||"] = !open["||"]
+			i++
+		case c == '*' || c == '_' || c == '~':
+			open[string(c)] = !open[string(c)]
+		case c == '[' && !inLink:
+			inLink = true
+		case c == ']' && inLink && i+1 < len(runes) && runes[i+1] == '(':
+			end := indexUnescaped(runes, i+2, ")")
+			if end < 0 {
+				return TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"("}}
+			}
+
+			inLink, i = false, end
+		case strings.ContainsRune(markdownReserved, c):
+			return TemplateError{Key: "template.markdown_escape", Args: []interface{}{string(c)}}
+		}
+	}
+
+	if inLink {
+		return TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"["}}
+	}
+
+	for _, entity := range []string{"*", "_", "__", "~", "||"} {
+		if open[entity] {
+			return TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{entity}}
+		}
+	}
+
+	return nil
+}
+
+// indexUnescaped finds the first occurrence of the substring from the rune at from on, skipping escaped runes.
+func indexUnescaped(runes []rune, from int, sub string) int {
+	needle := []rune(sub)
+
+	for i := from; i+len(needle) <= len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+
+			continue
+		}
+
+		if string(runes[i:i+len(needle)]) == sub {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// htmlTags are the tags Telegram formats; it rejects messages with any other.
+var htmlTags = types.Stringies{
+	"b", "strong", "i", "em", "u", "ins", "s", "strike", "del",
+	"a", "code", "pre", "tg-spoiler", "span", "blockquote",
+}
+
+// validateHTML checks the text parses as Telegram's HTML: known tags properly nested and entities for <, > and &.
+func validateHTML(text string) error {
+	var stack []string
+
+	for len(text) > 0 {
+		idx := strings.IndexAny(text, "<&>")
+		if idx < 0 {
+			break
+		}
+
+		c := text[idx]
+		text = text[idx:]
+
+		switch c {
+		case '>':
+			return TemplateError{Key: "template.html_escape", Args: []interface{}{">"}}
+		case '&':
+			end := strings.IndexByte(text, ';')
+			if end < 0 || !isHTMLEntity(text[1:end]) {
+				return TemplateError{Key: "template.html_escape", Args: []interface{}{"&"}}
+			}
+
+			text = text[end+1:]
+
+			continue
+		}
+
+		end := strings.IndexByte(text, '>')
+		if end < 0 {
+			return TemplateError{Key: "template.html_escape", Args: []interface{}{"<"}}
+		}
+
+		tag := text[1:end]
+		text = text[end+1:]
+
+		closing := strings.HasPrefix(tag, "/")
+
+		var name string
+		if fields := strings.Fields(strings.TrimPrefix(tag, "/")); len(fields) > 0 {
+			name = strings.ToLower(fields[0])
+		}
+
+		if !htmlTags.Contains(name) {
+			return TemplateError{Key: "template.html_tag", Args: []interface{}{name}}
+		}
+
+		if !closing {
+			stack = append(stack, name)
+
+			continue
+		}
+
+		if len(stack) == 0 || stack[len(stack)-1] != name {
+			return TemplateError{Key: "template.html_unexpected", Args: []interface{}{name}}
+		}
+
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(stack) > 0 {
+		return TemplateError{Key: "template.html_unclosed", Args: []interface{}{stack[len(stack)-1]}}
+	}
+
+	return nil
+}
+
+func isHTMLEntity(name string) bool {
+	switch name {
+	case "lt", "gt", "amp", "quot":
+		return true
+	}
+
+	if !strings.HasPrefix(name, "#") || len(name) < 2 {
+		return false
+	}
+
+	digits := name[1:]
+	if digits[0] == 'x' || digits[0] == 'X' {
+		digits = digits[1:]
+	}
+
+	return len(digits) > 0 && strings.Trim(digits, "0123456789abcdefABCDEF") == ""
+}
+
+// ChatTemplateStore keeps the notification templates of chats; repositories.Templates implements it.
+type ChatTemplateStore interface {
+	Templates(ctx context.Context, ids []int64) (types2.ChatTemplates, error)
+	Save(ctx context.Context, template types2.ChatTemplate) error
+	Delete(ctx context.Context, chatID int64, kind string) (bool, error)
+}
+
+type Templates struct {
+	templates ChatTemplateStore
+}
+
+func NewTemplates(templates ChatTemplateStore) Templates {
+	return Templates{templates: templates}
+}
+
+func (r Templates) Get(ctx context.Context, chatID int64) (types2.ChatTemplates, error) {
+	list, err := r.templates.Templates(ctx, []int64{chatID})
+	if err != nil {
+		return nil, fmt.Errorf("templates: %w", err)
+	}
+
+	return list, nil
+}
+
+// Save replaces the text of the notification kind in the chat, if the template is valid.
+func (r Templates) Save(ctx context.Context, p i18n.Printer, chatTemplate types2.ChatTemplate) error {
+	if err := ValidateTemplate(p, chatTemplate); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	if err := r.templates.Save(ctx, chatTemplate); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	return nil
+}
+
+// Reset brings back the default text of the notification kind, telling whether the chat had a template for it.
+func (r Templates) Reset(ctx context.Context, chatID int64, kind string) (bool, error) {
+	deleted, err := r.templates.Delete(ctx, chatID, kind)
+	if err != nil {
+		return false, fmt.Errorf("delete: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	types2 "closealerts/app/repositories/types"
+	"reflect"
+	"testing"
+)
+
+func TestValidateMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{name: "plain text", text: "Тривога в Київській", want: nil},
+		{name: "entities", text: "*bold* _italic_ __underline__ ~strike~ ||spoiler||", want: nil},
+		{name: "nested entities", text: "*bold _italic_ bold*", want: nil},
+		{name: "code keeps reserved characters", text: "`a-b.c` and ```1. item```", want: nil},
+		{name: "link keeps reserved characters", text: "[карта](https://alerts.in.ua/?a=1-2)", want: nil},
+		{name: "escaped reserved characters", text: "м\\. Київ \\(1\\-2\\)\\!", want: nil},
+		{name: "escaped entity marks", text: "2 \\* 2 \\_ \\~", want: nil},
+		{name: "unescaped dot", text: "м. Київ", want: TemplateError{Key: "template.markdown_escape", Args: []interface{}{"."}}},
+		{name: "unescaped dash", text: "1-2", want: TemplateError{Key: "template.markdown_escape", Args: []interface{}{"-"}}},
+		{name: "unclosed bold", text: "*bold", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"*"}}},
+		{name: "unclosed underline", text: "__underline", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"__"}}},
+		{name: "unclosed spoiler", text: "||spoiler", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"||"}}},
+		{name: "unclosed code", text: "`code", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"`"}}},
+		{name: "unclosed pre", text: "```pre`", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"```"}}},
+		{name: "escaped code end", text: "`code\\`", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"`"}}},
+		{name: "unclosed link text", text: "[карта", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"["}}},
+		{name: "unclosed link url", text: "[карта](https://alerts.in.ua", want: TemplateError{Key: "template.markdown_unclosed", Args: []interface{}{"("}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMarkdown(tt.text); !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("validateMarkdown(%q) = %v, want %v", tt.text, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{name: "plain text", text: "Тривога в Київській", want: nil},
+		{name: "tags", text: "<b>bold</b> <i>italic</i> <tg-spoiler>spoiler</tg-spoiler>", want: nil},
+		{name: "nested tags", text: "<b><i>bold italic</i></b>", want: nil},
+		{name: "tag with attributes", text: `<a href="https://alerts.in.ua">карта</a>`, want: nil},
+		{name: "tag names are case insensitive", text: "<B>bold</b>", want: nil},
+		{name: "entities", text: "&lt;1&gt; &amp; &quot;2&quot; &#39; &#x1F6A8;", want: nil},
+		{name: "unclosed tag", text: "<b>bold", want: TemplateError{Key: "template.html_unclosed", Args: []interface{}{"b"}}},
+		{name: "outer tag closed over an open one", text: "<b><i>bold italic</b>", want: TemplateError{Key: "template.html_unexpected", Args: []interface{}{"b"}}},
+		{name: "crossed tags", text: "<b><i>bold italic</b></i>", want: TemplateError{Key: "template.html_unexpected", Args: []interface{}{"b"}}},
+		{name: "closing without opening", text: "bold</b>", want: TemplateError{Key: "template.html_unexpected", Args: []interface{}{"b"}}},
+		{name: "unknown tag", text: "<script>alert()</script>", want: TemplateError{Key: "template.html_tag", Args: []interface{}{"script"}}},
+		{name: "empty tag", text: "<>", want: TemplateError{Key: "template.html_tag", Args: []interface{}{""}}},
+		{name: "bare less than", text: "1 < 2", want: TemplateError{Key: "template.html_escape", Args: []interface{}{"<"}}},
+		{name: "bare greater than", text: "2 > 1", want: TemplateError{Key: "template.html_escape", Args: []interface{}{">"}}},
+		{name: "bare ampersand", text: "Київ & область", want: TemplateError{Key: "template.html_escape", Args: []interface{}{"&"}}},
+		{name: "unknown entity", text: "&nbsp;", want: TemplateError{Key: "template.html_escape", Args: []interface{}{"&"}}},
+		{name: "numeric entity without digits", text: "&#x;", want: TemplateError{Key: "template.html_escape", Args: []interface{}{"&"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateHTML(tt.text); !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("validateHTML(%q) = %v, want %v", tt.text, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := TemplateData{
+		Areas:    "м. Київ (1-2) & <Бучанський>",
+		Count:    2,
+		Type:     "повітряна_тривога",
+		Start:    "12:30",
+		Duration: "1 год.",
+	}
+
+	tests := []struct {
+		name          string
+		format        string
+		text          string
+		wantText      string
+		wantParseMode string
+		wantErr       error
+	}{
+		{
+			name:     "text is not escaped",
+			format:   FormatText,
+			text:     "{{.Areas}}: {{.Count}}",
+			wantText: "м. Київ (1-2) & <Бучанський>: 2",
+		},
+		{
+			name:     "spaces in placeholders",
+			format:   FormatText,
+			text:     "{{ .Start }}, {{.Duration }}",
+			wantText: "12:30, 1 год.",
+		},
+		{
+			name:          "markdown escapes values",
+			format:        FormatMarkdown,
+			text:          "*{{.Areas}}* {{.Type}}",
+			wantText:      "*м\\. Київ \\(1\\-2\\) & <Бучанський\\>* повітряна\\_тривога",
+			wantParseMode: "MarkdownV2",
+		},
+		{
+			name:          "html escapes values",
+			format:        FormatHTML,
+			text:          "<b>{{.Areas}}</b> {{.Count}}",
+			wantText:      "<b>м. Київ (1-2) &amp; &lt;Бучанський&gt;</b> 2",
+			wantParseMode: "HTML",
+		},
+		{
+			name:    "unknown placeholder",
+			format:  FormatText,
+			text:    "{{.Areas}} {{.Oblast}}",
+			wantErr: TemplateError{Key: "template.unknown_field", Args: []interface{}{"Oblast", TemplatePlaceholders().Join(", ")}},
+		},
+		{
+			name:    "placeholder without a dot",
+			format:  FormatText,
+			text:    "{{Areas}}",
+			wantErr: TemplateError{Key: "template.syntax", Args: []interface{}{TemplatePlaceholders().Join(", ")}},
+		},
+		{
+			name:    "unclosed placeholder",
+			format:  FormatHTML,
+			text:    "{{.Areas}} {{.Count",
+			wantErr: TemplateError{Key: "template.syntax", Args: []interface{}{TemplatePlaceholders().Join(", ")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, parseMode, err := RenderTemplate(types2.ChatTemplate{Kind: TemplateAlert, Format: tt.format, Text: tt.text}, data)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("RenderTemplate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if text != tt.wantText || parseMode != tt.wantParseMode {
+				t.Errorf("RenderTemplate() = %q, %q, want %q, %q", text, parseMode, tt.wantText, tt.wantParseMode)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// escaped values must not break the markup around them
+			switch tt.format {
+			case FormatMarkdown:
+				err = validateMarkdown(text)
+			case FormatHTML:
+				err = validateHTML(text)
+			}
+
+			if err != nil {
+				t.Errorf("rendered %q does not validate: %v", text, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package tracing sets up OpenTelemetry and has the helpers the bot starts and ends its spans with.
+package tracing
+
+import (
+	"closealerts/app/types"
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+var tracer = otel.Tracer("closealerts")
+
+// Start starts a span, a child of the one in the context if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Register sets up the global tracer provider with the configured exporter, flushing it on stop.
+// Packages trace through otel.Tracer, so with the exporter set to none spans go nowhere.
+func Register(lc fx.Lifecycle, config types.Config) error {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch config.TracingExporter {
+	case "", ExporterNone:
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.TracingEndpoint)}
+		if config.TracingInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return fmt.Errorf("unknown tracing exporter %s", config.TracingExporter)
+	}
+
+	if err != nil {
+		return fmt.Errorf("new %s exporter: %w", config.TracingExporter, err)
+	}
+
+	provider := NewProvider(exporter, config.TracingSampleRatio)
+
+	// a provider without span processors has nothing to flush and fails to shut down
+	if exporter == nil {
+		return nil
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			if err := provider.Shutdown(ctx); err != nil {
+				return fmt.Errorf("shutdown: %w", err)
+			}
+
+			return nil
+		},
+	})
+
+	return nil
+}
+
+// NewProvider installs a global provider batching spans to the exporter, if any;
+// tracetest.NewInMemoryExporter makes the spans of a run checkable.
+func NewProvider(exporter sdktrace.SpanExporter, ratio float64) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String("closealerts"))),
+	}
+
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+
+	return provider
+}
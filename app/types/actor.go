@@ -0,0 +1,17 @@
+package types
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor remembers the Telegram user who caused the update being handled.
+func WithActor(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFrom returns the user from WithActor, or 0 when the action is not caused by a user, like channel posts.
+func ActorFrom(ctx context.Context) int64 {
+	userID, _ := ctx.Value(actorKey{}).(int64)
+
+	return userID
+}
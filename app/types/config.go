@@ -1,46 +1,308 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
 )
 
+// Config is read from the YAML file named by CONFIG_FILE, if any, with environment variables on top of it.
+// Every field can be overridden by the variable named after its yaml key in upper case, e.g. TICK_INTERVAL;
+// lists are comma separated.
 type Config struct {
-	Addr           string
-	TickInterval   time.Duration
-	SQLite3DBPath  string
-	TelegramBotAPI string
-	WHEndpoint     string
-	Updates        chan Update
-	Cert           string
-	Key            string
-	DebugTelegram  bool
+	Addr           string        `yaml:"server_addr"`
+	TickInterval   time.Duration `yaml:"tick_interval"`
+	SQLite3DBPath  string        `yaml:"sqlite3_db_path"`
+	TelegramBotAPI string        `yaml:"telegram_bot_api"`
+	WHEndpoint     string        `yaml:"webhook_endpoint"`
+	Updates        chan Update   `yaml:"-"`
+	Cert           string        `yaml:"server_cert"`
+	Key            string        `yaml:"server_key"`
+	DebugTelegram  bool          `yaml:"debug_telegram"`
+	AdminUserIDs   []int64       `yaml:"admin_user_ids"`
+	// FakeScenariosDir holds simulation scenarios played by /admin_fake_scenario.
+	FakeScenariosDir string `yaml:"fake_scenarios_dir"`
+	// GeoAreasFile is a GeoJSON file with hromada borders, or more precise raion ones, to locate shared locations
+	// by, on top of the bundled oblast and raion ones. Features carry name, level (oblast, raion or hromada) and
+	// parent properties.
+	GeoAreasFile string `yaml:"geo_areas_file"`
+	// GeoNeighborsFile is a JSON object listing the neighbors of hromadas, or any other areas, for /near,
+	// on top of the bundled oblast and raion ones.
+	GeoNeighborsFile string `yaml:"geo_neighbors_file"`
+
+	// SourcesMode is live, record or replay; recordings are kept in SourcesDir.
+	SourcesMode        string  `yaml:"sources_mode"`
+	SourcesDir         string  `yaml:"sources_dir"`
+	SourcesReplaySpeed float64 `yaml:"sources_replay_speed"`
+	// SourcesBaseURL replaces the scheme and host of alert sources, to point them to a stand-in server.
+	SourcesBaseURL string `yaml:"sources_base_url"`
+
+	// TelegramAPIEndpoint is the Bot API URL pattern, for pointing the bot to a stand-in like fakebot.
+	TelegramAPIEndpoint string `yaml:"telegram_api_endpoint"`
+
+	// DBDriver is sqlite, using SQLite3DBPath, or postgres, using DatabaseURL.
+	DBDriver    string `yaml:"db_driver"`
+	DatabaseURL string `yaml:"database_url"`
+
+	// StaleAfter is how old alert data may get before the bot is reported not ready.
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// StaleNotifyAfter is how old alert data gets before admins are told about it.
+	StaleNotifyAfter time.Duration `yaml:"stale_notify_after"`
+
+	UkrzenAPIKey string `yaml:"ukrzen_api_key"`
+	// TelegramRateLimit is the number of Bot API requests a second.
+	TelegramRateLimit int `yaml:"telegram_rate_limit"`
+	// StatusEditRateLimit is the number of status message edits a second, taken out of the TelegramRateLimit.
+	StatusEditRateLimit int `yaml:"status_edit_rate_limit"`
+	// StatusCheckInterval is how often status messages are edited even if unchanged, for the deleted ones to be
+	// found and recreated.
+	StatusCheckInterval time.Duration `yaml:"status_check_interval"`
+
+	LogLevel zapcore.Level `yaml:"log_level"`
+	// LogEncoding is json or console.
+	LogEncoding string `yaml:"log_encoding"`
+	// LogOutputs and LogErrorOutputs are stderr, stdout or file paths; files are rotated
+	// at LogMaxSizeMB and, when set, every LogRotateEvery.
+	LogOutputs      []string      `yaml:"log_outputs"`
+	LogErrorOutputs []string      `yaml:"log_error_outputs"`
+	LogMaxSizeMB    int           `yaml:"log_max_size_mb"`
+	LogMaxBackups   int           `yaml:"log_max_backups"`
+	LogMaxAgeDays   int           `yaml:"log_max_age_days"`
+	LogCompress     bool          `yaml:"log_compress"`
+	LogRotateEvery  time.Duration `yaml:"log_rotate_every"`
+	// LogSamplingInitial messages with the same text are logged each second, then every LogSamplingThereafter-th
+	// one, or none when it is zero. Zero LogSamplingInitial turns sampling off.
+	LogSamplingInitial    int `yaml:"log_sampling_initial"`
+	LogSamplingThereafter int `yaml:"log_sampling_thereafter"`
+
+	// TracingExporter is none, stdout or otlp, which sends spans over HTTP to TracingEndpoint (host:port).
+	TracingExporter    string  `yaml:"tracing_exporter"`
+	TracingEndpoint    string  `yaml:"tracing_endpoint"`
+	TracingInsecure    bool    `yaml:"tracing_insecure"`
+	TracingSampleRatio float64 `yaml:"tracing_sample_ratio"`
 }
 
 func NewConfig() (Config, error) {
-	tick, err := time.ParseDuration(os.Getenv("TICK_INTERVAL"))
+	config, err := LoadConfig(os.Getenv("CONFIG_FILE"))
 	if err != nil {
-		return Config{}, fmt.Errorf("parse duration: %w", err)
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+
+	config.Updates = make(chan Update, 1000)
+
+	return config, nil
+}
+
+// LoadConfig reads the file, when the path is set, applies the environment and validates the result.
+func LoadConfig(path string) (Config, error) {
+	config := Config{
+		Addr:                "localhost:8080",
+		SourcesDir:          "./recordings",
+		SourcesReplaySpeed:  1,
+		TelegramAPIEndpoint: "https://api.telegram.org/bot%s/%s",
+		TelegramRateLimit:   30,
+		StatusEditRateLimit: 5,
+		StatusCheckInterval: 10 * time.Minute,
+		LogLevel:            zapcore.DebugLevel,
+		LogEncoding:         "json",
+		LogOutputs:          []string{"stderr", "./log.log"},
+		LogErrorOutputs:     []string{"stderr", "./internal.log"},
+		LogMaxSizeMB:        100,
+		LogMaxBackups:       5,
+		TracingSampleRatio:  1,
+	}
+
+	if len(path) > 0 {
+		bts, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("read file: %w", err)
+		}
+
+		if err := yaml.Unmarshal(bts, &config); err != nil {
+			return Config{}, fmt.Errorf("unmarshal %s: %w", path, err)
+		}
+	}
+
+	problems := config.applyEnv()
+
+	if config.StaleAfter == 0 {
+		config.StaleAfter = 3 * config.TickInterval
+	}
+
+	if config.StaleNotifyAfter == 0 {
+		config.StaleNotifyAfter = config.StaleAfter
+	}
+
+	problems = append(problems, config.validate()...)
+
+	if len(problems) > 0 {
+		return Config{}, fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return config, nil
+}
+
+var levelType = reflect.TypeOf(zapcore.DebugLevel)
+
+// applyEnv overrides fields with the variables set, returning the values that could not be parsed.
+func (c *Config) applyEnv() []string {
+	var problems []string
+
+	value := reflect.ValueOf(c).Elem()
+
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("yaml")
+		if key == "-" {
+			continue
+		}
+
+		name := strings.ToUpper(key)
+
+		raw, ok := os.LookupEnv(name)
+		if !ok || len(raw) == 0 {
+			continue
+		}
+
+		if err := setField(value.Field(i), raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
 	}
 
-	addr := "localhost:8080"
-	if tmp := os.Getenv("SERVER_ADDR"); len(tmp) > 0 {
-		addr = tmp
+	return problems
+}
+
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == levelType:
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(raw)); err != nil {
+			return err
+		}
+
+		field.Set(reflect.ValueOf(level))
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		field.SetBool(strings.ToLower(raw) == "true")
+	case field.Kind() == reflect.Int, field.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(n)
+	case field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(f)
+	case field.Kind() == reflect.Slice:
+		list := reflect.MakeSlice(field.Type(), 0, 0)
+
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); len(item) == 0 {
+				continue
+			}
+
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setField(elem, item); err != nil {
+				return fmt.Errorf("%s: %w", item, err)
+			}
+
+			list = reflect.Append(list, elem)
+		}
+
+		field.Set(list)
+	default:
+		return errors.New("unsupported type " + field.Type().String())
 	}
 
-	debugTelegram := strings.ToLower(os.Getenv("DEBUG_TELEGRAM")) == "true"
+	return nil
+}
+
+func (c Config) validate() []string {
+	var problems []string
+
+	check := func(ok bool, problem string) {
+		if !ok {
+			problems = append(problems, problem)
+		}
+	}
+
+	check(len(c.TelegramBotAPI) > 0, "telegram_bot_api (TELEGRAM_BOT_API) is required")
+	check(c.TickInterval > 0, "tick_interval (TICK_INTERVAL) must be a positive duration like 30s")
+	check(c.TelegramRateLimit > 0, "telegram_rate_limit (TELEGRAM_RATE_LIMIT) must be positive")
+	check(c.StatusEditRateLimit > 0, "status_edit_rate_limit (STATUS_EDIT_RATE_LIMIT) must be positive")
+	check(c.StatusCheckInterval > 0, "status_check_interval (STATUS_CHECK_INTERVAL) must be a positive duration like 10m")
+	check(c.SourcesReplaySpeed > 0, "sources_replay_speed (SOURCES_REPLAY_SPEED) must be positive")
+	check((len(c.Cert) > 0) == (len(c.Key) > 0), "server_cert and server_key (SERVER_CERT, SERVER_KEY) go together")
+	check(len(c.LogOutputs) > 0, "log_outputs (LOG_OUTPUTS) must not be empty")
+	check(Stringies{"json", "console"}.Contains(c.LogEncoding), "log_encoding (LOG_ENCODING) must be json or console")
+	check(c.LogMaxSizeMB > 0, "log_max_size_mb (LOG_MAX_SIZE_MB) must be positive")
+	check(c.LogSamplingInitial >= 0 && c.LogSamplingThereafter >= 0,
+		"log_sampling_initial and log_sampling_thereafter (LOG_SAMPLING_INITIAL, LOG_SAMPLING_THEREAFTER) can't be negative")
+
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1,
+		"tracing_sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+
+	// values of clients.SourcesMode*, tracing.Exporter* and clients.DBDriver*, which can't be imported here
+	check(Stringies{"", "none", "stdout", "otlp"}.Contains(c.TracingExporter),
+		"tracing_exporter (TRACING_EXPORTER) must be none, stdout or otlp, not "+c.TracingExporter)
+	check(c.TracingExporter != "otlp" || len(c.TracingEndpoint) > 0,
+		"tracing_endpoint (TRACING_ENDPOINT) is required for otlp")
+	check(Stringies{"", "live", "record", "replay"}.Contains(c.SourcesMode),
+		"sources_mode (SOURCES_MODE) must be live, record or replay, not "+c.SourcesMode)
+
+	switch c.DBDriver {
+	case "", "sqlite":
+		check(len(c.SQLite3DBPath) > 0, "sqlite3_db_path (SQLITE3_DB_PATH) is required for sqlite")
+	case "postgres":
+		check(len(c.DatabaseURL) > 0, "database_url (DATABASE_URL) is required for postgres")
+	default:
+		problems = append(problems, "db_driver (DB_DRIVER) must be sqlite or postgres, not "+c.DBDriver)
+	}
+
+	return problems
+}
+
+// RestartNeeded names the fields changed between the configs which only take effect on restart.
+// Sources, the log level and the rate limit are applied on reload.
+func RestartNeeded(old, current Config) []string {
+	var fields []string
+
+	oldValue, currentValue := reflect.ValueOf(old), reflect.ValueOf(current)
+
+	for i := 0; i < oldValue.NumField(); i++ {
+		key := oldValue.Type().Field(i).Tag.Get("yaml")
+		if key == "-" || reloadable.Contains(key) {
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), currentValue.Field(i).Interface()) {
+			fields = append(fields, key)
+		}
+	}
+
+	return fields
+}
 
-	return Config{
-		SQLite3DBPath:  os.Getenv("SQLITE3_DB_PATH"),
-		TickInterval:   tick,
-		Addr:           addr,
-		TelegramBotAPI: os.Getenv("TELEGRAM_BOT_API"),
-		WHEndpoint:     os.Getenv("WEBHOOK_ENDPOINT"),
-		Updates:        make(chan Update, 1000),
-		Cert:           os.Getenv("SERVER_CERT"),
-		Key:            os.Getenv("SERVER_KEY"),
-		DebugTelegram:  debugTelegram,
-	}, nil
+var reloadable = Stringies{
+	"sources_mode", "sources_dir", "sources_replay_speed", "sources_base_url", "ukrzen_api_key",
+	"telegram_rate_limit", "log_level",
 }
@@ -2,7 +2,17 @@ package types
 
 import "errors"
 
+const (
+	// PermAdmin grants and revokes permissions, and has all of them.
+	PermAdmin         = "admin"
+	PermSendBroadcast = "send_broadcast"
+	PermSendFakeEvent = "send_fake_event"
+)
+
+var Permissions = Stringies{PermAdmin, PermSendBroadcast, PermSendFakeEvent}
+
 var (
 	ErrLinkExists      = errors.New("link exists")
 	ErrUnknownCBAction = errors.New("unknown action")
+	ErrUnknownArea     = errors.New("unknown area")
 )
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringiesDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      Stringies
+		payload string
+		want    Stringies
+	}{
+		{name: "nil", in: nil, payload: "a", want: nil},
+		{name: "missing", in: Stringies{"a", "b"}, payload: "c", want: Stringies{"a", "b"}},
+		{name: "every occurrence", in: Stringies{"a", "b", "a"}, payload: "a", want: Stringies{"b"}},
+		{name: "the only one", in: Stringies{"a"}, payload: "a", want: Stringies{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.Delete(tt.payload); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Delete(%q) = %#v, want %#v", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringiesSort(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Stringies
+		want Stringies
+	}{
+		{name: "nil", in: nil, want: nil},
+		{name: "empty", in: Stringies{}, want: nil},
+		{name: "unsorted", in: Stringies{"Львівська", "Київська", "Волинська"},
+			want: Stringies{"Волинська", "Київська", "Львівська"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in.Join(",")
+
+			if got := tt.in.Sort(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort() = %#v, want %#v", got, tt.want)
+			}
+
+			if tt.in.Join(",") != in {
+				t.Errorf("Sort() changed the receiver to %#v", tt.in)
+			}
+		})
+	}
+}
+
+func TestStringiesContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    Stringies
+		match string
+		want  bool
+	}{
+		{name: "nil", in: nil, match: "a", want: false},
+		{name: "contains", in: Stringies{"a", "b"}, match: "b", want: true},
+		{name: "prefix is not a match", in: Stringies{"ab"}, match: "a", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.Contains(tt.match); got != tt.want {
+				t.Errorf("Contains(%q) = %v, want %v", tt.match, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringiesPrependIfContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    Stringies
+		match string
+		want  string
+	}{
+		{name: "contains", in: Stringies{"a"}, match: "a", want: "✅ a"},
+		{name: "missing", in: Stringies{"b"}, match: "a", want: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.PrependIfContains(tt.match, "✅ "); got != tt.want {
+				t.Errorf("PrependIfContains(%q) = %q, want %q", tt.match, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringiesJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Stringies
+		want string
+	}{
+		{name: "nil", in: nil, want: ""},
+		{name: "one", in: Stringies{"a"}, want: "a"},
+		{name: "several", in: Stringies{"a", "b", "c"}, want: "a, b, c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.Join(", "); got != tt.want {
+				t.Errorf("Join() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package types
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type Update struct {
 	tgbotapi.Update
+
+	// SpanContext is the span of the webhook request the update came with; handling continues its trace.
+	SpanContext trace.SpanContext `json:"-"`
 }